@@ -0,0 +1,78 @@
+package main
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/parser"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// astCommand implements "monkey ast [--json] [file]" and returns the process exit code
+// the parsed program is printed in its fully parenthesized form, or as JSON with --json
+func astCommand(args []string) int {
+	flags := flag.NewFlagSet("ast", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the AST as JSON")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey ast [--json] [file]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+
+	var in io.Reader = os.Stdin
+	if flags.NArg() == 1 && flags.Arg(0) != "-" {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer file.Close()
+
+		in = file
+	}
+
+	l := lexer.NewReader(in)
+	l.KeepComments(true)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if errors := p.Errors(); len(errors) > 0 {
+		for _, msg := range errors {
+			fmt.Fprintln(os.Stderr, msg)
+		}
+		return 1
+	}
+
+	if !*asJSON {
+		for _, stmt := range program.Statements {
+			fmt.Println(stmt.String())
+		}
+		return 0
+	}
+
+	data, err := ast.EncodeJSON(program)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var out bytes.Buffer
+	json.Indent(&out, data, "", "  ")
+	out.WriteString("\n")
+	out.WriteTo(os.Stdout)
+
+	return 0
+}
@@ -25,8 +25,10 @@ type Expression interface {
 }
 
 // Program is a node that will be the root of the AST.  It is represented as a series of statements
+// Comments holds every comment in the source in order.  It is only filled in when the lexer keeps comments
 type Program struct {
 	Statements []Statement
+	Comments   []token.Comment
 }
 
 func (p *Program) TokenLiteral() string {
@@ -193,6 +195,7 @@ func (b *Boolean) String() string       { return b.Token.Literal }
 type BlockStatement struct {
 	Token      token.Token // the { token
 	Statements []Statement
+	Rbrace     token.Token // the closing } token
 }
 
 func (bs *BlockStatement) statementNode()       {}
@@ -265,6 +268,7 @@ type CallExpression struct {
 	Token     token.Token // the '(' token
 	Function  Expression  // Identifier or FunctionLiteral
 	Arguments []Expression
+	Rparen    token.Token // the closing ) token
 }
 
 func (ce *CallExpression) expressionNode()      {}
@@ -284,3 +288,49 @@ func (ce *CallExpression) String() string {
 
 	return out.String()
 }
+
+// MacroLiteral represents a macro literal.  it looks like a function literal, but its body is evaluated with the unevaluated, quoted arguments of the macro call and must return quoted code.  of the format macro(<identifier list>) { <block statements> }
+type MacroLiteral struct {
+	Token      token.Token // macro token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode()      {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range ml.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString(ml.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ","))
+	out.WriteString(") ")
+	out.WriteString(ml.Body.String())
+
+	return out.String()
+}
+
+// SpawnExpression represents running a function on a goroutine of its own, of the format spawn <expression>.  the expression is either a function, which is called without arguments, or a call, of which the function and arguments are evaluated right away but the call itself is made on the new goroutine
+type SpawnExpression struct {
+	Token    token.Token // spawn token
+	Function Expression
+}
+
+func (se *SpawnExpression) expressionNode()      {}
+func (se *SpawnExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpawnExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.TokenLiteral())
+	out.WriteString(" ")
+	out.WriteString(se.Function.String())
+	out.WriteString(")")
+
+	return out.String()
+}
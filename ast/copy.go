@@ -0,0 +1,140 @@
+package ast
+
+import "akdjr/monkey/token"
+
+// Copy returns a deep copy of node.  The copy shares nothing with node, so either can be changed (for example with Modify) without affecting the other
+func Copy(node Node) Node {
+	if isNil(node) {
+		return node
+	}
+
+	switch node := node.(type) {
+	// statements
+	case *Program:
+		return &Program{Statements: copyStatements(node.Statements), Comments: copyComments(node.Comments)}
+	case *LetStatement:
+		stmt := *node
+		stmt.Token = copyToken(node.Token)
+		stmt.Name, _ = Copy(node.Name).(*Identifier)
+		stmt.Value, _ = Copy(node.Value).(Expression)
+		return &stmt
+	case *ReturnStatement:
+		stmt := *node
+		stmt.Token = copyToken(node.Token)
+		stmt.ReturnValue, _ = Copy(node.ReturnValue).(Expression)
+		return &stmt
+	case *ExpressionStatement:
+		stmt := *node
+		stmt.Token = copyToken(node.Token)
+		stmt.Expression, _ = Copy(node.Expression).(Expression)
+		return &stmt
+	case *BlockStatement:
+		block := *node
+		block.Token = copyToken(node.Token)
+		block.Statements = copyStatements(node.Statements)
+		block.Rbrace = copyToken(node.Rbrace)
+		return &block
+
+	// expressions
+	case *Identifier:
+		ident := *node
+		ident.Token = copyToken(node.Token)
+		return &ident
+	case *IntegerLiteral:
+		lit := *node
+		lit.Token = copyToken(node.Token)
+		return &lit
+	case *Boolean:
+		b := *node
+		b.Token = copyToken(node.Token)
+		return &b
+	case *PrefixExpression:
+		exp := *node
+		exp.Token = copyToken(node.Token)
+		exp.Right, _ = Copy(node.Right).(Expression)
+		return &exp
+	case *InfixExpression:
+		exp := *node
+		exp.Token = copyToken(node.Token)
+		exp.Left, _ = Copy(node.Left).(Expression)
+		exp.Right, _ = Copy(node.Right).(Expression)
+		return &exp
+	case *IfExpression:
+		exp := *node
+		exp.Token = copyToken(node.Token)
+		exp.Condition, _ = Copy(node.Condition).(Expression)
+		exp.Consequence, _ = Copy(node.Consequence).(*BlockStatement)
+		exp.Alternative, _ = Copy(node.Alternative).(*BlockStatement)
+		return &exp
+	case *FunctionLiteral:
+		fn := *node
+		fn.Token = copyToken(node.Token)
+		fn.Parameters = copyIdentifiers(node.Parameters)
+		fn.Body, _ = Copy(node.Body).(*BlockStatement)
+		return &fn
+	case *MacroLiteral:
+		macro := *node
+		macro.Token = copyToken(node.Token)
+		macro.Parameters = copyIdentifiers(node.Parameters)
+		macro.Body, _ = Copy(node.Body).(*BlockStatement)
+		return &macro
+	case *SpawnExpression:
+		exp := *node
+		exp.Token = copyToken(node.Token)
+		exp.Function, _ = Copy(node.Function).(Expression)
+		return &exp
+	case *CallExpression:
+		call := *node
+		call.Token = copyToken(node.Token)
+		call.Rparen = copyToken(node.Rparen)
+		call.Function, _ = Copy(node.Function).(Expression)
+		if node.Arguments != nil {
+			call.Arguments = make([]Expression, len(node.Arguments))
+			for i, a := range node.Arguments {
+				call.Arguments[i], _ = Copy(a).(Expression)
+			}
+		}
+		return &call
+	}
+
+	return node
+}
+
+func copyStatements(stmts []Statement) []Statement {
+	if stmts == nil {
+		return nil
+	}
+
+	result := make([]Statement, len(stmts))
+	for i, s := range stmts {
+		result[i], _ = Copy(s).(Statement)
+	}
+
+	return result
+}
+
+func copyIdentifiers(idents []*Identifier) []*Identifier {
+	if idents == nil {
+		return nil
+	}
+
+	result := make([]*Identifier, len(idents))
+	for i, ident := range idents {
+		result[i], _ = Copy(ident).(*Identifier)
+	}
+
+	return result
+}
+
+func copyToken(t token.Token) token.Token {
+	t.Comments = copyComments(t.Comments)
+	return t
+}
+
+func copyComments(comments []token.Comment) []token.Comment {
+	if comments == nil {
+		return nil
+	}
+
+	return append([]token.Comment{}, comments...)
+}
@@ -0,0 +1,490 @@
+package ast
+
+import (
+	"akdjr/monkey/token"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// jsonNode is the JSON form of every node.  Kind names the node type and decides which of the other fields are used
+// slices are held through pointers so that a nil slice (encoded as null) and an empty slice (encoded as []) survive the round trip.  "value" is only ever the scalar value of a literal, every field that holds a node holds nothing but nodes
+type jsonNode struct {
+	Kind        string          `json:"kind"`
+	Token       *jsonToken      `json:"token,omitempty"`
+	Name        *jsonNode       `json:"name,omitempty"`
+	Operator    string          `json:"operator,omitempty"`
+	Left        *jsonNode       `json:"left,omitempty"`
+	Right       *jsonNode       `json:"right,omitempty"`
+	Value       json.RawMessage `json:"value,omitempty"`
+	BoundValue  *jsonNode       `json:"boundValue,omitempty"`
+	ReturnValue *jsonNode       `json:"returnValue,omitempty"`
+	Expression  *jsonNode       `json:"expression,omitempty"`
+	Condition   *jsonNode       `json:"condition,omitempty"`
+	Consequence *jsonNode       `json:"consequence,omitempty"`
+	Alternative *jsonNode       `json:"alternative,omitempty"`
+	Function    *jsonNode       `json:"function,omitempty"`
+	Parameters  *[]*jsonNode    `json:"parameters,omitempty"`
+	Arguments   *[]*jsonNode    `json:"arguments,omitempty"`
+	Body        *jsonNode       `json:"body,omitempty"`
+	Statements  *[]*jsonNode    `json:"statements,omitempty"`
+	Rbrace      *jsonToken      `json:"rbrace,omitempty"`
+	Rparen      *jsonToken      `json:"rparen,omitempty"`
+	Comments    *[]jsonComment  `json:"comments,omitempty"`
+}
+
+// jsonToken is the JSON form of a token.Token, including its position
+type jsonToken struct {
+	Type     token.TokenType `json:"type"`
+	Literal  string          `json:"literal"`
+	Line     int             `json:"line"`
+	Column   int             `json:"column"`
+	Comments *[]jsonComment  `json:"comments,omitempty"`
+}
+
+// jsonComment is the JSON form of a token.Comment
+type jsonComment struct {
+	Text   string `json:"text"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// EncodeJSON encodes node and all of its children as JSON.  Every node carries a "kind" naming its type and the tokens it was parsed from, so DecodeJSON can rebuild an identical tree
+func EncodeJSON(node Node) ([]byte, error) {
+	n, err := toJSONNode(node)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(n)
+}
+
+// DecodeJSON rebuilds a node from JSON produced by EncodeJSON
+func DecodeJSON(data []byte) (Node, error) {
+	var n *jsonNode
+	if err := json.Unmarshal(data, &n); err != nil {
+		return nil, err
+	}
+
+	return fromJSONNode(n)
+}
+
+// DecodeProgramJSON rebuilds a program from JSON produced by EncodeJSON.  It is an error if the JSON holds any other kind of node
+func DecodeProgramJSON(data []byte) (*Program, error) {
+	node, err := DecodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	program, ok := node.(*Program)
+	if !ok {
+		return nil, fmt.Errorf("ast: expected a Program, got %T", node)
+	}
+
+	return program, nil
+}
+
+func toJSONNode(node Node) (*jsonNode, error) {
+	var err error
+	n := &jsonNode{}
+
+	// sub encodes a child node, remembering the first error
+	sub := func(child Node) *jsonNode {
+		if err != nil {
+			return nil
+		}
+
+		var encoded *jsonNode
+		encoded, err = toJSONNode(child)
+		return encoded
+	}
+
+	// value encodes a literal value for the "value" field
+	value := func(v interface{}) json.RawMessage {
+		if err != nil {
+			return nil
+		}
+
+		var encoded []byte
+		encoded, err = json.Marshal(v)
+		return encoded
+	}
+
+	// a typed nil, such as a missing else block, is encoded the same as a missing node
+	if v := reflect.ValueOf(node); !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return nil, nil
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		n.Kind = "Program"
+		n.Statements = toJSONStatements(node.Statements, sub)
+		n.Comments = toJSONComments(node.Comments)
+	case *LetStatement:
+		n.Kind = "LetStatement"
+		n.Token = toJSONToken(node.Token)
+		n.Name = sub(node.Name)
+		n.BoundValue = sub(node.Value)
+	case *ReturnStatement:
+		n.Kind = "ReturnStatement"
+		n.Token = toJSONToken(node.Token)
+		n.ReturnValue = sub(node.ReturnValue)
+	case *ExpressionStatement:
+		n.Kind = "ExpressionStatement"
+		n.Token = toJSONToken(node.Token)
+		n.Expression = sub(node.Expression)
+	case *BlockStatement:
+		n.Kind = "BlockStatement"
+		n.Token = toJSONToken(node.Token)
+		n.Statements = toJSONStatements(node.Statements, sub)
+		n.Rbrace = toJSONToken(node.Rbrace)
+	case *Identifier:
+		n.Kind = "Identifier"
+		n.Token = toJSONToken(node.Token)
+		n.Value = value(node.Value)
+	case *IntegerLiteral:
+		n.Kind = "IntegerLiteral"
+		n.Token = toJSONToken(node.Token)
+		n.Value = value(node.Value)
+	case *Boolean:
+		n.Kind = "Boolean"
+		n.Token = toJSONToken(node.Token)
+		n.Value = value(node.Value)
+	case *PrefixExpression:
+		n.Kind = "PrefixExpression"
+		n.Token = toJSONToken(node.Token)
+		n.Operator = node.Operator
+		n.Right = sub(node.Right)
+	case *InfixExpression:
+		n.Kind = "InfixExpression"
+		n.Token = toJSONToken(node.Token)
+		n.Left = sub(node.Left)
+		n.Operator = node.Operator
+		n.Right = sub(node.Right)
+	case *IfExpression:
+		n.Kind = "IfExpression"
+		n.Token = toJSONToken(node.Token)
+		n.Condition = sub(node.Condition)
+		n.Consequence = sub(node.Consequence)
+		n.Alternative = sub(node.Alternative)
+	case *FunctionLiteral:
+		n.Kind = "FunctionLiteral"
+		n.Token = toJSONToken(node.Token)
+		n.Parameters = toJSONIdentifiers(node.Parameters, sub)
+		n.Body = sub(node.Body)
+	case *MacroLiteral:
+		n.Kind = "MacroLiteral"
+		n.Token = toJSONToken(node.Token)
+		n.Parameters = toJSONIdentifiers(node.Parameters, sub)
+		n.Body = sub(node.Body)
+	case *SpawnExpression:
+		n.Kind = "SpawnExpression"
+		n.Token = toJSONToken(node.Token)
+		n.Function = sub(node.Function)
+	case *CallExpression:
+		n.Kind = "CallExpression"
+		n.Token = toJSONToken(node.Token)
+		n.Function = sub(node.Function)
+		n.Arguments = toJSONExpressions(node.Arguments, sub)
+		n.Rparen = toJSONToken(node.Rparen)
+	default:
+		return nil, fmt.Errorf("ast: cannot encode node of type %T", node)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func toJSONStatements(stmts []Statement, sub func(Node) *jsonNode) *[]*jsonNode {
+	var nodes []*jsonNode
+	if stmts != nil {
+		nodes = make([]*jsonNode, 0, len(stmts))
+	}
+
+	for _, s := range stmts {
+		nodes = append(nodes, sub(s))
+	}
+
+	return &nodes
+}
+
+func toJSONExpressions(exps []Expression, sub func(Node) *jsonNode) *[]*jsonNode {
+	var nodes []*jsonNode
+	if exps != nil {
+		nodes = make([]*jsonNode, 0, len(exps))
+	}
+
+	for _, e := range exps {
+		nodes = append(nodes, sub(e))
+	}
+
+	return &nodes
+}
+
+func toJSONIdentifiers(idents []*Identifier, sub func(Node) *jsonNode) *[]*jsonNode {
+	var nodes []*jsonNode
+	if idents != nil {
+		nodes = make([]*jsonNode, 0, len(idents))
+	}
+
+	for _, i := range idents {
+		nodes = append(nodes, sub(i))
+	}
+
+	return &nodes
+}
+
+func toJSONToken(t token.Token) *jsonToken {
+	return &jsonToken{
+		Type:     t.Type,
+		Literal:  t.Literal,
+		Line:     t.Line,
+		Column:   t.Column,
+		Comments: toJSONComments(t.Comments),
+	}
+}
+
+func toJSONComments(comments []token.Comment) *[]jsonComment {
+	if comments == nil {
+		return nil
+	}
+
+	result := make([]jsonComment, 0, len(comments))
+	for _, c := range comments {
+		result = append(result, jsonComment{Text: c.Text, Line: c.Line, Column: c.Column})
+	}
+
+	return &result
+}
+
+func fromJSONNode(n *jsonNode) (Node, error) {
+	if n == nil {
+		return nil, nil
+	}
+
+	var err error
+
+	// expression decodes a child that must be an expression, remembering the first error
+	expression := func(child *jsonNode) Expression {
+		if err != nil || child == nil {
+			return nil
+		}
+
+		var node Node
+		node, err = fromJSONNode(child)
+		if err != nil {
+			return nil
+		}
+
+		exp, ok := node.(Expression)
+		if !ok {
+			err = fmt.Errorf("ast: %s is not an expression", child.Kind)
+			return nil
+		}
+
+		return exp
+	}
+
+	// block decodes a child that must be a block statement
+	block := func(child *jsonNode) *BlockStatement {
+		if err != nil || child == nil {
+			return nil
+		}
+
+		var node Node
+		node, err = fromJSONNode(child)
+		if err != nil {
+			return nil
+		}
+
+		b, ok := node.(*BlockStatement)
+		if !ok {
+			err = fmt.Errorf("ast: %s is not a BlockStatement", child.Kind)
+			return nil
+		}
+
+		return b
+	}
+
+	// identifier decodes a child that must be an identifier
+	identifier := func(child *jsonNode) *Identifier {
+		exp := expression(child)
+		if exp == nil {
+			return nil
+		}
+
+		ident, ok := exp.(*Identifier)
+		if !ok {
+			err = fmt.Errorf("ast: %s is not an Identifier", child.Kind)
+			return nil
+		}
+
+		return ident
+	}
+
+	// statements decodes a list of statements
+	statements := func(children *[]*jsonNode) []Statement {
+		if err != nil || children == nil || *children == nil {
+			return nil
+		}
+
+		stmts := make([]Statement, 0, len(*children))
+		for _, child := range *children {
+			var node Node
+			node, err = fromJSONNode(child)
+			if err != nil {
+				return nil
+			}
+
+			if node == nil {
+				stmts = append(stmts, nil)
+				continue
+			}
+
+			stmt, ok := node.(Statement)
+			if !ok {
+				err = fmt.Errorf("ast: %s is not a statement", child.Kind)
+				return nil
+			}
+
+			stmts = append(stmts, stmt)
+		}
+
+		return stmts
+	}
+
+	// value decodes the "value" field into v
+	value := func(v interface{}) {
+		if err != nil {
+			return
+		}
+
+		if n.Value == nil {
+			err = fmt.Errorf("ast: %s is missing its value", n.Kind)
+			return
+		}
+
+		err = json.Unmarshal(n.Value, v)
+	}
+
+	var node Node
+
+	switch n.Kind {
+	case "Program":
+		node = &Program{
+			Statements: statements(n.Statements),
+			Comments:   fromJSONComments(n.Comments),
+		}
+	case "LetStatement":
+		node = &LetStatement{Token: fromJSONToken(n.Token), Name: identifier(n.Name), Value: expression(n.BoundValue)}
+	case "ReturnStatement":
+		node = &ReturnStatement{Token: fromJSONToken(n.Token), ReturnValue: expression(n.ReturnValue)}
+	case "ExpressionStatement":
+		node = &ExpressionStatement{Token: fromJSONToken(n.Token), Expression: expression(n.Expression)}
+	case "BlockStatement":
+		node = &BlockStatement{
+			Token:      fromJSONToken(n.Token),
+			Statements: statements(n.Statements),
+			Rbrace:     fromJSONToken(n.Rbrace),
+		}
+	case "Identifier":
+		ident := &Identifier{Token: fromJSONToken(n.Token)}
+		value(&ident.Value)
+		node = ident
+	case "IntegerLiteral":
+		lit := &IntegerLiteral{Token: fromJSONToken(n.Token)}
+		value(&lit.Value)
+		node = lit
+	case "Boolean":
+		b := &Boolean{Token: fromJSONToken(n.Token)}
+		value(&b.Value)
+		node = b
+	case "PrefixExpression":
+		node = &PrefixExpression{
+			Token:    fromJSONToken(n.Token),
+			Operator: n.Operator,
+			Right:    expression(n.Right),
+		}
+	case "InfixExpression":
+		node = &InfixExpression{
+			Token:    fromJSONToken(n.Token),
+			Left:     expression(n.Left),
+			Operator: n.Operator,
+			Right:    expression(n.Right),
+		}
+	case "IfExpression":
+		node = &IfExpression{
+			Token:       fromJSONToken(n.Token),
+			Condition:   expression(n.Condition),
+			Consequence: block(n.Consequence),
+			Alternative: block(n.Alternative),
+		}
+	case "FunctionLiteral":
+		fn := &FunctionLiteral{Token: fromJSONToken(n.Token), Body: block(n.Body)}
+		if n.Parameters != nil && *n.Parameters != nil {
+			fn.Parameters = make([]*Identifier, 0, len(*n.Parameters))
+			for _, p := range *n.Parameters {
+				fn.Parameters = append(fn.Parameters, identifier(p))
+			}
+		}
+		node = fn
+	case "MacroLiteral":
+		macro := &MacroLiteral{Token: fromJSONToken(n.Token), Body: block(n.Body)}
+		if n.Parameters != nil && *n.Parameters != nil {
+			macro.Parameters = make([]*Identifier, 0, len(*n.Parameters))
+			for _, p := range *n.Parameters {
+				macro.Parameters = append(macro.Parameters, identifier(p))
+			}
+		}
+		node = macro
+	case "SpawnExpression":
+		node = &SpawnExpression{Token: fromJSONToken(n.Token), Function: expression(n.Function)}
+	case "CallExpression":
+		call := &CallExpression{Token: fromJSONToken(n.Token), Function: expression(n.Function), Rparen: fromJSONToken(n.Rparen)}
+		if n.Arguments != nil && *n.Arguments != nil {
+			call.Arguments = make([]Expression, 0, len(*n.Arguments))
+			for _, a := range *n.Arguments {
+				call.Arguments = append(call.Arguments, expression(a))
+			}
+		}
+		node = call
+	default:
+		return nil, fmt.Errorf("ast: unknown node kind %q", n.Kind)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+func fromJSONToken(t *jsonToken) token.Token {
+	if t == nil {
+		return token.Token{}
+	}
+
+	return token.Token{
+		Type:     t.Type,
+		Literal:  t.Literal,
+		Line:     t.Line,
+		Column:   t.Column,
+		Comments: fromJSONComments(t.Comments),
+	}
+}
+
+func fromJSONComments(comments *[]jsonComment) []token.Comment {
+	if comments == nil {
+		return nil
+	}
+
+	result := make([]token.Comment, 0, len(*comments))
+	for _, c := range *comments {
+		result = append(result, token.Comment{Text: c.Text, Line: c.Line, Column: c.Column})
+	}
+
+	return result
+}
@@ -0,0 +1,113 @@
+package ast_test
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/parser"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	input := `// adds things
+let add = fn(x, y) { return x + y; };
+let noop = fn() {};
+let max = fn(a, b) {
+  if (a > b) { a } else { b } /* trailing */
+};
+let check = if (!true) { 1 };
+let unless = macro(c, body) { quote(if (!(unquote(c))) { unquote(body) }) };
+let task = spawn fn() { add(1, 2) };
+spawn add(task, 1);
+add(max(-1, 2 * 3), 9223372036854775807) != 5 == false;
+`
+
+	l := lexer.New(input)
+	l.KeepComments(true)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser has errors: %v", p.Errors())
+	}
+
+	data, err := ast.EncodeJSON(program)
+	if err != nil {
+		t.Fatalf("EncodeJSON returned an error: %s", err)
+	}
+
+	decoded, err := ast.DecodeProgramJSON(data)
+	if err != nil {
+		t.Fatalf("DecodeProgramJSON returned an error: %s", err)
+	}
+
+	if !reflect.DeepEqual(program, decoded) {
+		t.Fatalf("decoded program is not equal to the original.\noriginal=%s\ndecoded=%s", program.String(), decoded.String())
+	}
+}
+
+func TestJSONEncoding(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"-x",
+			`{"kind":"ExpressionStatement","token":{"type":"-","literal":"-","line":1,"column":1},` +
+				`"expression":{"kind":"PrefixExpression","token":{"type":"-","literal":"-","line":1,"column":1},"operator":"-",` +
+				`"right":{"kind":"Identifier","token":{"type":"IDENTIFIER","literal":"x","line":1,"column":2},"value":"x"}}}`,
+		},
+		{
+			// the expression a let statement binds has a field of its own, "value" is always a scalar
+			"let a = 5;",
+			`{"kind":"LetStatement","token":{"type":"LET","literal":"let","line":1,"column":1},` +
+				`"name":{"kind":"Identifier","token":{"type":"IDENTIFIER","literal":"a","line":1,"column":5},"value":"a"},` +
+				`"boundValue":{"kind":"IntegerLiteral","token":{"type":"INT","literal":"5","line":1,"column":9},"value":5}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+
+		data, err := ast.EncodeJSON(program.Statements[0])
+		if err != nil {
+			t.Fatalf("EncodeJSON returned an error: %s", err)
+		}
+
+		if string(data) != tt.expected {
+			t.Errorf("wrong encoding of %q.\nexpected=%s\ngot=%s", tt.input, tt.expected, string(data))
+		}
+	}
+}
+
+func TestJSONDecodeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"kind":"Nonsense"}`, `ast: unknown node kind "Nonsense"`},
+		{`{"kind":"ExpressionStatement","expression":{"kind":"LetStatement"}}`, "ast: LetStatement is not an expression"},
+		{`{"kind":"IfExpression","consequence":{"kind":"Identifier","value":"x"}}`, "ast: Identifier is not a BlockStatement"},
+		{`{"kind":"Identifier"}`, "ast: Identifier is missing its value"},
+		{`{"kind":"Boolean","value":"yes"}`, "json: cannot unmarshal string into Go value of type bool"},
+	}
+
+	for _, tt := range tests {
+		_, err := ast.DecodeJSON([]byte(tt.input))
+		if err == nil {
+			t.Errorf("expected an error decoding %s", tt.input)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("wrong error decoding %s. expected=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+
+	if _, err := ast.DecodeProgramJSON([]byte(`{"kind":"Identifier","value":"x"}`)); err == nil {
+		t.Errorf("expected an error decoding a non-program as a program")
+	}
+}
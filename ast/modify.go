@@ -0,0 +1,129 @@
+package ast
+
+import "fmt"
+
+// ModifierFunc is called by Modify for every node in a tree.  The node it returns takes the place of the node it was given
+type ModifierFunc func(Node) Node
+
+// Modify rewrites an AST from the bottom up.  The children of node are modified first and stored back into node, then node itself is passed to modifier and the result is returned
+// Modify panics when a child is replaced with a node of the wrong kind for its position, for example a statement where an expression is expected, rather than leave a hole in the tree for later code to trip over.  a child replaced with nil becomes missing
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	// statements
+	case *Program:
+		for i, statement := range node.Statements {
+			node.Statements[i] = asStatement(node, modifyIfPresent(statement, modifier))
+		}
+	case *LetStatement:
+		node.Name = asIdentifier(node, modifyIfPresent(node.Name, modifier))
+		node.Value = asExpression(node, modifyIfPresent(node.Value, modifier))
+	case *ReturnStatement:
+		node.ReturnValue = asExpression(node, modifyIfPresent(node.ReturnValue, modifier))
+	case *ExpressionStatement:
+		node.Expression = asExpression(node, modifyIfPresent(node.Expression, modifier))
+	case *BlockStatement:
+		for i, statement := range node.Statements {
+			node.Statements[i] = asStatement(node, modifyIfPresent(statement, modifier))
+		}
+
+	// expressions
+	case *PrefixExpression:
+		node.Right = asExpression(node, modifyIfPresent(node.Right, modifier))
+	case *InfixExpression:
+		node.Left = asExpression(node, modifyIfPresent(node.Left, modifier))
+		node.Right = asExpression(node, modifyIfPresent(node.Right, modifier))
+	case *IfExpression:
+		node.Condition = asExpression(node, modifyIfPresent(node.Condition, modifier))
+		node.Consequence = asBlock(node, modifyIfPresent(node.Consequence, modifier))
+		node.Alternative = asBlock(node, modifyIfPresent(node.Alternative, modifier))
+	case *FunctionLiteral:
+		for i, parameter := range node.Parameters {
+			node.Parameters[i] = asIdentifier(node, modifyIfPresent(parameter, modifier))
+		}
+
+		node.Body = asBlock(node, modifyIfPresent(node.Body, modifier))
+	case *MacroLiteral:
+		for i, parameter := range node.Parameters {
+			node.Parameters[i] = asIdentifier(node, modifyIfPresent(parameter, modifier))
+		}
+
+		node.Body = asBlock(node, modifyIfPresent(node.Body, modifier))
+	case *SpawnExpression:
+		node.Function = asExpression(node, modifyIfPresent(node.Function, modifier))
+	case *CallExpression:
+		node.Function = asExpression(node, modifyIfPresent(node.Function, modifier))
+
+		for i, argument := range node.Arguments {
+			node.Arguments[i] = asExpression(node, modifyIfPresent(argument, modifier))
+		}
+	}
+
+	return modifier(node)
+}
+
+// modifyIfPresent modifies node unless it is missing, a missing node stays missing
+func modifyIfPresent(node Node, modifier ModifierFunc) Node {
+	if isNil(node) {
+		return node
+	}
+
+	return Modify(node, modifier)
+}
+
+// the as functions check the kind of a modified child of parent.  a missing child is always allowed
+
+func asStatement(parent Node, child Node) Statement {
+	if isNil(child) {
+		return nil
+	}
+
+	statement, ok := child.(Statement)
+	if !ok {
+		wrongKind(parent, child, "a statement")
+	}
+
+	return statement
+}
+
+func asExpression(parent Node, child Node) Expression {
+	if isNil(child) {
+		return nil
+	}
+
+	expression, ok := child.(Expression)
+	if !ok {
+		wrongKind(parent, child, "an expression")
+	}
+
+	return expression
+}
+
+func asIdentifier(parent Node, child Node) *Identifier {
+	if isNil(child) {
+		return nil
+	}
+
+	identifier, ok := child.(*Identifier)
+	if !ok {
+		wrongKind(parent, child, "an identifier")
+	}
+
+	return identifier
+}
+
+func asBlock(parent Node, child Node) *BlockStatement {
+	if isNil(child) {
+		return nil
+	}
+
+	block, ok := child.(*BlockStatement)
+	if !ok {
+		wrongKind(parent, child, "a block")
+	}
+
+	return block
+}
+
+func wrongKind(parent Node, child Node, want string) {
+	panic(fmt.Sprintf("ast: Modify replaced a child of %T with %T, where %s is expected", parent, child, want))
+}
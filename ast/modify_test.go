@@ -0,0 +1,232 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok {
+			return node
+		}
+
+		if integer.Value != 1 {
+			return node
+		}
+
+		integer.Value = 2
+		return integer
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{
+			one(),
+			two(),
+		},
+		{
+			&Program{
+				Statements: []Statement{
+					&ExpressionStatement{Expression: one()},
+				},
+			},
+			&Program{
+				Statements: []Statement{
+					&ExpressionStatement{Expression: two()},
+				},
+			},
+		},
+		{
+			&InfixExpression{Left: one(), Operator: "+", Right: two()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&InfixExpression{Left: two(), Operator: "+", Right: one()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&PrefixExpression{Operator: "-", Right: one()},
+			&PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			&IfExpression{
+				Condition: one(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+				Alternative: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&IfExpression{
+				Condition: two(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+				Alternative: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&IfExpression{
+				Condition: one(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&IfExpression{
+				Condition: two(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&ReturnStatement{ReturnValue: one()},
+			&ReturnStatement{ReturnValue: two()},
+		},
+		{
+			&LetStatement{Name: &Identifier{Value: "x"}, Value: one()},
+			&LetStatement{Name: &Identifier{Value: "x"}, Value: two()},
+		},
+		{
+			&FunctionLiteral{
+				Parameters: []*Identifier{},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&FunctionLiteral{
+				Parameters: []*Identifier{},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{one(), two(), one()}},
+			&CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{two(), two(), two()}},
+		},
+	}
+
+	for i, tt := range tests {
+		modified := Modify(tt.input, turnOneIntoTwo)
+
+		if !reflect.DeepEqual(modified, tt.expected) {
+			t.Errorf("tests[%d] - not equal. got=%#v, want=%#v", i, modified, tt.expected)
+		}
+	}
+}
+
+func TestModifyParameters(t *testing.T) {
+	rename := func(node Node) Node {
+		ident, ok := node.(*Identifier)
+		if !ok || ident.Value != "x" {
+			return node
+		}
+
+		return &Identifier{Value: "renamed"}
+	}
+
+	function := &FunctionLiteral{
+		Parameters: []*Identifier{{Value: "x"}, {Value: "y"}},
+		Body: &BlockStatement{
+			Statements: []Statement{
+				&ExpressionStatement{Expression: &Identifier{Value: "x"}},
+			},
+		},
+	}
+
+	modified := Modify(function, rename).(*FunctionLiteral)
+
+	if modified.String() != "(renamed,y) renamed" {
+		t.Errorf("function not modified. got=%q", modified.String())
+	}
+}
+
+func TestModifyWrongKind(t *testing.T) {
+	tests := []struct {
+		input    Node
+		modifier ModifierFunc
+		expected string
+	}{
+		{
+			&ExpressionStatement{Expression: &IntegerLiteral{Value: 1}},
+			func(node Node) Node {
+				if _, ok := node.(*IntegerLiteral); ok {
+					return &LetStatement{}
+				}
+				return node
+			},
+			"ast: Modify replaced a child of *ast.ExpressionStatement with *ast.LetStatement, where an expression is expected",
+		},
+		{
+			&FunctionLiteral{Parameters: []*Identifier{{Value: "x"}}, Body: &BlockStatement{}},
+			func(node Node) Node {
+				if _, ok := node.(*Identifier); ok {
+					return &IntegerLiteral{Value: 1}
+				}
+				return node
+			},
+			"ast: Modify replaced a child of *ast.FunctionLiteral with *ast.IntegerLiteral, where an identifier is expected",
+		},
+		{
+			&IfExpression{Condition: &Boolean{Value: true}, Consequence: &BlockStatement{}},
+			func(node Node) Node {
+				if _, ok := node.(*BlockStatement); ok {
+					return &ExpressionStatement{}
+				}
+				return node
+			},
+			"ast: Modify replaced a child of *ast.IfExpression with *ast.ExpressionStatement, where a block is expected",
+		},
+	}
+
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if r := recover(); r != tt.expected {
+					t.Errorf("wrong panic.\nexpected=%q\ngot=%v", tt.expected, r)
+				}
+			}()
+
+			Modify(tt.input, tt.modifier)
+		}()
+	}
+
+	// a child replaced with nil is missing, which is not the wrong kind
+	statement := Modify(&ExpressionStatement{Expression: &IntegerLiteral{Value: 1}}, func(node Node) Node {
+		if _, ok := node.(*IntegerLiteral); ok {
+			return nil
+		}
+		return node
+	}).(*ExpressionStatement)
+
+	if statement.Expression != nil {
+		t.Errorf("expected a missing expression. got=%#v", statement.Expression)
+	}
+}
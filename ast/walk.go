@@ -0,0 +1,101 @@
+package ast
+
+import "reflect"
+
+// Visitor is called by Walk for every node in a tree.  If Visit returns a non-nil visitor w, Walk visits each child of node with w, followed by a call of w.Visit(nil)
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order.  It starts by calling v.Visit(node), and if the visitor it returns is not nil, Walk is called recursively with it for each of the non-nil children of node
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	// statements
+	case *Program:
+		for _, s := range n.Statements {
+			walkIfPresent(v, s)
+		}
+	case *LetStatement:
+		walkIfPresent(v, n.Name)
+		walkIfPresent(v, n.Value)
+	case *ReturnStatement:
+		walkIfPresent(v, n.ReturnValue)
+	case *ExpressionStatement:
+		walkIfPresent(v, n.Expression)
+	case *BlockStatement:
+		for _, s := range n.Statements {
+			walkIfPresent(v, s)
+		}
+
+	// expressions
+	case *Identifier, *IntegerLiteral, *Boolean:
+		// nothing to do, these have no children
+	case *PrefixExpression:
+		walkIfPresent(v, n.Right)
+	case *InfixExpression:
+		walkIfPresent(v, n.Left)
+		walkIfPresent(v, n.Right)
+	case *IfExpression:
+		walkIfPresent(v, n.Condition)
+		walkIfPresent(v, n.Consequence)
+		walkIfPresent(v, n.Alternative)
+	case *FunctionLiteral:
+		for _, p := range n.Parameters {
+			walkIfPresent(v, p)
+		}
+		walkIfPresent(v, n.Body)
+	case *MacroLiteral:
+		for _, p := range n.Parameters {
+			walkIfPresent(v, p)
+		}
+		walkIfPresent(v, n.Body)
+	case *SpawnExpression:
+		walkIfPresent(v, n.Function)
+	case *CallExpression:
+		walkIfPresent(v, n.Function)
+		for _, a := range n.Arguments {
+			walkIfPresent(v, a)
+		}
+	}
+
+	v.Visit(nil)
+}
+
+// walkIfPresent walks node unless it is missing.  a missing child can be a nil interface or a typed nil, such as an IfExpression without an else block
+func walkIfPresent(v Visitor, node Node) {
+	if isNil(node) {
+		return
+	}
+
+	Walk(v, node)
+}
+
+// isNil reports whether node is nil or holds a nil pointer
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+
+	value := reflect.ValueOf(node)
+	return value.Kind() == reflect.Ptr && value.IsNil()
+}
+
+// inspector adapts a function to the Visitor interface for Inspect
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order, calling f(node) for each node.  If f returns true, Inspect continues with the children of node, followed by a call of f(nil)
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
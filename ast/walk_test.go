@@ -0,0 +1,117 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	// fn(x) { if (x) { -x } else { f(x, 1 + true) } }
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Name: &Identifier{Value: "g"},
+				Value: &FunctionLiteral{
+					Parameters: []*Identifier{{Value: "x"}},
+					Body: &BlockStatement{
+						Statements: []Statement{
+							&ExpressionStatement{
+								Expression: &IfExpression{
+									Condition: &Identifier{Value: "x"},
+									Consequence: &BlockStatement{
+										Statements: []Statement{
+											&ReturnStatement{ReturnValue: &PrefixExpression{Operator: "-", Right: &Identifier{Value: "x"}}},
+										},
+									},
+									Alternative: &BlockStatement{
+										Statements: []Statement{
+											&ExpressionStatement{
+												Expression: &CallExpression{
+													Function: &Identifier{Value: "f"},
+													Arguments: []Expression{
+														&Identifier{Value: "x"},
+														&InfixExpression{Left: &IntegerLiteral{Value: 1}, Operator: "+", Right: &Boolean{Value: true}},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	visited := []string{}
+	depth := 0
+	maxDepth := 0
+
+	Inspect(program, func(node Node) bool {
+		if node == nil {
+			depth--
+			return false
+		}
+
+		depth++
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+
+		visited = append(visited, strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast."))
+		return true
+	})
+
+	expected := []string{
+		"Program", "LetStatement", "Identifier", "FunctionLiteral", "Identifier", "BlockStatement",
+		"ExpressionStatement", "IfExpression", "Identifier",
+		"BlockStatement", "ReturnStatement", "PrefixExpression", "Identifier",
+		"BlockStatement", "ExpressionStatement", "CallExpression", "Identifier", "Identifier",
+		"InfixExpression", "IntegerLiteral", "Boolean",
+	}
+
+	if strings.Join(visited, " ") != strings.Join(expected, " ") {
+		t.Errorf("wrong visit order.\nexpected=%v\ngot=%v", expected, visited)
+	}
+
+	if depth != 0 {
+		t.Errorf("every visit should be closed by a nil visit. depth=%d", depth)
+	}
+
+	if maxDepth != 11 {
+		t.Errorf("wrong maximum depth. expected=11, got=%d", maxDepth)
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			&ExpressionStatement{
+				Expression: &FunctionLiteral{
+					Parameters: []*Identifier{{Value: "x"}},
+					Body:       &BlockStatement{Statements: []Statement{}},
+				},
+			},
+			&ExpressionStatement{Expression: &Identifier{Value: "y"}},
+		},
+	}
+
+	identifiers := []string{}
+
+	Inspect(program, func(node Node) bool {
+		if ident, ok := node.(*Identifier); ok {
+			identifiers = append(identifiers, ident.Value)
+		}
+
+		// do not descend into function literals
+		_, isFunction := node.(*FunctionLiteral)
+		return !isFunction
+	})
+
+	if strings.Join(identifiers, ",") != "y" {
+		t.Errorf("wrong identifiers visited. got=%v", identifiers)
+	}
+}
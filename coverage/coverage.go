@@ -0,0 +1,256 @@
+package coverage
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/evaluator"
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/object"
+	"akdjr/monkey/parser"
+	"sort"
+	"strings"
+)
+
+// Coverage records how many times the statements and the branches of if expressions of programs run.  programs are told apart by their file names, and the counts of a program that runs more than once add up
+type Coverage struct {
+	files []*file
+	names map[string]*file
+}
+
+// file is the coverage of a program.  nodes are found by their position, as the program that runs is parsed from the same source, but not into the same tree
+type file struct {
+	name       string
+	lines      []string
+	statements []*statement
+	ifs        []*branches
+
+	positions map[position]*statement
+	blocks    map[position]*branch
+	ifsAt     map[position]*branches
+
+	// pending are the if expressions being evaluated, the innermost last, to find out when one without an else leaves its consequence out
+	pending []*pending
+}
+
+// position is the line and column of the first token of a node
+type position struct {
+	line   int
+	column int
+}
+
+// statement is a statement and how many times it ran
+type statement struct {
+	position
+	count int
+}
+
+// branches are the branches of an if expression: its consequence, and its alternative.  an if expression without an else has an alternative all the same, it is taken whenever the consequence is not
+type branches struct {
+	position
+	consequence branch
+	alternative branch
+	count       int
+}
+
+// branch is a branch of an if expression and how many times it was taken
+type branch struct {
+	count int
+}
+
+type pending struct {
+	branches *branches
+	taken    bool
+}
+
+// New creates a Coverage with no programs
+func New() *Coverage {
+	return &Coverage{names: map[string]*file{}}
+}
+
+// Hooks returns the hooks that record the coverage of the program in source, named filename, as it runs
+func (c *Coverage) Hooks(filename string, source string) *evaluator.Hooks {
+	f, ok := c.names[filename]
+	if !ok {
+		f = newFile(filename, source)
+		c.files = append(c.files, f)
+		c.names[filename] = f
+	}
+
+	return &evaluator.Hooks{
+		BeforeNode: f.beforeNode,
+		AfterNode:  f.afterNode,
+	}
+}
+
+// newFile finds the statements and if expressions of the program in source.  macro definitions are left out, they never run
+func newFile(name string, source string) *file {
+	f := &file{
+		name:      name,
+		lines:     strings.Split(strings.TrimSuffix(source, "\n"), "\n"),
+		positions: map[position]*statement{},
+		blocks:    map[position]*branch{},
+		ifsAt:     map[position]*branches{},
+	}
+
+	program := parser.New(lexer.New(source)).ParseProgram()
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		if let, ok := node.(*ast.LetStatement); ok {
+			if _, ok := let.Value.(*ast.MacroLiteral); ok {
+				return false
+			}
+		}
+
+		if p, ok := statementPosition(node); ok {
+			s := &statement{position: p}
+			f.statements = append(f.statements, s)
+			f.positions[p] = s
+		}
+
+		if ie, ok := node.(*ast.IfExpression); ok && ie.Token.Line > 0 {
+			b := &branches{position: position{ie.Token.Line, ie.Token.Column}}
+			f.ifs = append(f.ifs, b)
+			f.ifsAt[b.position] = b
+
+			f.blocks[blockPosition(ie.Consequence)] = &b.consequence
+			if ie.Alternative != nil {
+				f.blocks[blockPosition(ie.Alternative)] = &b.alternative
+			}
+		}
+
+		return true
+	})
+
+	sort.Slice(f.statements, func(i, j int) bool { return before(f.statements[i].position, f.statements[j].position) })
+	sort.Slice(f.ifs, func(i, j int) bool { return before(f.ifs[i].position, f.ifs[j].position) })
+
+	return f
+}
+
+func (f *file) beforeNode(node ast.Node, env *object.Environment) {
+	if p, ok := statementPosition(node); ok {
+		if s, ok := f.positions[p]; ok {
+			s.count++
+		}
+		return
+	}
+
+	switch node := node.(type) {
+	case *ast.IfExpression:
+		if b, ok := f.ifsAt[position{node.Token.Line, node.Token.Column}]; ok {
+			b.count++
+			f.pending = append(f.pending, &pending{branches: b})
+		}
+	case *ast.BlockStatement:
+		if b, ok := f.blocks[blockPosition(node)]; ok {
+			b.count++
+
+			if len(f.pending) > 0 {
+				f.pending[len(f.pending)-1].taken = true
+			}
+		}
+	}
+}
+
+func (f *file) afterNode(node ast.Node, env *object.Environment, result object.Object) {
+	ie, ok := node.(*ast.IfExpression)
+	if !ok || len(f.pending) == 0 {
+		return
+	}
+
+	p := f.pending[len(f.pending)-1]
+	if p.branches.position != (position{ie.Token.Line, ie.Token.Column}) {
+		return
+	}
+
+	f.pending = f.pending[:len(f.pending)-1]
+
+	// an if expression without an else takes its missing alternative when its condition is false, which is when neither branch ran and the condition did not fail
+	if _, failed := result.(*object.Error); !p.taken && !failed && ie.Alternative == nil {
+		p.branches.alternative.count++
+	}
+}
+
+// statementPosition returns the position of node if it is a statement other than a block
+func statementPosition(node ast.Node) (position, bool) {
+	var p position
+
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		p = position{node.Token.Line, node.Token.Column}
+	case *ast.ReturnStatement:
+		p = position{node.Token.Line, node.Token.Column}
+	case *ast.ExpressionStatement:
+		p = position{node.Token.Line, node.Token.Column}
+	}
+
+	return p, p.line > 0
+}
+
+func blockPosition(block *ast.BlockStatement) position {
+	return position{block.Token.Line, block.Token.Column}
+}
+
+func before(p position, q position) bool {
+	return p.line < q.line || p.line == q.line && p.column < q.column
+}
+
+// Summary is how much of a program, or of all programs, ran
+type Summary struct {
+	Statements    int
+	StatementsRun int
+	Branches      int
+	BranchesTaken int
+}
+
+// StatementPercent returns the percentage of statements that ran, 100 when there are none
+func (s Summary) StatementPercent() float64 {
+	return percent(s.StatementsRun, s.Statements)
+}
+
+// BranchPercent returns the percentage of branches that were taken, 100 when there are none
+func (s Summary) BranchPercent() float64 {
+	return percent(s.BranchesTaken, s.Branches)
+}
+
+func percent(n int, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+
+	return 100 * float64(n) / float64(total)
+}
+
+// Summary returns how much of the programs ran, all together
+func (c *Coverage) Summary() Summary {
+	var total Summary
+
+	for _, f := range c.files {
+		s := f.summary()
+		total.Statements += s.Statements
+		total.StatementsRun += s.StatementsRun
+		total.Branches += s.Branches
+		total.BranchesTaken += s.BranchesTaken
+	}
+
+	return total
+}
+
+func (f *file) summary() Summary {
+	s := Summary{Statements: len(f.statements), Branches: 2 * len(f.ifs)}
+
+	for _, stmt := range f.statements {
+		if stmt.count > 0 {
+			s.StatementsRun++
+		}
+	}
+
+	for _, b := range f.ifs {
+		for _, branch := range []branch{b.consequence, b.alternative} {
+			if branch.count > 0 {
+				s.BranchesTaken++
+			}
+		}
+	}
+
+	return s
+}
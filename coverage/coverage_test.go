@@ -0,0 +1,141 @@
+package coverage
+
+import (
+	"akdjr/monkey/interpreter"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// run runs program with the hooks of c, as the file program.monkey
+func run(t *testing.T, c *Coverage, program string) {
+	i := interpreter.New()
+	i.Hooks = c.Hooks("program.monkey", program)
+
+	if _, err := i.Run(program); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Summary
+	}{
+		{"let a = 1;\na + 1;", Summary{Statements: 2, StatementsRun: 2}},
+		{"let f = fn(x) { x };\n1;", Summary{Statements: 3, StatementsRun: 2}},
+		{"if (true) { 1 } else { 2 };", Summary{Statements: 3, StatementsRun: 2, Branches: 2, BranchesTaken: 1}},
+		{"if (false) { 1 } else { 2 };", Summary{Statements: 3, StatementsRun: 2, Branches: 2, BranchesTaken: 1}},
+		// an if without an else takes its alternative when the condition is false
+		{"if (false) { 1 };", Summary{Statements: 2, StatementsRun: 1, Branches: 2, BranchesTaken: 1}},
+		{"if (true) { 1 };", Summary{Statements: 2, StatementsRun: 2, Branches: 2, BranchesTaken: 1}},
+		{"let f = fn(x) { if (x) { 1 } };\nf(true);\nf(false);", Summary{Statements: 5, StatementsRun: 5, Branches: 2, BranchesTaken: 2}},
+		// the branches of an if in tail position are followed too
+		{"let f = fn(x) { if (x) { 1 } else { f(true) } };\nf(false);", Summary{Statements: 5, StatementsRun: 5, Branches: 2, BranchesTaken: 2}},
+		// and so are those of an if that is not
+		{"let f = fn(x) { if (x) { 1 }; 2 };\nf(false);\nf(true);", Summary{Statements: 6, StatementsRun: 6, Branches: 2, BranchesTaken: 2}},
+		{"let f = fn(x) { if (x) { 1 } else { 3 }; 2 };\nf(false);", Summary{Statements: 6, StatementsRun: 5, Branches: 2, BranchesTaken: 1}},
+		{"let f = fn(x, y) { if (x) { if (y) { 1 }; 2 }; 3 };\nf(true, false);", Summary{Statements: 7, StatementsRun: 6, Branches: 4, BranchesTaken: 2}},
+		{"let m = macro(x) { quote(unquote(x)) };\nm(1);", Summary{Statements: 1, StatementsRun: 1}},
+	}
+
+	for _, tt := range tests {
+		c := New()
+		run(t, c, tt.input)
+
+		if s := c.Summary(); s != tt.expected {
+			t.Errorf("wrong summary for %q.\nexpected=%+v\ngot=%+v", tt.input, tt.expected, s)
+		}
+	}
+}
+
+func TestRunsAddUp(t *testing.T) {
+	program := "let f = fn(x) { if (x) { 1 } else { 2 } };\nf(x);"
+
+	c := New()
+	run(t, c, "let x = true;\n"+program)
+	run(t, c, "let x = false;\n"+program)
+
+	// the source of the first run is the one measured, but the second one took the other branch
+	if s := c.Summary(); s.BranchesTaken != 2 {
+		t.Errorf("the branches of both runs were not counted. got=%+v", s)
+	}
+}
+
+const program = `let f = fn(x) {
+  if (x > 1) {
+    x
+  } else {
+    0
+  }
+};
+f(2);`
+
+func TestText(t *testing.T) {
+	c := New()
+	run(t, c, program)
+
+	var out bytes.Buffer
+	if err := c.WriteText(&out); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := `program.monkey:5:5: statement never ran
+program.monkey:2:3: if never took its alternative
+program.monkey: 80.0% of statements (4/5), 50.0% of branches (1/2)
+`
+
+	if out.String() != expected {
+		t.Errorf("wrong report.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestLcov(t *testing.T) {
+	c := New()
+	run(t, c, program)
+
+	var out bytes.Buffer
+	if err := c.WriteLcov(&out); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := `TN:
+SF:program.monkey
+BRDA:2,0,0,1
+BRDA:2,0,1,0
+BRF:2
+BRH:1
+DA:1,1
+DA:2,1
+DA:3,1
+DA:5,0
+DA:8,1
+LF:5
+LH:4
+end_of_record
+`
+
+	if out.String() != expected {
+		t.Errorf("wrong tracefile.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestHTML(t *testing.T) {
+	c := New()
+	run(t, c, program)
+
+	var out bytes.Buffer
+	if err := c.WriteHTML(&out); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, expected := range []string{
+		`<tr class="partial"><td class="number">2</td><td class="count">1x</td><td class="source">  if (x &gt; 1) {</td></tr>`,
+		`<tr class="uncovered"><td class="number">5</td><td class="count">0x</td><td class="source">    0</td></tr>`,
+		`<tr class=""><td class="number">4</td><td class="count"></td><td class="source">  } else {</td></tr>`,
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("the page does not contain %q.\ngot=%s", expected, out.String())
+		}
+	}
+}
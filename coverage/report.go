@@ -0,0 +1,216 @@
+package coverage
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+)
+
+// WriteText writes a report of what did not run: a line for every statement that never ran and every branch that was never taken, followed by the percentages covered of every program
+func (c *Coverage) WriteText(w io.Writer) error {
+	for _, f := range c.files {
+		for _, s := range f.statements {
+			if s.count == 0 {
+				if _, err := fmt.Fprintf(w, "%s:%d:%d: statement never ran\n", f.name, s.line, s.column); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, b := range f.ifs {
+			if b.consequence.count == 0 {
+				if _, err := fmt.Fprintf(w, "%s:%d:%d: if never took its consequence\n", f.name, b.line, b.column); err != nil {
+					return err
+				}
+			}
+
+			if b.alternative.count == 0 {
+				if _, err := fmt.Fprintf(w, "%s:%d:%d: if never took its alternative\n", f.name, b.line, b.column); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, f := range c.files {
+		if _, err := fmt.Fprintf(w, "%s: %s\n", f.name, describe(f.summary())); err != nil {
+			return err
+		}
+	}
+
+	if len(c.files) > 1 {
+		if _, err := fmt.Fprintf(w, "total: %s\n", describe(c.Summary())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func describe(s Summary) string {
+	return fmt.Sprintf("%.1f%% of statements (%d/%d), %.1f%% of branches (%d/%d)", s.StatementPercent(), s.StatementsRun, s.Statements, s.BranchPercent(), s.BranchesTaken, s.Branches)
+}
+
+// WriteLcov writes the coverage in the lcov tracefile format, which tools such as genhtml and most code coverage services read.  every if expression is a block of two branches, its consequence and its alternative
+func (c *Coverage) WriteLcov(w io.Writer) error {
+	for _, f := range c.files {
+		if _, err := fmt.Fprintf(w, "TN:\nSF:%s\n", f.name); err != nil {
+			return err
+		}
+
+		for i, b := range f.ifs {
+			for n, branch := range []branch{b.consequence, b.alternative} {
+				taken := "-"
+				if b.count > 0 {
+					taken = strconv.Itoa(branch.count)
+				}
+
+				if _, err := fmt.Fprintf(w, "BRDA:%d,%d,%d,%s\n", b.line, i, n, taken); err != nil {
+					return err
+				}
+			}
+		}
+
+		s := f.summary()
+		if _, err := fmt.Fprintf(w, "BRF:%d\nBRH:%d\n", s.Branches, s.BranchesTaken); err != nil {
+			return err
+		}
+
+		found, hit := 0, 0
+		for _, l := range f.annotate() {
+			if !l.Code {
+				continue
+			}
+
+			found++
+			if l.Count > 0 {
+				hit++
+			}
+
+			if _, err := fmt.Fprintf(w, "DA:%d,%d\n", l.Number, l.Count); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "LF:%d\nLH:%d\nend_of_record\n", found, hit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// line is a line of source as the reports show it.  Code is set when a statement starts on the line, and Count is how many times the most run of them ran
+// Class is covered when everything on the line ran, uncovered when nothing did and partial otherwise, or empty when there is nothing to run on the line
+type line struct {
+	Number int
+	Text   string
+	Code   bool
+	Count  int
+	Class  string
+}
+
+// annotate returns the lines of the program with their coverage
+func (f *file) annotate() []line {
+	lines := make([]line, len(f.lines))
+	ran := make([]int, len(f.lines))
+	missed := make([]int, len(f.lines))
+
+	for i, text := range f.lines {
+		lines[i] = line{Number: i + 1, Text: text}
+	}
+
+	for _, s := range f.statements {
+		i := s.line - 1
+		if i >= len(lines) {
+			continue
+		}
+
+		lines[i].Code = true
+		if s.count > lines[i].Count {
+			lines[i].Count = s.count
+		}
+
+		if s.count > 0 {
+			ran[i]++
+		} else {
+			missed[i]++
+		}
+	}
+
+	for _, b := range f.ifs {
+		i := b.line - 1
+		if i >= len(lines) {
+			continue
+		}
+
+		for _, branch := range []branch{b.consequence, b.alternative} {
+			if branch.count > 0 {
+				ran[i]++
+			} else {
+				missed[i]++
+			}
+		}
+	}
+
+	for i := range lines {
+		switch {
+		case ran[i] > 0 && missed[i] > 0:
+			lines[i].Class = "partial"
+		case ran[i] > 0:
+			lines[i].Class = "covered"
+		case missed[i] > 0:
+			lines[i].Class = "uncovered"
+		}
+	}
+
+	return lines
+}
+
+// WriteHTML writes a page with the source of every program, each line colored by whether it ran and annotated with how many times it did
+func (c *Coverage) WriteHTML(w io.Writer) error {
+	type page struct {
+		Name    string
+		Summary string
+		Lines   []line
+	}
+
+	pages := []page{}
+	for _, f := range c.files {
+		pages = append(pages, page{Name: f.name, Summary: describe(f.summary()), Lines: f.annotate()})
+	}
+
+	return htmlReport.Execute(w, struct {
+		Summary string
+		Files   []page
+	}{describe(c.Summary()), pages})
+}
+
+var htmlReport = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>monkey coverage</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; font-family: monospace; }
+td { padding: 0 8px; white-space: pre; }
+td.number, td.count { text-align: right; color: #888; }
+tr.covered td.source { background: #dfd; }
+tr.uncovered td.source { background: #fdd; }
+tr.partial td.source { background: #ffd; }
+</style>
+</head>
+<body>
+<h1>Coverage: {{.Summary}}</h1>
+{{range .Files}}
+<h2>{{.Name}}</h2>
+<p>{{.Summary}}</p>
+<table>
+{{range .Lines}}<tr class="{{.Class}}"><td class="number">{{.Number}}</td><td class="count">{{if .Code}}{{.Count}}x{{end}}</td><td class="source">{{.Text}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
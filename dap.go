@@ -0,0 +1,23 @@
+package main
+
+import (
+	"akdjr/monkey/dap"
+	"fmt"
+	"os"
+)
+
+// dapCommand implements "monkey dap" and returns the process exit code
+// the debug adapter talks to the editor over stdin and stdout, the program to debug is named by the editor's launch request
+func dapCommand(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: monkey dap")
+		return 2
+	}
+
+	if err := dap.New(os.Stdin, os.Stdout).Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
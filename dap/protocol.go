@@ -0,0 +1,191 @@
+package dap
+
+import "encoding/json"
+
+// the parts of the Debug Adapter Protocol the server implements, see https://microsoft.github.io/debug-adapter-protocol/specification
+
+// message is a request, response or event as it is read.  Type says which, and only the fields of that type are set
+type message struct {
+	Seq  int    `json:"seq"`
+	Type string `json:"type"`
+
+	// requests, and responses, which have the Command of their request
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+
+	// responses
+	RequestSeq int    `json:"request_seq"`
+	Success    bool   `json:"success"`
+	Message    string `json:"message"`
+
+	// events
+	Event string `json:"event"`
+
+	// responses and events
+	Body json.RawMessage `json:"body"`
+}
+
+// response is the answer to a request.  Message says why the request failed, when it did not succeed
+type response struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+// event is a message the server sends on its own, such as when the program stops
+type event struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+// Capabilities are the optional features of the protocol the server supports
+type Capabilities struct {
+	SupportsConfigurationDoneRequest bool `json:"supportsConfigurationDoneRequest"`
+	SupportsEvaluateForHovers        bool `json:"supportsEvaluateForHovers"`
+	SupportsTerminateRequest         bool `json:"supportsTerminateRequest"`
+}
+
+// LaunchArguments are the arguments of the launch request.  Program is the path of the file to debug
+type LaunchArguments struct {
+	Program     string `json:"program"`
+	StopOnEntry bool   `json:"stopOnEntry"`
+}
+
+// Source is a source file
+type Source struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+// SourceBreakpoint is a breakpoint the client asks for.  Line is 1-based
+type SourceBreakpoint struct {
+	Line int `json:"line"`
+}
+
+// SetBreakpointsArguments are the arguments of the setBreakpoints request, which replaces every breakpoint in Source
+type SetBreakpointsArguments struct {
+	Source      Source             `json:"source"`
+	Breakpoints []SourceBreakpoint `json:"breakpoints"`
+}
+
+// Breakpoint is a breakpoint as the server set it.  a breakpoint is only Verified on a line a statement starts on, Message says why when it is not
+type Breakpoint struct {
+	Verified bool    `json:"verified"`
+	Line     int     `json:"line"`
+	Message  string  `json:"message,omitempty"`
+	Source   *Source `json:"source,omitempty"`
+}
+
+// SetBreakpointsResponse is the body of the response to setBreakpoints, with a breakpoint for every one asked for, in the same order
+type SetBreakpointsResponse struct {
+	Breakpoints []Breakpoint `json:"breakpoints"`
+}
+
+// Thread is a thread of the program.  monkey programs are debugged on a single thread
+type Thread struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// ThreadsResponse is the body of the response to threads
+type ThreadsResponse struct {
+	Threads []Thread `json:"threads"`
+}
+
+// ThreadArguments are the arguments of requests about a single thread, such as stackTrace, continue and the steps
+type ThreadArguments struct {
+	ThreadID int `json:"threadId"`
+}
+
+// StackFrame is a frame of the call stack.  Line and Column are 1-based
+type StackFrame struct {
+	ID     int     `json:"id"`
+	Name   string  `json:"name"`
+	Source *Source `json:"source,omitempty"`
+	Line   int     `json:"line"`
+	Column int     `json:"column"`
+}
+
+// StackTraceResponse is the body of the response to stackTrace, the innermost frame first
+type StackTraceResponse struct {
+	StackFrames []StackFrame `json:"stackFrames"`
+	TotalFrames int          `json:"totalFrames"`
+}
+
+// ScopesArguments are the arguments of the scopes request
+type ScopesArguments struct {
+	FrameID int `json:"frameId"`
+}
+
+// Scope is a set of variables visible in a frame, which the variables request returns
+type Scope struct {
+	Name               string `json:"name"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+// ScopesResponse is the body of the response to scopes
+type ScopesResponse struct {
+	Scopes []Scope `json:"scopes"`
+}
+
+// VariablesArguments are the arguments of the variables request
+type VariablesArguments struct {
+	VariablesReference int `json:"variablesReference"`
+}
+
+// Variable is a binding, or an element of an array or hash.  a VariablesReference other than 0 means the value has variables of its own
+type Variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	Type               string `json:"type,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+// VariablesResponse is the body of the response to variables
+type VariablesResponse struct {
+	Variables []Variable `json:"variables"`
+}
+
+// ContinueResponse is the body of the response to continue
+type ContinueResponse struct {
+	AllThreadsContinued bool `json:"allThreadsContinued"`
+}
+
+// EvaluateArguments are the arguments of the evaluate request.  without a FrameID, the expression is evaluated in the innermost frame
+type EvaluateArguments struct {
+	Expression string `json:"expression"`
+	FrameID    int    `json:"frameId"`
+	Context    string `json:"context"`
+}
+
+// EvaluateResponse is the body of the response to evaluate
+type EvaluateResponse struct {
+	Result             string `json:"result"`
+	Type               string `json:"type,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+// StoppedEvent is the body of the stopped event
+type StoppedEvent struct {
+	Reason            string `json:"reason"`
+	ThreadID          int    `json:"threadId"`
+	AllThreadsStopped bool   `json:"allThreadsStopped"`
+}
+
+// OutputEvent is the body of the output event.  Category is stdout or stderr
+type OutputEvent struct {
+	Category string `json:"category"`
+	Output   string `json:"output"`
+}
+
+// ExitedEvent is the body of the exited event
+type ExitedEvent struct {
+	ExitCode int `json:"exitCode"`
+}
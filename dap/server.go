@@ -0,0 +1,583 @@
+package dap
+
+import (
+	"akdjr/monkey/debugger"
+	"akdjr/monkey/framing"
+	"akdjr/monkey/interpreter"
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/object"
+	"akdjr/monkey/parser"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// threadID is the id of the only thread a program is debugged on
+const threadID = 1
+
+// errNotStopped is the error of requests that need a stopped program when it is not
+var errNotStopped = errors.New("the program is not stopped")
+
+// Server is a debug adapter for monkey.  It serves a single client, which launches one program and debugs it with requests read from one stream, answered on another
+type Server struct {
+	in  *framing.Reader
+	out *framing.Writer
+
+	// sendMu keeps the messages in the order of their seq
+	sendMu sync.Mutex
+	seq    int
+
+	debugger    *debugger.Debugger
+	breakpoints map[string][]int
+	program     string
+	source      string
+	launched    bool
+	configured  bool
+	ctx         context.Context
+	cancel      context.CancelFunc
+	done        chan struct{}
+
+	// resume hands the command to carry on with to the stopped program
+	resume chan debugger.Command
+
+	// stateMu guards the state of the stopped program, which requests are answered from.  stop is nil while the program runs
+	stateMu    sync.Mutex
+	stop       *debugger.Stop
+	references []interface{}
+
+	// stopCtx is canceled once the stopped program carries on, which ends the evaluations made while it was stopped.  it is guarded by stateMu
+	stopCtx    context.Context
+	stopCancel context.CancelFunc
+
+	// evaluations are the evaluate requests being answered.  each is evaluated on a goroutine of its own, so that an expression that takes long, or never ends, does not keep the requests after it from being read
+	evaluations sync.WaitGroup
+}
+
+// New creates a server that reads the messages of the client from in and writes its own to out
+func New(in io.Reader, out io.Writer) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Server{
+		in:          framing.NewReader(in),
+		out:         framing.NewWriter(out),
+		debugger:    debugger.New(),
+		breakpoints: map[string][]int{},
+		ctx:         ctx,
+		cancel:      cancel,
+		resume:      make(chan debugger.Command),
+	}
+}
+
+// Run serves the client until it disconnects, or until its messages end.  the program is terminated if it is still running.  it returns an error reading or writing messages, or nil
+func (s *Server) Run() error {
+	defer s.terminate()
+
+	for {
+		data, err := s.in.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			// a message that can not be decoded has no seq to answer to, so it is dropped
+			continue
+		}
+
+		if msg.Type != "request" {
+			continue
+		}
+
+		if msg.Command == "disconnect" {
+			s.terminate()
+			return s.respond(msg, nil, nil)
+		}
+
+		if err := s.request(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// request handles a request and responds to it.  only an error writing to the client is returned
+func (s *Server) request(msg message) error {
+	switch msg.Command {
+	case "initialize":
+		capabilities := Capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsEvaluateForHovers:        true,
+			SupportsTerminateRequest:         true,
+		}
+		if err := s.respond(msg, capabilities, nil); err != nil {
+			return err
+		}
+		return s.event("initialized", nil)
+	case "launch":
+		var args LaunchArguments
+		if err := json.Unmarshal(msg.Arguments, &args); err != nil {
+			return s.respond(msg, nil, err)
+		}
+		if err := s.launch(args); err != nil {
+			return s.respond(msg, nil, err)
+		}
+		if err := s.respond(msg, nil, nil); err != nil {
+			return err
+		}
+		return s.start()
+	case "configurationDone":
+		s.configured = true
+		if err := s.respond(msg, nil, nil); err != nil {
+			return err
+		}
+		return s.start()
+	case "setBreakpoints":
+		var args SetBreakpointsArguments
+		if err := json.Unmarshal(msg.Arguments, &args); err != nil {
+			return s.respond(msg, nil, err)
+		}
+		return s.respond(msg, s.setBreakpoints(args), nil)
+	case "threads":
+		return s.respond(msg, ThreadsResponse{Threads: []Thread{{ID: threadID, Name: "main"}}}, nil)
+	case "stackTrace":
+		result, err := s.stackTrace()
+		return s.respond(msg, result, err)
+	case "scopes":
+		var args ScopesArguments
+		if err := json.Unmarshal(msg.Arguments, &args); err != nil {
+			return s.respond(msg, nil, err)
+		}
+		result, err := s.scopes(args.FrameID)
+		return s.respond(msg, result, err)
+	case "variables":
+		var args VariablesArguments
+		if err := json.Unmarshal(msg.Arguments, &args); err != nil {
+			return s.respond(msg, nil, err)
+		}
+		result, err := s.variables(args.VariablesReference)
+		return s.respond(msg, result, err)
+	case "evaluate":
+		var args EvaluateArguments
+		if err := json.Unmarshal(msg.Arguments, &args); err != nil {
+			return s.respond(msg, nil, err)
+		}
+		s.evaluations.Add(1)
+		go func() {
+			defer s.evaluations.Done()
+
+			result, err := s.evaluate(args)
+			s.respond(msg, result, err)
+		}()
+		return nil
+	case "continue":
+		return s.carryOn(msg, debugger.Continue, ContinueResponse{AllThreadsContinued: true})
+	case "next":
+		return s.carryOn(msg, debugger.StepOver, nil)
+	case "stepIn":
+		return s.carryOn(msg, debugger.StepIn, nil)
+	case "stepOut":
+		return s.carryOn(msg, debugger.StepOut, nil)
+	case "terminate":
+		s.debugger.Terminate()
+		s.cancel()
+		if err := s.respond(msg, nil, nil); err != nil {
+			return err
+		}
+		// a program that is running sends terminated once it has ended
+		if s.done == nil {
+			return s.event("terminated", nil)
+		}
+		return nil
+	}
+
+	return s.respond(msg, nil, fmt.Errorf("unknown command: %s", msg.Command))
+}
+
+// launch reads the program to debug.  it starts once the client is done setting breakpoints and sends configurationDone
+func (s *Server) launch(args LaunchArguments) error {
+	if s.launched {
+		return errors.New("a program has already been launched")
+	}
+
+	path, err := filepath.Abs(args.Program)
+	if err != nil {
+		return err
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	s.program, s.source, s.launched = path, string(source), true
+	s.debugger.StopOnEntry = args.StopOnEntry
+	s.debugger.SetBreakpoints(s.breakpoints[path])
+
+	return nil
+}
+
+// start runs the program in a goroutine of its own, once it has been launched and configured
+func (s *Server) start() error {
+	if !s.launched || !s.configured || s.done != nil {
+		return nil
+	}
+
+	s.done = make(chan struct{})
+
+	i := interpreter.New()
+	i.Stdout = &output{server: s, category: "stdout"}
+	i.Stderr = &output{server: s, category: "stderr"}
+
+	s.debugger.Stopped = s.stopped
+
+	go func() {
+		defer close(s.done)
+
+		exitCode := 0
+
+		_, err := s.debugger.Run(s.ctx, i, s.source)
+
+		var runtimeErr *interpreter.RuntimeError
+		switch {
+		case err == nil, err == debugger.ErrTerminated:
+		case errors.As(err, &runtimeErr):
+			s.event("output", OutputEvent{Category: "stderr", Output: runtimeErr.Object.Inspect() + "\n"})
+			exitCode = 1
+		default:
+			s.event("output", OutputEvent{Category: "stderr", Output: err.Error() + "\n"})
+			exitCode = 1
+		}
+
+		s.event("exited", ExitedEvent{ExitCode: exitCode})
+		s.event("terminated", nil)
+	}()
+
+	return nil
+}
+
+// terminate ends the program, if it is running, and the evaluations being made, and waits for them to end
+func (s *Server) terminate() {
+	s.debugger.Terminate()
+	s.cancel()
+
+	if s.done != nil {
+		<-s.done
+	}
+
+	s.evaluations.Wait()
+}
+
+// stopped is called on the goroutine of the program when it stops.  it tells the client, then waits for a request that carries on
+func (s *Server) stopped(stop *debugger.Stop) debugger.Command {
+	s.stateMu.Lock()
+	s.stop, s.references = stop, nil
+	s.stopCtx, s.stopCancel = context.WithCancel(s.ctx)
+	s.stateMu.Unlock()
+
+	s.event("stopped", StoppedEvent{Reason: string(stop.Reason), ThreadID: threadID, AllThreadsStopped: true})
+
+	select {
+	case command := <-s.resume:
+		return command
+	case <-s.ctx.Done():
+		s.stateMu.Lock()
+		s.carryingOn()
+		s.stateMu.Unlock()
+
+		return debugger.Continue
+	}
+}
+
+// carryingOn forgets the state of the stopped program as it carries on, and ends the evaluations still being made in it.  stateMu must be held
+func (s *Server) carryingOn() {
+	if s.stopCancel != nil {
+		s.stopCancel()
+	}
+
+	s.stop, s.references = nil, nil
+	s.stopCtx, s.stopCancel = nil, nil
+}
+
+// carryOn responds to a request that resumes the stopped program, then resumes it with command.  the response comes first, so that it is sent before the program stops again
+func (s *Server) carryOn(msg message, command debugger.Command, body interface{}) error {
+	s.stateMu.Lock()
+	stopped := s.stop != nil
+	s.carryingOn()
+	s.stateMu.Unlock()
+
+	if !stopped {
+		return s.respond(msg, nil, errNotStopped)
+	}
+
+	if err := s.respond(msg, body, nil); err != nil {
+		return err
+	}
+
+	select {
+	case s.resume <- command:
+	case <-s.ctx.Done():
+	}
+
+	return nil
+}
+
+// setBreakpoints replaces the breakpoints of a source file.  breakpoints on lines no statement starts on are not verified, as they would never be hit
+func (s *Server) setBreakpoints(args SetBreakpointsArguments) SetBreakpointsResponse {
+	result := SetBreakpointsResponse{Breakpoints: []Breakpoint{}}
+
+	path, err := filepath.Abs(args.Source.Path)
+	if err != nil {
+		path = args.Source.Path
+	}
+
+	lines := map[int]bool{}
+	var message string
+
+	if source, err := os.ReadFile(path); err != nil {
+		message = err.Error()
+	} else {
+		for _, line := range debugger.Lines(parser.New(lexer.New(string(source))).ParseProgram()) {
+			lines[line] = true
+		}
+	}
+
+	verified := []int{}
+	for _, requested := range args.Breakpoints {
+		breakpoint := Breakpoint{Line: requested.Line, Source: &Source{Name: filepath.Base(path), Path: path}}
+
+		switch {
+		case message != "":
+			breakpoint.Message = message
+		case !lines[requested.Line]:
+			breakpoint.Message = "no statement starts on this line"
+		default:
+			breakpoint.Verified = true
+			verified = append(verified, requested.Line)
+		}
+
+		result.Breakpoints = append(result.Breakpoints, breakpoint)
+	}
+
+	s.breakpoints[path] = verified
+	if path == s.program {
+		s.debugger.SetBreakpoints(verified)
+	}
+
+	return result
+}
+
+func (s *Server) stackTrace() (*StackTraceResponse, error) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	if s.stop == nil {
+		return nil, errNotStopped
+	}
+
+	result := &StackTraceResponse{StackFrames: []StackFrame{}, TotalFrames: len(s.stop.Frames)}
+	for i, frame := range s.stop.Frames {
+		result.StackFrames = append(result.StackFrames, StackFrame{
+			ID:     i + 1,
+			Name:   frame.Name,
+			Source: &Source{Name: filepath.Base(s.program), Path: s.program},
+			Line:   frame.Line,
+			Column: frame.Column,
+		})
+	}
+
+	return result, nil
+}
+
+// scopes returns the scopes of a frame: the bindings of a function call and the globals, or only the globals for the program itself
+func (s *Server) scopes(frameID int) (*ScopesResponse, error) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	frame, err := s.frame(frameID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ScopesResponse{Scopes: []Scope{}}
+	if frame.Function != nil {
+		result.Scopes = append(result.Scopes, Scope{Name: "Locals", VariablesReference: s.reference(frame.Env)})
+	}
+
+	globals := s.stop.Frames[len(s.stop.Frames)-1].Env
+	result.Scopes = append(result.Scopes, Scope{Name: "Globals", VariablesReference: s.reference(globals)})
+
+	return result, nil
+}
+
+// variables returns the bindings of a scope, or the elements of an array or hash
+func (s *Server) variables(reference int) (*VariablesResponse, error) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	if s.stop == nil {
+		return nil, errNotStopped
+	}
+
+	if reference < 1 || reference > len(s.references) {
+		return nil, fmt.Errorf("unknown variables reference %d", reference)
+	}
+
+	result := &VariablesResponse{Variables: []Variable{}}
+
+	switch value := s.references[reference-1].(type) {
+	case *object.Environment:
+		for _, name := range value.Names() {
+			obj, _ := value.Get(name)
+			// the builtins are the same in every scope, they would only crowd out the bindings of the program
+			if _, ok := obj.(*object.Builtin); ok {
+				continue
+			}
+
+			result.Variables = append(result.Variables, s.variable(name, obj))
+		}
+	case *object.Array:
+		for i, element := range value.Elements {
+			result.Variables = append(result.Variables, s.variable(fmt.Sprintf("[%d]", i), element))
+		}
+	case *object.Hash:
+		pairs := []object.HashPair{}
+		for _, pair := range value.Pairs {
+			pairs = append(pairs, pair)
+		}
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key.Inspect() < pairs[j].Key.Inspect() })
+
+		for _, pair := range pairs {
+			result.Variables = append(result.Variables, s.variable(pair.Key.Inspect(), pair.Value))
+		}
+	}
+
+	return result, nil
+}
+
+// evaluate evaluates an expression in a frame of the stopped program.  stateMu is not held while the expression is evaluated, and the evaluation ends once the program carries on
+func (s *Server) evaluate(args EvaluateArguments) (*EvaluateResponse, error) {
+	frameID := args.FrameID
+	if frameID == 0 {
+		frameID = 1
+	}
+
+	s.stateMu.Lock()
+	frame, err := s.frame(frameID)
+	stop, ctx := s.stop, s.stopCtx
+	s.stateMu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := s.debugger.EvaluateContext(ctx, frame, args.Expression)
+	if err, ok := result.(*object.Error); ok {
+		return nil, errors.New(err.Message)
+	}
+
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	// the references of the result are only valid while the program is stopped where it was evaluated
+	if s.stop != stop {
+		return nil, errNotStopped
+	}
+
+	v := s.variable("", result)
+	return &EvaluateResponse{Result: v.Value, Type: v.Type, VariablesReference: v.VariablesReference}, nil
+}
+
+// frame returns the frame of the stopped program with id.  stateMu must be held
+func (s *Server) frame(id int) (*debugger.Frame, error) {
+	if s.stop == nil {
+		return nil, errNotStopped
+	}
+
+	if id < 1 || id > len(s.stop.Frames) {
+		return nil, fmt.Errorf("unknown frame %d", id)
+	}
+
+	return s.stop.Frames[id-1], nil
+}
+
+// variable describes obj as a variable named name.  arrays and hashes get a reference to their elements.  stateMu must be held
+func (s *Server) variable(name string, obj object.Object) Variable {
+	v := Variable{Name: name, Value: strings.Join(strings.Fields(obj.Inspect()), " "), Type: string(obj.Type())}
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		if len(obj.Elements) > 0 {
+			v.VariablesReference = s.reference(obj)
+		}
+	case *object.Hash:
+		if len(obj.Pairs) > 0 {
+			v.VariablesReference = s.reference(obj)
+		}
+	}
+
+	return v
+}
+
+// reference returns a variables reference to an environment, array or hash, which is valid until the program carries on.  stateMu must be held
+func (s *Server) reference(value interface{}) int {
+	s.references = append(s.references, value)
+	return len(s.references)
+}
+
+// output writes what the program prints to the client as output events
+type output struct {
+	server   *Server
+	category string
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	if err := o.server.event("output", OutputEvent{Category: o.category, Output: string(p)}); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// respond answers a request, with either its body or the error it failed with
+func (s *Server) respond(request message, body interface{}, err error) error {
+	r := response{Type: "response", RequestSeq: request.Seq, Command: request.Command, Success: err == nil, Body: body}
+	if err != nil {
+		r.Message = err.Error()
+	}
+
+	return s.send(func(seq int) interface{} {
+		r.Seq = seq
+		return r
+	})
+}
+
+// event sends an event to the client
+func (s *Server) event(name string, body interface{}) error {
+	return s.send(func(seq int) interface{} {
+		return event{Seq: seq, Type: "event", Event: name, Body: body}
+	})
+}
+
+// send writes the message made with the next seq to the client
+func (s *Server) send(build func(seq int) interface{}) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	s.seq++
+
+	data, err := json.Marshal(build(s.seq))
+	if err != nil {
+		return err
+	}
+
+	return s.out.Write(data)
+}
@@ -0,0 +1,474 @@
+package dap
+
+import (
+	"akdjr/monkey/framing"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const program = `let add = fn(a, b) {
+  let sum = a + b;
+  sum
+};
+let x = add(1, 2);
+puts(x);
+let y = add(x, 10);
+y * 2
+`
+
+// client is a fake editor that talks to a server running in the same process
+type client struct {
+	t        *testing.T
+	w        *framing.Writer
+	in       io.WriteCloser
+	messages chan message
+	done     chan error
+	seq      int
+
+	// events are the events received while waiting for responses
+	events []message
+}
+
+func newClient(t *testing.T) *client {
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+
+	c := &client{
+		t:        t,
+		w:        framing.NewWriter(clientOut),
+		in:       clientOut,
+		messages: make(chan message, 100),
+		done:     make(chan error, 1),
+	}
+
+	go func() {
+		c.done <- New(serverIn, serverOut).Run()
+		serverOut.Close()
+	}()
+
+	go func() {
+		r := framing.NewReader(clientIn)
+		for {
+			data, err := r.Read()
+			if err != nil {
+				close(c.messages)
+				return
+			}
+
+			var msg message
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Errorf("the server sent invalid JSON: %s", data)
+			}
+			c.messages <- msg
+		}
+	}()
+
+	t.Cleanup(func() { clientOut.Close() })
+
+	return c
+}
+
+// send sends a request without waiting for its response, and returns its seq
+func (c *client) send(command string, arguments interface{}) int {
+	c.seq++
+
+	data, _ := json.Marshal(arguments)
+	request, _ := json.Marshal(message{Seq: c.seq, Type: "request", Command: command, Arguments: data})
+	if err := c.w.Write(request); err != nil {
+		c.t.Fatalf("writing a request: %s", err)
+	}
+
+	return c.seq
+}
+
+// request sends a request and decodes the body of its response into body.  it returns the message of a response that is not a success
+func (c *client) request(command string, arguments interface{}, body interface{}) string {
+	seq := c.send(command, arguments)
+
+	for {
+		msg := c.receive()
+		if msg.Type == "event" {
+			c.events = append(c.events, msg)
+			continue
+		}
+
+		if msg.RequestSeq != seq || msg.Command != command {
+			c.t.Fatalf("response to the wrong request. expected=%d %s, got=%d %s", seq, command, msg.RequestSeq, msg.Command)
+		}
+
+		if !msg.Success {
+			return msg.Message
+		}
+
+		if body != nil {
+			if err := json.Unmarshal(msg.Body, body); err != nil {
+				c.t.Fatalf("decoding the body of %s: %s", command, err)
+			}
+		}
+
+		return ""
+	}
+}
+
+// mustRequest sends a request that must succeed
+func (c *client) mustRequest(command string, arguments interface{}, body interface{}) {
+	if message := c.request(command, arguments, body); message != "" {
+		c.t.Fatalf("%s failed: %s", command, message)
+	}
+}
+
+func (c *client) receive() message {
+	select {
+	case msg, ok := <-c.messages:
+		if !ok {
+			c.t.Fatalf("the server closed the connection")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatalf("timed out waiting for the server")
+		return message{}
+	}
+}
+
+// waitFor returns the next event named name, receiving messages until it comes.  the events before it are dropped
+func (c *client) waitFor(name string, body interface{}) {
+	for {
+		var msg message
+		if len(c.events) > 0 {
+			msg, c.events = c.events[0], c.events[1:]
+		} else {
+			msg = c.receive()
+		}
+
+		if msg.Type == "event" && msg.Event == name {
+			if body != nil {
+				if err := json.Unmarshal(msg.Body, body); err != nil {
+					c.t.Fatalf("decoding the body of %s: %s", name, err)
+				}
+			}
+			return
+		}
+	}
+}
+
+// launch starts debugging program with the breakpoints on lines, and returns the path of the program
+func (c *client) launch(source string, stopOnEntry bool, lines ...int) string {
+	path := filepath.Join(c.t.TempDir(), "program.monkey")
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		c.t.Fatal(err)
+	}
+
+	var capabilities Capabilities
+	c.mustRequest("initialize", map[string]interface{}{"adapterID": "monkey"}, &capabilities)
+	if !capabilities.SupportsConfigurationDoneRequest {
+		c.t.Errorf("configurationDone is not supported")
+	}
+	c.waitFor("initialized", nil)
+
+	c.mustRequest("launch", LaunchArguments{Program: path, StopOnEntry: stopOnEntry}, nil)
+
+	breakpoints := []SourceBreakpoint{}
+	for _, line := range lines {
+		breakpoints = append(breakpoints, SourceBreakpoint{Line: line})
+	}
+	c.mustRequest("setBreakpoints", SetBreakpointsArguments{Source: Source{Path: path}, Breakpoints: breakpoints}, nil)
+
+	c.mustRequest("configurationDone", nil, nil)
+
+	return path
+}
+
+// stack returns the names and lines of the frames of the stopped program
+func (c *client) stack() []StackFrame {
+	var trace StackTraceResponse
+	c.mustRequest("stackTrace", ThreadArguments{ThreadID: threadID}, &trace)
+
+	for i := range trace.StackFrames {
+		trace.StackFrames[i].Source = nil
+	}
+
+	return trace.StackFrames
+}
+
+func TestBreakpointsAndVariables(t *testing.T) {
+	c := newClient(t)
+	c.launch(program, false, 2)
+
+	var stopped StoppedEvent
+	c.waitFor("stopped", &stopped)
+	if stopped.Reason != "breakpoint" || stopped.ThreadID != threadID {
+		t.Errorf("wrong stopped event. got=%+v", stopped)
+	}
+
+	var threads ThreadsResponse
+	c.mustRequest("threads", nil, &threads)
+	if !reflect.DeepEqual(threads.Threads, []Thread{{ID: threadID, Name: "main"}}) {
+		t.Errorf("wrong threads. got=%+v", threads.Threads)
+	}
+
+	expected := []StackFrame{{ID: 1, Name: "add", Line: 2, Column: 3}, {ID: 2, Name: "main", Line: 5, Column: 1}}
+	if frames := c.stack(); !reflect.DeepEqual(frames, expected) {
+		t.Errorf("wrong stack trace. expected=%+v, got=%+v", expected, frames)
+	}
+
+	var scopes ScopesResponse
+	c.mustRequest("scopes", ScopesArguments{FrameID: 1}, &scopes)
+	if len(scopes.Scopes) != 2 || scopes.Scopes[0].Name != "Locals" || scopes.Scopes[1].Name != "Globals" {
+		t.Fatalf("wrong scopes. got=%+v", scopes.Scopes)
+	}
+
+	var locals VariablesResponse
+	c.mustRequest("variables", VariablesArguments{VariablesReference: scopes.Scopes[0].VariablesReference}, &locals)
+	expectedLocals := []Variable{{Name: "a", Value: "1", Type: "INTEGER"}, {Name: "b", Value: "2", Type: "INTEGER"}}
+	if !reflect.DeepEqual(locals.Variables, expectedLocals) {
+		t.Errorf("wrong locals. expected=%+v, got=%+v", expectedLocals, locals.Variables)
+	}
+
+	var globals VariablesResponse
+	c.mustRequest("variables", VariablesArguments{VariablesReference: scopes.Scopes[1].VariablesReference}, &globals)
+	if len(globals.Variables) != 1 || globals.Variables[0].Name != "add" || globals.Variables[0].Type != "FUNCTION" {
+		t.Errorf("wrong globals. got=%+v", globals.Variables)
+	}
+
+	c.mustRequest("scopes", ScopesArguments{FrameID: 2}, &scopes)
+	if len(scopes.Scopes) != 1 || scopes.Scopes[0].Name != "Globals" {
+		t.Errorf("wrong scopes of the program. got=%+v", scopes.Scopes)
+	}
+
+	var evaluated EvaluateResponse
+	c.mustRequest("evaluate", EvaluateArguments{Expression: "a + b", FrameID: 1}, &evaluated)
+	if evaluated.Result != "3" || evaluated.Type != "INTEGER" {
+		t.Errorf("wrong result of evaluate. got=%+v", evaluated)
+	}
+
+	if message := c.request("evaluate", EvaluateArguments{Expression: "a", FrameID: 2}, nil); message != "identifier not found: a" {
+		t.Errorf("wrong error of evaluate. got=%q", message)
+	}
+
+	// the breakpoint is hit again by the second call
+	c.mustRequest("continue", ThreadArguments{ThreadID: threadID}, nil)
+
+	var output OutputEvent
+	c.waitFor("output", &output)
+	if output.Category != "stdout" || output.Output != "3\n" {
+		t.Errorf("wrong output. got=%+v", output)
+	}
+
+	c.waitFor("stopped", &stopped)
+
+	// references from an earlier stop are no longer valid
+	if message := c.request("variables", VariablesArguments{VariablesReference: 4}, nil); message != "unknown variables reference 4" {
+		t.Errorf("a stale reference was accepted. got=%q", message)
+	}
+
+	c.mustRequest("scopes", ScopesArguments{FrameID: 1}, &scopes)
+	c.mustRequest("variables", VariablesArguments{VariablesReference: scopes.Scopes[0].VariablesReference}, &locals)
+	expectedLocals = []Variable{{Name: "a", Value: "3", Type: "INTEGER"}, {Name: "b", Value: "10", Type: "INTEGER"}}
+	if !reflect.DeepEqual(locals.Variables, expectedLocals) {
+		t.Errorf("wrong locals at the second stop. expected=%+v, got=%+v", expectedLocals, locals.Variables)
+	}
+
+	c.mustRequest("continue", ThreadArguments{ThreadID: threadID}, nil)
+
+	var exited ExitedEvent
+	c.waitFor("exited", &exited)
+	if exited.ExitCode != 0 {
+		t.Errorf("wrong exit code. got=%d", exited.ExitCode)
+	}
+	c.waitFor("terminated", nil)
+
+	if message := c.request("stackTrace", ThreadArguments{ThreadID: threadID}, nil); message != errNotStopped.Error() {
+		t.Errorf("stackTrace of a finished program did not fail. got=%q", message)
+	}
+
+	c.mustRequest("disconnect", nil, nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestStepping(t *testing.T) {
+	c := newClient(t)
+	c.launch(program, true)
+
+	steps := []struct {
+		command string
+		reason  string
+		frames  []string
+		line    int
+	}{
+		{"", "entry", []string{"main"}, 1},
+		{"next", "step", []string{"main"}, 5},
+		{"stepIn", "step", []string{"add", "main"}, 2},
+		{"next", "step", []string{"add", "main"}, 3},
+		{"stepOut", "step", []string{"main"}, 6},
+		{"next", "step", []string{"main"}, 7},
+	}
+
+	for _, step := range steps {
+		if step.command != "" {
+			c.mustRequest(step.command, ThreadArguments{ThreadID: threadID}, nil)
+		}
+
+		var stopped StoppedEvent
+		c.waitFor("stopped", &stopped)
+		if stopped.Reason != step.reason {
+			t.Errorf("wrong reason after %q. expected=%q, got=%q", step.command, step.reason, stopped.Reason)
+		}
+
+		frames := c.stack()
+		names := []string{}
+		for _, frame := range frames {
+			names = append(names, frame.Name)
+		}
+
+		if !reflect.DeepEqual(names, step.frames) || frames[0].Line != step.line {
+			t.Errorf("wrong stop after %q. expected=%v at line %d, got=%v at line %d", step.command, step.frames, step.line, names, frames[0].Line)
+		}
+	}
+
+	// a program that is stopped can only be resumed once
+	c.mustRequest("continue", ThreadArguments{ThreadID: threadID}, nil)
+	c.waitFor("terminated", nil)
+
+	if message := c.request("next", ThreadArguments{ThreadID: threadID}, nil); message != errNotStopped.Error() {
+		t.Errorf("next on a finished program did not fail. got=%q", message)
+	}
+}
+
+func TestSetBreakpoints(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "program.monkey")
+	if err := os.WriteFile(path, []byte(program), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newClient(t)
+
+	var result SetBreakpointsResponse
+	c.mustRequest("setBreakpoints", SetBreakpointsArguments{Source: Source{Path: path}, Breakpoints: []SourceBreakpoint{{Line: 2}, {Line: 4}, {Line: 7}}}, &result)
+
+	verified := []bool{}
+	for _, breakpoint := range result.Breakpoints {
+		verified = append(verified, breakpoint.Verified)
+	}
+
+	if !reflect.DeepEqual(verified, []bool{true, false, true}) {
+		t.Errorf("wrong breakpoints verified. got=%+v", result.Breakpoints)
+	}
+
+	c.mustRequest("setBreakpoints", SetBreakpointsArguments{Source: Source{Path: filepath.Join(t.TempDir(), "missing.monkey")}, Breakpoints: []SourceBreakpoint{{Line: 1}}}, &result)
+	if len(result.Breakpoints) != 1 || result.Breakpoints[0].Verified || result.Breakpoints[0].Message == "" {
+		t.Errorf("a breakpoint in a missing file was verified. got=%+v", result.Breakpoints)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c := newClient(t)
+	c.launch("let x = 1;\n-true;\n", false)
+
+	var output OutputEvent
+	c.waitFor("output", &output)
+	if output.Category != "stderr" || output.Output != "ERROR: unknown operator: -BOOLEAN\n" {
+		t.Errorf("wrong output. got=%+v", output)
+	}
+
+	var exited ExitedEvent
+	c.waitFor("exited", &exited)
+	if exited.ExitCode != 1 {
+		t.Errorf("wrong exit code. got=%d", exited.ExitCode)
+	}
+
+	if message := c.request("launch", LaunchArguments{Program: "other.monkey"}, nil); message != "a program has already been launched" {
+		t.Errorf("wrong error for a second launch. got=%q", message)
+	}
+
+	if message := c.request("nonsense", nil, nil); message != "unknown command: nonsense" {
+		t.Errorf("wrong error for an unknown command. got=%q", message)
+	}
+}
+
+func TestTerminate(t *testing.T) {
+	c := newClient(t)
+	c.launch("puts(1);\nputs(2);\n", true)
+	c.waitFor("stopped", nil)
+
+	c.mustRequest("terminate", nil, nil)
+	c.waitFor("terminated", nil)
+
+	for _, msg := range c.events {
+		if msg.Event == "output" {
+			t.Errorf("the program ran after it was terminated. got=%s", msg.Body)
+		}
+	}
+
+	// the end of the input ends the server, even with a program stopped
+	c = newClient(t)
+	c.launch("puts(1);\n", true)
+	c.waitFor("stopped", nil)
+
+	c.in.Close()
+	select {
+	case err := <-c.done:
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the server did not end")
+	}
+}
+
+func TestEvaluateBlocking(t *testing.T) {
+	// an evaluation that never ends on its own does not keep the requests after it from being answered
+	c := newClient(t)
+	c.launch("puts(1);\n", true)
+	c.waitFor("stopped", nil)
+
+	evaluate := c.send("evaluate", EvaluateArguments{Expression: "recv(channel())", FrameID: 1})
+
+	var frames []StackFrame
+	if frames = c.stack(); len(frames) != 1 || frames[0].Line != 1 {
+		t.Errorf("wrong stack trace during an evaluation. got=%+v", frames)
+	}
+
+	// carrying on ends the evaluation
+	c.mustRequest("continue", ThreadArguments{ThreadID: threadID}, nil)
+
+	for {
+		msg := c.receive()
+		if msg.Type == "response" && msg.RequestSeq == evaluate {
+			if msg.Success || msg.Message != "evaluation canceled" {
+				t.Errorf("wrong response to the evaluation. got=%+v", msg)
+			}
+			break
+		}
+	}
+
+	// and so does a disconnect
+	c = newClient(t)
+	c.launch("puts(1);\n", true)
+	c.waitFor("stopped", nil)
+
+	c.send("evaluate", EvaluateArguments{Expression: "recv(channel())", FrameID: 1})
+	disconnect := c.send("disconnect", nil)
+
+	for {
+		msg := c.receive()
+		if msg.Type == "response" && msg.RequestSeq == disconnect {
+			break
+		}
+	}
+
+	select {
+	case err := <-c.done:
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the server did not end")
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"akdjr/monkey/debugger"
+	"akdjr/monkey/interpreter"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// debugCommand implements "monkey debug file" and returns the process exit code
+// the program is stopped before its first statement, and debugger commands are read from stdin
+func debugCommand(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: monkey debug file")
+		return 2
+	}
+
+	source, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	err = debugger.NewCLI(os.Stdin, os.Stdout).Run(interpreter.New(), string(source))
+
+	var runtimeErr *interpreter.RuntimeError
+	switch {
+	case errors.As(err, &runtimeErr):
+		fmt.Fprintln(os.Stderr, runtimeErr.Object.Inspect())
+		return 1
+	case err != nil:
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
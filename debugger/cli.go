@@ -0,0 +1,257 @@
+package debugger
+
+import (
+	"akdjr/monkey/interpreter"
+	"akdjr/monkey/object"
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PROMPT is the prompt shown while the program is stopped
+const PROMPT = "(debug) "
+
+// CLI is a terminal user interface for the debugger.  it reads commands a line at a time from in and writes what it shows to out
+type CLI struct {
+	debugger *Debugger
+	scanner  *bufio.Scanner
+	out      io.Writer
+	lines    []string
+
+	// the frames of the current stop, and the one commands such as locals and print look at
+	frames []*Frame
+	frame  int
+}
+
+// NewCLI creates a CLI that reads commands from in and writes to out
+func NewCLI(in io.Reader, out io.Writer) *CLI {
+	c := &CLI{
+		debugger: New(),
+		scanner:  bufio.NewScanner(in),
+		out:      out,
+	}
+
+	c.debugger.StopOnEntry = true
+	c.debugger.Stopped = c.stopped
+
+	return c
+}
+
+// Run runs source with i, stopped before its first statement so that breakpoints can be set.  it returns the error the program ended with, if any.  a program ended with the quit command, or by the end of the input, is not an error
+func (c *CLI) Run(i *interpreter.Interpreter, source string) error {
+	c.lines = strings.Split(strings.TrimSuffix(source, "\n"), "\n")
+
+	result, err := c.debugger.Run(context.Background(), i, source)
+
+	switch {
+	case err == ErrTerminated:
+		return nil
+	case err != nil:
+		return err
+	}
+
+	fmt.Fprintf(c.out, "program finished: %s\n", result.Inspect())
+	return nil
+}
+
+// stopped shows where the program stopped and reads commands until one of them carries on
+func (c *CLI) stopped(stop *Stop) Command {
+	c.frames, c.frame = stop.Frames, 0
+
+	fmt.Fprintf(c.out, "stopped at line %d in %s (%s)\n", stop.Line, stop.Frames[0].Name, stop.Reason)
+	c.printLine(stop.Line, true)
+
+	for {
+		io.WriteString(c.out, PROMPT)
+
+		if !c.scanner.Scan() {
+			c.debugger.Terminate()
+			return Continue
+		}
+
+		name, args, _ := strings.Cut(strings.TrimSpace(c.scanner.Text()), " ")
+		args = strings.TrimSpace(args)
+
+		switch name {
+		case "":
+			continue
+		case "continue", "c":
+			return Continue
+		case "step", "s":
+			return StepIn
+		case "next", "n":
+			return StepOver
+		case "out", "o":
+			return StepOut
+		case "quit", "q":
+			c.debugger.Terminate()
+			return Continue
+		case "help", "h":
+			io.WriteString(c.out, help)
+		case "break", "b":
+			c.setBreakpoint(args, true)
+		case "clear":
+			c.setBreakpoint(args, false)
+		case "breakpoints":
+			c.listBreakpoints()
+		case "stack", "bt":
+			c.printStack()
+		case "frame", "f":
+			c.selectFrame(args)
+		case "locals":
+			c.printBindings(c.frames[c.frame].Env)
+		case "globals":
+			c.printBindings(c.frames[len(c.frames)-1].Env)
+		case "print", "p":
+			c.print(args)
+		case "list", "l":
+			c.list()
+		default:
+			fmt.Fprintf(c.out, "unknown command %s, try help\n", name)
+		}
+	}
+}
+
+const help = `break <line>   stop at line, also b
+clear <line>   remove the breakpoint at line
+breakpoints    list the breakpoints
+continue       run until the next breakpoint, also c
+step           run to the next line, stepping into functions, also s
+next           run to the next line, stepping over functions, also n
+out            run until the current function returns, also o
+stack          show the call stack, also bt
+frame <n>      look at frame n of the call stack, also f
+locals         list the bindings of the frame
+globals        list the global bindings
+print <expr>   evaluate expr in the frame and print its value, also p
+list           show the source around the line of the frame, also l
+quit           end the program, also q
+`
+
+// setBreakpoint sets or clears the breakpoint on the line args holds
+func (c *CLI) setBreakpoint(args string, set bool) {
+	line, err := strconv.Atoi(args)
+	if err != nil || line < 1 || line > len(c.lines) {
+		fmt.Fprintf(c.out, "expected a line number between 1 and %d\n", len(c.lines))
+		return
+	}
+
+	lines := []int{}
+	for _, l := range c.debugger.Breakpoints() {
+		if l != line {
+			lines = append(lines, l)
+		}
+	}
+
+	if set {
+		lines = append(lines, line)
+	}
+
+	c.debugger.SetBreakpoints(lines)
+
+	if set {
+		fmt.Fprintf(c.out, "breakpoint set at line %d\n", line)
+	} else {
+		fmt.Fprintf(c.out, "breakpoint cleared at line %d\n", line)
+	}
+}
+
+func (c *CLI) listBreakpoints() {
+	lines := c.debugger.Breakpoints()
+	if len(lines) == 0 {
+		io.WriteString(c.out, "no breakpoints\n")
+		return
+	}
+
+	for _, line := range lines {
+		c.printLine(line, false)
+	}
+}
+
+// printStack lists the frames, marking the selected one
+func (c *CLI) printStack() {
+	for n, frame := range c.frames {
+		marker := " "
+		if n == c.frame {
+			marker = ">"
+		}
+
+		fmt.Fprintf(c.out, "%s #%d %s at line %d\n", marker, n, frame.Name, frame.Line)
+	}
+}
+
+func (c *CLI) selectFrame(args string) {
+	n, err := strconv.Atoi(args)
+	if err != nil || n < 0 || n >= len(c.frames) {
+		fmt.Fprintf(c.out, "expected a frame number between 0 and %d\n", len(c.frames)-1)
+		return
+	}
+
+	c.frame = n
+	fmt.Fprintf(c.out, "#%d %s at line %d\n", n, c.frames[n].Name, c.frames[n].Line)
+	c.printLine(c.frames[n].Line, true)
+}
+
+// printBindings lists the bindings set in env itself, each on one line.  builtins are left out, they are the same everywhere
+func (c *CLI) printBindings(env *object.Environment) {
+	printed := false
+
+	for _, name := range env.Names() {
+		value, _ := env.Get(name)
+		if _, ok := value.(*object.Builtin); ok {
+			continue
+		}
+
+		fmt.Fprintf(c.out, "%s = %s\n", name, strings.Join(strings.Fields(value.Inspect()), " "))
+		printed = true
+	}
+
+	if !printed {
+		io.WriteString(c.out, "no bindings\n")
+	}
+}
+
+func (c *CLI) print(code string) {
+	if code == "" {
+		io.WriteString(c.out, "usage: print <expr>\n")
+		return
+	}
+
+	result := c.debugger.Evaluate(c.frames[c.frame], code)
+	io.WriteString(c.out, result.Inspect()+"\n")
+}
+
+// list shows the lines around the line of the selected frame
+func (c *CLI) list() {
+	current := c.frames[c.frame].Line
+
+	for line := current - 3; line <= current+3; line++ {
+		if line >= 1 && line <= len(c.lines) {
+			c.printLine(line, line == current)
+		}
+	}
+}
+
+// printLine prints a line of the source with its number, marking the current line with an arrow and breakpoints with an asterisk
+func (c *CLI) printLine(line int, current bool) {
+	if line < 1 || line > len(c.lines) {
+		return
+	}
+
+	marker := "  "
+	if current {
+		marker = "=>"
+	}
+
+	breakpoint := " "
+	for _, l := range c.debugger.Breakpoints() {
+		if l == line {
+			breakpoint = "*"
+		}
+	}
+
+	fmt.Fprintf(c.out, "%s%s%4d  %s\n", marker, breakpoint, line, c.lines[line-1])
+}
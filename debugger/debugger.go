@@ -0,0 +1,289 @@
+package debugger
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/evaluator"
+	"akdjr/monkey/interpreter"
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/object"
+	"akdjr/monkey/parser"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Command tells a stopped program how to carry on
+type Command int
+
+// the commands a stopped program can be given.  stepping goes a line at a time: StepIn stops at the next line run, in any function, StepOver skips the lines of the functions called from the current line, and StepOut runs until the current function returns
+const (
+	Continue Command = iota
+	StepIn
+	StepOver
+	StepOut
+)
+
+// Reason is why a program stopped
+type Reason string
+
+// the reasons a program stops for
+const (
+	ReasonEntry      Reason = "entry"
+	ReasonBreakpoint Reason = "breakpoint"
+	ReasonStep       Reason = "step"
+)
+
+// ErrTerminated is returned by Run when the program was ended with Terminate before it finished
+var ErrTerminated = errors.New("debugger: the program was terminated")
+
+// Frame is a function call that has not returned yet.  the frame of the program itself is named main and has no Function
+// Env holds the bindings visible in the frame, and Line and Column are the position of the statement the frame is at
+type Frame struct {
+	Name     string
+	Function *object.Function
+	Env      *object.Environment
+	Line     int
+	Column   int
+}
+
+// Stop describes where a program stopped.  Frames is the call stack at that point, the innermost frame first
+type Stop struct {
+	Reason Reason
+	Line   int
+	Column int
+	Frames []*Frame
+}
+
+// Debugger runs a program a statement at a time, stopping at breakpoints and after steps
+type Debugger struct {
+	// Stopped is called whenever the program stops, and the program waits for it to return the command that tells it how to carry on.  it is called on the goroutine running the program.  a nil Stopped lets the program run to the end
+	Stopped func(stop *Stop) Command
+
+	// StopOnEntry stops the program before its first statement runs
+	StopOnEntry bool
+
+	mu          sync.Mutex
+	breakpoints map[int]bool
+	cancel      context.CancelFunc
+	ctx         context.Context
+	terminated  bool
+
+	// only touched by the goroutine running the program
+	frames  []*Frame
+	command Command
+	depth   int
+	entry   bool
+}
+
+// New creates a Debugger with no breakpoints
+func New() *Debugger {
+	return &Debugger{breakpoints: map[int]bool{}}
+}
+
+// SetBreakpoints replaces the breakpoints with ones on lines.  it can be called while the program is running
+func (d *Debugger) SetBreakpoints(lines []int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.breakpoints = map[int]bool{}
+	for _, line := range lines {
+		d.breakpoints[line] = true
+	}
+}
+
+// Breakpoints returns the lines with a breakpoint, in order
+func (d *Debugger) Breakpoints() []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	lines := []int{}
+	for line := range d.breakpoints {
+		lines = append(lines, line)
+	}
+
+	sort.Ints(lines)
+
+	return lines
+}
+
+// Terminate ends the program being run.  a stopped program ends as soon as Stopped returns
+func (d *Debugger) Terminate() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.terminated = true
+	if d.cancel != nil {
+		d.cancel()
+	}
+}
+
+// Run runs source with i, stopping as the breakpoints and commands say, and returns the same as i.RunContext.  i.Hooks are replaced for the duration of the run
+func (d *Debugger) Run(ctx context.Context, i *interpreter.Interpreter, source string) (object.Object, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	d.mu.Lock()
+	d.ctx, d.cancel, d.terminated = ctx, cancel, false
+	d.mu.Unlock()
+
+	d.frames = nil
+	d.command = Continue
+	d.depth = 0
+	d.entry = d.StopOnEntry
+
+	hooks := i.Hooks
+	i.Hooks = &evaluator.Hooks{
+		BeforeNode:    d.beforeNode,
+		EnterFunction: d.enterFunction,
+		ExitFunction:  d.exitFunction,
+	}
+	defer func() { i.Hooks = hooks }()
+
+	result, err := i.RunContext(ctx, source)
+
+	d.mu.Lock()
+	terminated := d.terminated
+	d.mu.Unlock()
+
+	var runtimeErr *interpreter.RuntimeError
+	if terminated && errors.As(err, &runtimeErr) && runtimeErr.Object == evaluator.ErrCanceled {
+		return nil, ErrTerminated
+	}
+
+	return result, err
+}
+
+// Evaluate evaluates code in the environment of frame, for inspecting a stopped program.  errors in code, including parser errors, are returned as an *object.Error.  the evaluation stops when the program is terminated
+func (d *Debugger) Evaluate(frame *Frame, code string) object.Object {
+	d.mu.Lock()
+	ctx := d.ctx
+	d.mu.Unlock()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return d.EvaluateContext(ctx, frame, code)
+}
+
+// EvaluateContext evaluates code the same as Evaluate, but stops once ctx is done instead.  code such as recv(channel()) may never end on its own, a caller that has to stay responsive gives it a context it can cancel
+func (d *Debugger) EvaluateContext(ctx context.Context, frame *Frame, code string) object.Object {
+	p := parser.New(lexer.New(code))
+	program := p.ParseProgram()
+
+	if errors := p.Errors(); len(errors) > 0 {
+		return &object.Error{Message: strings.Join(errors, "\n")}
+	}
+
+	return evaluator.EvalContext(ctx, program, frame.Env)
+}
+
+// beforeNode stops the program before a statement on a new line, if it should stop there
+func (d *Debugger) beforeNode(node ast.Node, env *object.Environment) {
+	line, column, ok := position(node)
+	if !ok {
+		return
+	}
+
+	if len(d.frames) == 0 {
+		d.frames = append(d.frames, &Frame{Name: "main", Env: env})
+	}
+
+	// a line is stopped at once, no matter how many statements are on it
+	frame := d.frames[len(d.frames)-1]
+	if frame.Line == line {
+		return
+	}
+
+	frame.Line, frame.Column = line, column
+
+	reason, stop := d.shouldStop(line)
+	if !stop || d.Stopped == nil {
+		return
+	}
+
+	d.command = d.Stopped(&Stop{Reason: reason, Line: line, Column: column, Frames: d.stack()})
+	d.depth = len(d.frames)
+}
+
+// shouldStop reports whether the program should stop at line, and why
+func (d *Debugger) shouldStop(line int) (Reason, bool) {
+	if d.entry {
+		d.entry = false
+		return ReasonEntry, true
+	}
+
+	d.mu.Lock()
+	breakpoint := d.breakpoints[line]
+	d.mu.Unlock()
+
+	if breakpoint {
+		return ReasonBreakpoint, true
+	}
+
+	switch d.command {
+	case StepIn:
+		return ReasonStep, true
+	case StepOver:
+		return ReasonStep, len(d.frames) <= d.depth
+	case StepOut:
+		return ReasonStep, len(d.frames) < d.depth
+	}
+
+	return "", false
+}
+
+func (d *Debugger) enterFunction(fn *object.Function, args []object.Object, env *object.Environment) {
+	d.frames = append(d.frames, &Frame{Name: fn.Name(), Function: fn, Env: env})
+}
+
+func (d *Debugger) exitFunction(fn *object.Function, result object.Object) {
+	if len(d.frames) > 0 {
+		d.frames = d.frames[:len(d.frames)-1]
+	}
+}
+
+// stack returns a copy of the frames, the innermost first
+func (d *Debugger) stack() []*Frame {
+	frames := make([]*Frame, 0, len(d.frames))
+	for i := len(d.frames) - 1; i >= 0; i-- {
+		frame := *d.frames[i]
+		frames = append(frames, &frame)
+	}
+
+	return frames
+}
+
+// Lines returns the lines of program that start a statement, in order.  these are the lines a program can stop at, the others are passed over by steps and their breakpoints are never hit
+func Lines(program *ast.Program) []int {
+	seen := map[int]bool{}
+	lines := []int{}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		if line, _, ok := position(node); ok && !seen[line] {
+			seen[line] = true
+			lines = append(lines, line)
+		}
+		return true
+	})
+
+	sort.Ints(lines)
+
+	return lines
+}
+
+// position returns the position of node if it is a statement the program can stop at.  block statements are not, the statements in them are.  statements made up by macros have no position
+func position(node ast.Node) (line int, column int, ok bool) {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		line, column = node.Token.Line, node.Token.Column
+	case *ast.ReturnStatement:
+		line, column = node.Token.Line, node.Token.Column
+	case *ast.ExpressionStatement:
+		line, column = node.Token.Line, node.Token.Column
+	}
+
+	return line, column, line > 0
+}
@@ -0,0 +1,211 @@
+package debugger
+
+import (
+	"akdjr/monkey/interpreter"
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/parser"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const program = `let add = fn(a, b) {
+  let sum = a + b;
+  sum
+};
+let x = add(1, 2);
+let y = add(x, 10);
+y * 2
+`
+
+// describe describes a stop as reason, line and the names of the frames
+func describe(stop *Stop) string {
+	names := []string{}
+	for _, frame := range stop.Frames {
+		names = append(names, frame.Name)
+	}
+
+	return fmt.Sprintf("%s %d %s", stop.Reason, stop.Line, strings.Join(names, ","))
+}
+
+func TestStepping(t *testing.T) {
+	tests := []struct {
+		name        string
+		breakpoints []int
+		commands    []Command
+		expected    []string
+	}{
+		{"continue", []int{2}, []Command{Continue, Continue, Continue}, []string{"entry 1 main", "breakpoint 2 add,main", "breakpoint 2 add,main"}},
+		{"step in", nil, []Command{StepIn, StepIn, StepIn, StepIn, StepIn, Continue}, []string{"entry 1 main", "step 5 main", "step 2 add,main", "step 3 add,main", "step 6 main", "step 2 add,main"}},
+		{"step over", nil, []Command{StepOver, StepOver, StepOver, Continue}, []string{"entry 1 main", "step 5 main", "step 6 main", "step 7 main"}},
+		{"step over a breakpoint", []int{3}, []Command{StepOver, StepOver, StepOver, Continue}, []string{"entry 1 main", "step 5 main", "breakpoint 3 add,main", "step 6 main", "breakpoint 3 add,main"}},
+		{"step out", []int{2}, []Command{Continue, StepOut, Continue}, []string{"entry 1 main", "breakpoint 2 add,main", "step 6 main", "breakpoint 2 add,main"}},
+	}
+
+	for _, tt := range tests {
+		stops := []string{}
+
+		d := New()
+		d.StopOnEntry = true
+		d.SetBreakpoints(tt.breakpoints)
+		d.Stopped = func(stop *Stop) Command {
+			stops = append(stops, describe(stop))
+			if len(stops) > len(tt.commands) {
+				return Continue
+			}
+			return tt.commands[len(stops)-1]
+		}
+
+		result, err := d.Run(context.Background(), interpreter.New(), program)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.name, err)
+		}
+
+		if result.Inspect() != "26" {
+			t.Errorf("%s: wrong result. got=%s", tt.name, result.Inspect())
+		}
+
+		if strings.Join(stops, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%s: wrong stops.\nexpected=%q\ngot=%q", tt.name, tt.expected, stops)
+		}
+	}
+}
+
+func TestFrames(t *testing.T) {
+	input := `let countdown = fn(n) {
+  if (n == 0) {
+    0
+  } else {
+    countdown(n - 1)
+  }
+};
+let outer = fn(m) { let result = countdown(m); result };
+outer(2)`
+
+	tests := []struct {
+		frame    int
+		code     string
+		expected string
+	}{
+		{0, "n", "0"},
+		{1, "m * 10", "20"},
+		{2, "m", "ERROR: identifier not found: m"},
+		{1, "result", "ERROR: identifier not found: result"},
+		{0, "let", "ERROR: expected next token to be IDENTIFIER, got EOF instead"},
+	}
+
+	var frames []*Frame
+	values := []string{}
+
+	d := New()
+	d.SetBreakpoints([]int{3})
+	d.Stopped = func(stop *Stop) Command {
+		frames = stop.Frames
+		for _, tt := range tests {
+			values = append(values, d.Evaluate(frames[tt.frame], tt.code).Inspect())
+		}
+		return Continue
+	}
+
+	if _, err := d.Run(context.Background(), interpreter.New(), input); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// the tail calls of countdown take the place of the call that made them
+	expected := []string{"countdown 3", "outer 8", "main 9"}
+	if len(frames) != len(expected) {
+		t.Fatalf("wrong number of frames. expected=%d, got=%d", len(expected), len(frames))
+	}
+
+	for i, frame := range frames {
+		if got := fmt.Sprintf("%s %d", frame.Name, frame.Line); got != expected[i] {
+			t.Errorf("wrong frame %d. expected=%q, got=%q", i, expected[i], got)
+		}
+	}
+
+	for i, tt := range tests {
+		if values[i] != tt.expected {
+			t.Errorf("wrong value of %q in frame %d. expected=%q, got=%q", tt.code, tt.frame, tt.expected, values[i])
+		}
+	}
+}
+
+func TestTerminate(t *testing.T) {
+	d := New()
+	d.StopOnEntry = true
+	d.Stopped = func(stop *Stop) Command {
+		d.Terminate()
+		return Continue
+	}
+
+	var out bytes.Buffer
+	i := interpreter.New()
+	i.Stdout = &out
+
+	if _, err := d.Run(context.Background(), i, "puts(1); puts(2)"); err != ErrTerminated {
+		t.Errorf("expected ErrTerminated. got=%v", err)
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("the program ran after it was terminated. got=%q", out.String())
+	}
+
+	if i.Hooks != nil {
+		t.Errorf("the hooks of the interpreter were not restored")
+	}
+}
+
+func TestCLI(t *testing.T) {
+	input := "b 2\nc\nbt\nlocals\np a + b\nn\nf 1\nglobals\nclear 2\nbreakpoints\nc\n"
+
+	var out bytes.Buffer
+	if err := NewCLI(strings.NewReader(input), &out).Run(interpreter.New(), program); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := `stopped at line 1 in main (entry)
+=>    1  let add = fn(a, b) {
+(debug) breakpoint set at line 2
+(debug) stopped at line 2 in add (breakpoint)
+=>*   2    let sum = a + b;
+(debug) > #0 add at line 2
+  #1 main at line 5
+(debug) a = 1
+b = 2
+(debug) 3
+(debug) stopped at line 3 in add (step)
+=>    3    sum
+(debug) #1 main at line 5
+=>    5  let x = add(1, 2);
+(debug) add = fn(a, b) { let sum = (a + b);sum }
+(debug) breakpoint cleared at line 2
+(debug) no breakpoints
+(debug) program finished: 26
+`
+
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+
+	// the end of the input ends the program
+	out.Reset()
+	if err := NewCLI(strings.NewReader("s\n"), &out).Run(interpreter.New(), program); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if strings.Contains(out.String(), "program finished") {
+		t.Errorf("the program finished after the input ended. got=%q", out.String())
+	}
+}
+
+func TestLines(t *testing.T) {
+	p := parser.New(lexer.New(program))
+	lines := Lines(p.ParseProgram())
+
+	expected := []int{1, 2, 3, 5, 6, 7}
+	if fmt.Sprint(lines) != fmt.Sprint(expected) {
+		t.Errorf("wrong lines. expected=%v, got=%v", expected, lines)
+	}
+}
@@ -0,0 +1,233 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around every change
+const context = 3
+
+// opKind is the kind of an edit needed to turn one list of lines into another
+type opKind int
+
+const (
+	equal opKind = iota
+	deletion
+	insertion
+)
+
+// op is a single line of an edit script.  aLine and bLine are the 0-based indexes of the line in a and b
+type op struct {
+	kind  opKind
+	text  string
+	aLine int
+	bLine int
+}
+
+// Unified returns a unified diff that turns a into b, labelled with oldName and newName.  It returns an empty string when a and b are equal
+func Unified(oldName string, newName string, a string, b string) string {
+	if a == b {
+		return ""
+	}
+
+	ops := edits(splitLines(a), splitLines(b))
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+
+	for _, h := range hunks(ops) {
+		writeHunk(&out, h)
+	}
+
+	return out.String()
+}
+
+// splitLines splits s into lines, dropping the final newline so "a\n" and "a" only differ in content, not in line count
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// edits computes the shortest edit script from a to b with Myers' algorithm
+// every round d of the search records the furthest reaching x for each diagonal k, the trace of rounds is then walked backwards to recover the edits
+func edits(a []string, b []string) []op {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+
+	offset := max
+	v := make([]int, 2*max+2)
+	trace := [][]int{}
+
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				// step down, an insertion from b
+				x = v[offset+k+1]
+			} else {
+				// step right, a deletion from a
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// walk the trace backwards from the end of both inputs, collecting edits in reverse
+	reversed := []op{}
+	x, y := n, m
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, op{kind: equal, text: a[x], aLine: x, bLine: y})
+		}
+
+		if d > 0 {
+			if x == prevX {
+				y--
+				reversed = append(reversed, op{kind: insertion, text: b[y], aLine: x, bLine: y})
+			} else {
+				x--
+				reversed = append(reversed, op{kind: deletion, text: a[x], aLine: x, bLine: y})
+			}
+		}
+	}
+
+	ops := make([]op, len(reversed))
+	for i, o := range reversed {
+		ops[len(reversed)-1-i] = o
+	}
+
+	return ops
+}
+
+// hunks groups the edit script into runs of changes surrounded by up to context unchanged lines.  changes that are close together share a hunk
+func hunks(ops []op) [][]op {
+	result := [][]op{}
+
+	i := 0
+	for i < len(ops) {
+		// find the next change
+		for i < len(ops) && ops[i].kind == equal {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		// extend the hunk while the unchanged gap to the next change is small enough to show as context
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != equal {
+				end++
+				continue
+			}
+
+			gap := end
+			for gap < len(ops) && ops[gap].kind == equal {
+				gap++
+			}
+
+			if gap == len(ops) || gap-end > 2*context {
+				break
+			}
+
+			end = gap
+		}
+
+		stop := end + context
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+
+		result = append(result, ops[start:stop])
+		i = stop
+	}
+
+	return result
+}
+
+// writeHunk writes a single hunk with its @@ header
+func writeHunk(out *bytes.Buffer, h []op) {
+	aStart, bStart := h[0].aLine, h[0].bLine
+	aCount, bCount := 0, 0
+
+	for _, o := range h {
+		switch o.kind {
+		case equal:
+			aCount++
+			bCount++
+		case deletion:
+			aCount++
+		case insertion:
+			bCount++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+
+	for _, o := range h {
+		switch o.kind {
+		case equal:
+			out.WriteString(" " + o.text + "\n")
+		case deletion:
+			out.WriteString("-" + o.text + "\n")
+		case insertion:
+			out.WriteString("+" + o.text + "\n")
+		}
+	}
+}
+
+// hunkRange formats the start,count pair of a hunk header.  lines are 1-based, and an empty range points at the line before it
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,52 @@
+package diff
+
+import (
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected string
+	}{
+		{
+			"let x = 5;\n",
+			"let x = 5;\n",
+			"",
+		},
+		{
+			"a\nb\nc\n",
+			"a\nB\nc\n",
+			"--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			"",
+			"a\nb\n",
+			"--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			"a\nb\n",
+			"",
+			"--- old\n+++ new\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			"--- old\n+++ new\n@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n@@ -9,4 +10,3 @@\n 9\n 10\n 11\n-12\n",
+		},
+		{
+			"1\n2\n3\n4\n5\n6\n",
+			"1\n2\nthree\n4\nfive\n6\n",
+			"--- old\n+++ new\n@@ -1,6 +1,6 @@\n 1\n 2\n-3\n+three\n 4\n-5\n+five\n 6\n",
+		},
+	}
+
+	for i, tt := range tests {
+		actual := Unified("old", "new", tt.a, tt.b)
+
+		if actual != tt.expected {
+			t.Errorf("tests[%d] - diff wrong.\nexpected=\n%s\ngot=\n%s", i, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package evaluator
+
+import (
+	"akdjr/monkey/object"
+	"reflect"
+	"sort"
+)
+
+// builtin is a builtin function that is part of the language.  unlike an object.Builtin it is given the evaluator that calls it, so blocking builtins can stop when the evaluation is canceled
+type builtin struct {
+	name string
+	fn   func(e *Evaluator, args ...object.Object) object.Object
+}
+
+func (b *builtin) Inspect() string         { return "builtin function " + b.name }
+func (b *builtin) Type() object.ObjectType { return object.BUILTIN_OBJ }
+
+// builtins are found when an identifier is not defined in the environment, so they can be shadowed by a let statement
+var builtins map[string]*builtin
+
+// builtins is filled in by init, as the builtins that call functions refer back to it through the evaluator
+func init() {
+	builtins = map[string]*builtin{}
+
+	for _, b := range []*builtin{
+		{"channel", builtinChannel},
+		{"send", builtinSend},
+		{"recv", builtinRecv},
+		{"close", builtinClose},
+		{"wait", builtinWait},
+		{"select", builtinSelect},
+	} {
+		builtins[b.name] = b
+	}
+}
+
+// Builtins returns the names of the builtin functions that are part of the language, sorted
+func Builtins() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// channel() creates an unbuffered channel, channel(size) creates a channel that buffers up to size values
+func builtinChannel(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+
+	size := int64(0)
+	if len(args) == 1 {
+		integer, ok := args[0].(*object.Integer)
+		if !ok {
+			return newError("argument to `channel` must be INTEGER, got %s", args[0].Type())
+		}
+
+		if integer.Value < 0 {
+			return newError("argument to `channel` must not be negative, got %d", integer.Value)
+		}
+
+		size = integer.Value
+	}
+
+	// count the buffer before it is made, a huge buffer must not be allocated at all
+	if err := e.allocate(pointerSize * size); err != nil {
+		return err
+	}
+
+	return object.NewChannel(int(size))
+}
+
+// send(channel, value) sends value over channel, waiting until there is room for it
+func builtinSend(e *Evaluator, args ...object.Object) (result object.Object) {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	ch, ok := args[0].(*object.Channel)
+	if !ok {
+		return newError("first argument to `send` must be CHANNEL, got %s", args[0].Type())
+	}
+
+	// sending on a closed channel panics, even when the channel is closed while waiting to send
+	defer func() {
+		if recover() != nil {
+			result = newError("send on closed channel")
+		}
+	}()
+
+	select {
+	case ch.Ch <- args[1]:
+		return NULL
+	case <-e.done():
+		return e.interrupted()
+	}
+}
+
+// recv(channel) waits for a value from channel and returns it.  once the channel is closed and empty, recv returns null
+func builtinRecv(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	ch, ok := args[0].(*object.Channel)
+	if !ok {
+		return newError("argument to `recv` must be CHANNEL, got %s", args[0].Type())
+	}
+
+	select {
+	case value, ok := <-ch.Ch:
+		if !ok {
+			return NULL
+		}
+
+		return value
+	case <-e.done():
+		return e.interrupted()
+	}
+}
+
+// close(channel) closes channel, after which nothing more can be sent over it
+func builtinClose(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	ch, ok := args[0].(*object.Channel)
+	if !ok {
+		return newError("argument to `close` must be CHANNEL, got %s", args[0].Type())
+	}
+
+	if !ch.Close() {
+		return newError("close of closed channel")
+	}
+
+	return NULL
+}
+
+// wait(task, ...) waits for every task to finish.  it returns the result of a single task, or an array with the results of many.  an error in any of the tasks is returned as the result of wait, so it is not lost
+func builtinWait(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments. got=0, want at least 1")
+	}
+
+	results := make([]object.Object, 0, len(args))
+
+	for _, arg := range args {
+		task, ok := arg.(*object.Task)
+		if !ok {
+			return newError("argument to `wait` must be TASK, got %s", arg.Type())
+		}
+
+		select {
+		case <-task.Done():
+		case <-e.done():
+			return e.interrupted()
+		}
+
+		result := task.Result()
+		if isError(result) {
+			return result
+		}
+
+		results = append(results, result)
+	}
+
+	if len(results) == 1 {
+		return results[0]
+	}
+
+	return &object.Array{Elements: results}
+}
+
+// select(channel, fn(value) { ... }, ...) waits until one of the channels has a value and calls the function that follows it with that value, or with null when the channel is closed
+// a function without a channel before it, at the end of the arguments, is called instead of waiting when none of the channels have a value
+func builtinSelect(e *Evaluator, args ...object.Object) object.Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments. got=0, want at least 1")
+	}
+
+	cases := []reflect.SelectCase{}
+	handlers := []object.Object{}
+	var fallback object.Object
+
+	for i := 0; i < len(args); i += 2 {
+		if i == len(args)-1 {
+			fallback = args[i]
+			break
+		}
+
+		ch, ok := args[i].(*object.Channel)
+		if !ok {
+			return newError("argument %d to `select` must be CHANNEL, got %s", i+1, args[i].Type())
+		}
+
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch.Ch)})
+		handlers = append(handlers, args[i+1])
+	}
+
+	if fallback != nil {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	}
+
+	if done := e.done(); done != nil {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(done)})
+	}
+
+	chosen, value, ok := reflect.Select(cases)
+
+	switch {
+	case chosen < len(handlers):
+		var received object.Object = NULL
+		if ok {
+			received = value.Interface().(object.Object)
+		}
+
+		return e.applyFunction(handlers[chosen], []object.Object{received})
+	case fallback != nil && chosen == len(handlers):
+		return e.applyFunction(fallback, nil)
+	default:
+		return e.interrupted()
+	}
+}
@@ -0,0 +1,68 @@
+package evaluator
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/object"
+	"context"
+	"errors"
+)
+
+// the errors returned when the context of an evaluation is done before the evaluation is
+var (
+	ErrCanceled         = &object.Error{Message: "evaluation canceled"}
+	ErrDeadlineExceeded = &object.Error{Message: "evaluation deadline exceeded"}
+)
+
+// EvalContext evaluates node in env the same as Eval, but stops with ErrCanceled or ErrDeadlineExceeded once ctx is done
+func EvalContext(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	return New().EvalContext(ctx, node, env)
+}
+
+// EvalContext evaluates node in env the same as e.Eval, but stops with ErrCanceled or ErrDeadlineExceeded once ctx is done.  ctx is checked before every function call, which includes every iteration of a tail recursive loop
+func (e *Evaluator) EvalContext(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	previous := e.ctx
+	e.ctx = ctx
+	defer func() { e.ctx = previous }()
+
+	if err := e.interrupted(); err != nil {
+		return err
+	}
+
+	return e.Eval(node, env)
+}
+
+// interrupted returns the error for the context of the current evaluation if it is done, or nil
+func (e *Evaluator) interrupted() *object.Error {
+	if e.ctx == nil {
+		return nil
+	}
+
+	select {
+	case <-e.ctx.Done():
+		if errors.Is(e.ctx.Err(), context.DeadlineExceeded) {
+			return ErrDeadlineExceeded
+		}
+
+		return ErrCanceled
+	default:
+		return nil
+	}
+}
+
+// ApplyContext calls fn with args the same as e.Apply, but stops with ErrCanceled or ErrDeadlineExceeded once ctx is done
+func (e *Evaluator) ApplyContext(ctx context.Context, fn object.Object, args []object.Object) object.Object {
+	previous := e.ctx
+	e.ctx = ctx
+	defer func() { e.ctx = previous }()
+
+	return e.Apply(fn, args)
+}
+
+// ExpandMacrosContext expands macros the same as e.ExpandMacros, but the macro bodies stop with ErrCanceled or ErrDeadlineExceeded once ctx is done, which fails the expansion
+func (e *Evaluator) ExpandMacrosContext(ctx context.Context, program ast.Node, env *object.Environment) (ast.Node, error) {
+	previous := e.ctx
+	e.ctx = ctx
+	defer func() { e.ctx = previous }()
+
+	return e.ExpandMacros(program, env)
+}
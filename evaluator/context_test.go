@@ -0,0 +1,55 @@
+package evaluator
+
+import (
+	"akdjr/monkey/object"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEvalContext(t *testing.T) {
+	forever := `let loop = fn(n) { loop(n + 1) }; loop(0)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if evaluated := EvalContext(ctx, testParseProgram(forever), object.NewEnvironment()); evaluated != ErrDeadlineExceeded {
+		t.Errorf("expected ErrDeadlineExceeded. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	if evaluated := EvalContext(ctx, testParseProgram(forever), object.NewEnvironment()); evaluated != ErrCanceled {
+		t.Errorf("expected ErrCanceled. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	// the evaluator can be used again once an evaluation has been canceled
+	e := New()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if evaluated := e.EvalContext(canceled, testParseProgram("1 + 1"), object.NewEnvironment()); evaluated != ErrCanceled {
+		t.Errorf("expected ErrCanceled. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	evaluated := e.EvalContext(context.Background(), testParseProgram("let f = fn(x) { x + 1 }; f(1)"), object.NewEnvironment())
+	testIntegerObject(t, evaluated, 2)
+}
+
+func TestExpandMacrosContext(t *testing.T) {
+	program := testParseProgram("let forever = macro() { let loop = fn(n) { loop(n + 1) }; loop(0) }; forever();")
+	env := object.NewEnvironment()
+	DefineMacros(program, env)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := New().ExpandMacrosContext(ctx, program, env)
+	if err == nil || err.Error() != "error expanding macro forever: evaluation deadline exceeded" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+}
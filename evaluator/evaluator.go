@@ -3,6 +3,7 @@ package evaluator
 import (
 	"akdjr/monkey/ast"
 	"akdjr/monkey/object"
+	"context"
 	"fmt"
 )
 
@@ -28,90 +29,368 @@ func isError(obj object.Object) bool {
 	return false
 }
 
+// Evaluator evaluates AST nodes.  It keeps track of the resources used while evaluating so that they can be bounded by Limits
+type Evaluator struct {
+	// Limits bounds the resources used by evaluation, the zero value places no bounds at all
+	Limits Limits
+
+	// depth is only counted for the goroutine this evaluator runs on, the rest of the resources are shared with the tasks it spawns
+	depth  int
+	budget *budget
+
+	// Hooks are called as nodes are evaluated and functions are called, when they are set.  tasks started with spawn run without hooks
+	Hooks *Hooks
+
+	// TaskContext, when set, is the context tasks started with spawn run in, instead of the context of the evaluation that spawned them.  it lets tasks outlive the evaluation, such as a REPL line, that started them
+	TaskContext context.Context
+
+	// ctx is the context of the current call to EvalContext, if any
+	ctx context.Context
+}
+
+// New creates an Evaluator without any limits
+func New() *Evaluator {
+	return &Evaluator{budget: &budget{}}
+}
+
 // Eval takes an AST node, evaluates it and returns the result wrapped in structure implementing the object interface.  Eval also takes an Environment that represents the current state of all names and values.
+// Eval evaluates node without any limits, use an Evaluator to bound the resources it may use
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return New().Eval(node, env)
+}
+
+// Eval evaluates node in env the same as the package level Eval, but returns one of ErrDepthLimit, ErrStepLimit or ErrAllocationLimit as soon as evaluation exceeds e.Limits
+// the resources used are counted over every call to Eval on the same Evaluator
+func (e *Evaluator) Eval(node ast.Node, env *object.Environment) object.Object {
+	if e.Hooks != nil {
+		return e.hooked(node, env, e.eval)
+	}
+
+	return e.eval(node, env)
+}
+
+// eval evaluates node without calling the hooks for it
+func (e *Evaluator) eval(node ast.Node, env *object.Environment) object.Object {
+	if err := e.step(); err != nil {
+		return err
+	}
+
 	switch node := node.(type) {
 	// statements
 	case *ast.Program:
-		return evalProgram(node.Statements, env)
+		return e.evalProgram(node.Statements, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return e.Eval(node.Expression, env)
 	case *ast.BlockStatement:
-		return evalBlockStatement(node.Statements, env)
+		return e.evalBlockStatement(node.Statements, env)
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
+		val := e.Eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 
 		return &object.ReturnValue{Value: val}
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
+		val := e.Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
 
 		env.Set(node.Name.Value, val)
 
+		// a let statement is only there for its binding, it has no value of its own
+		return NULL
+
 	// Expressions
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+		return e.track(&object.Integer{Value: node.Value})
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := e.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
 
-		return evalPrefixExpression(node.Operator, right)
+		return e.track(evalPrefixExpression(node.Operator, right))
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
+		left := e.Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
 
-		right := Eval(node.Right, env)
+		right := e.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
 
-		return evalInfixExpression(node.Operator, left, right)
+		return e.track(evalInfixExpression(node.Operator, left, right))
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return e.evalIfExpression(node, env)
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Body: body, Parameters: params, Env: env}
+		return e.track(&object.Function{Body: body, Parameters: params, Env: env})
+	case *ast.MacroLiteral:
+		return newError("macro literals can only be defined with a top-level let statement")
+	case *ast.SpawnExpression:
+		return e.track(e.evalSpawnExpression(node, env))
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		// quote is not a function, its argument must not be evaluated
+		if isQuoteCall(node) {
+			return e.track(e.evalQuoteCall(node, env))
+		}
+
+		function := e.Eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
-		args := evalExpressions(node.Arguments, env)
+		args := e.evalExpressions(node.Arguments, env)
 
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		return e.applyFunction(function, args)
+	}
+
+	return newError("unknown node: %T", node)
+}
+
+// applyFunction calls fn with args.  calls in tail position inside the function body are not made from within the body, they are handed back as a tailCall and made here instead.
+// this trampoline keeps tail recursive monkey functions from growing the Go stack, no matter how deep they recurse.  for the same reason, only the call made here counts towards Limits.MaxDepth
+func (e *Evaluator) applyFunction(fn object.Object, args []object.Object) object.Object {
+	if e.Limits.MaxDepth > 0 && e.depth >= e.Limits.MaxDepth {
+		return ErrDepthLimit
+	}
+
+	e.depth++
+	defer func() { e.depth-- }()
+
+	for {
+		if err := e.interrupted(); err != nil {
+			return err
+		}
+
+		switch b := fn.(type) {
+		case *object.Builtin:
+			return e.track(orNull(b.Fn(args...)))
+		case *builtin:
+			return e.track(b.fn(e, args...))
+		}
+
+		function, ok := fn.(*object.Function)
+		if !ok {
+			return newError("not a function: %s", fn.Type())
+		}
+
+		if len(args) != len(function.Parameters) {
+			return newError("wrong number of arguments: want=%d, got=%d", len(function.Parameters), len(args))
+		}
+
+		extendedEnv := extendFunctionEnv(function, args)
+		if err := e.allocate(environmentSize(len(args))); err != nil {
+			return err
+		}
+
+		if e.Hooks != nil && e.Hooks.EnterFunction != nil {
+			e.Hooks.EnterFunction(function, args, extendedEnv)
+		}
+
+		evaluated := unwrapReturnValue(e.evalTailBlock(function.Body.Statements, extendedEnv, true))
+
+		call, ok := evaluated.(*tailCall)
+
+		if e.Hooks != nil && e.Hooks.ExitFunction != nil {
+			if ok {
+				e.Hooks.ExitFunction(function, nil)
+			} else {
+				e.Hooks.ExitFunction(function, evaluated)
+			}
+		}
+
+		if !ok {
+			return evaluated
+		}
+
+		fn, args = call.function, call.args
+	}
+}
+
+// orNull returns obj, or NULL when a builtin implemented in Go returns nothing at all
+func orNull(obj object.Object) object.Object {
+	if obj == nil {
+		return NULL
+	}
+
+	return obj
+}
+
+// Apply calls fn, a monkey function or a builtin, with args and returns the result.  The call is made within e.Limits, the same as a call made from monkey code
+func (e *Evaluator) Apply(fn object.Object, args []object.Object) object.Object {
+	return e.applyFunction(fn, args)
+}
+
+// tailCall is a call in tail position that has not been made yet.  It is only ever produced while evaluating a function body for applyFunction and never escapes it
+type tailCall struct {
+	function object.Object
+	args     []object.Object
+}
+
+func (tc *tailCall) Inspect() string         { return "tail call to " + tc.function.Inspect() }
+func (tc *tailCall) Type() object.ObjectType { return "TAIL_CALL" }
+
+// evalTailBlock evaluates the statements of a function body, or of an if expression branch inside one.  tail is set when the last statement of the block is the last thing evaluated by the function
+// it behaves the same as evalBlockStatement, except that a call in tail position produces a tailCall
+func (e *Evaluator) evalTailBlock(stmts []ast.Statement, env *object.Environment, tail bool) object.Object {
+	var result object.Object = NULL
+
+	for i, statement := range stmts {
+		result = e.evalTailStatement(statement, env, tail && i == len(stmts)-1)
+
+		if rt := result.Type(); rt == object.ERROR_OBJ || rt == object.RETURN_VALUE_OBJ {
+			return result
+		}
+	}
+
+	return result
+}
+
+// evalTailStatement evaluates a statement in a function body.  a return leaves the function, so the value of a return statement is always in tail position
+func (e *Evaluator) evalTailStatement(stmt ast.Statement, env *object.Environment, tail bool) object.Object {
+	// statements in function bodies do not go through Eval, so the hooks are called here instead
+	if e.Hooks != nil {
+		return e.hooked(stmt, env, func(ast.Node, *object.Environment) object.Object {
+			return e.evalTailStatementNode(stmt, env, tail)
+		})
+	}
+
+	return e.evalTailStatementNode(stmt, env, tail)
+}
+
+func (e *Evaluator) evalTailStatementNode(stmt ast.Statement, env *object.Environment, tail bool) object.Object {
+	if err := e.step(); err != nil {
+		return err
+	}
+
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStatement:
+		val := e.evalTailExpression(stmt.ReturnValue, env)
+		if isError(val) {
+			return val
+		}
+
+		return &object.ReturnValue{Value: val}
+	case *ast.ExpressionStatement:
+		if tail {
+			return e.evalTailExpression(stmt.Expression, env)
+		}
+
+		// an if expression that is not in tail position can still contain return statements
+		if ie, ok := stmt.Expression.(*ast.IfExpression); ok {
+			if e.Hooks != nil {
+				return e.hooked(ie, env, e.evalInnerIf)
+			}
+
+			return e.evalInnerIf(ie, env)
+		}
+
+		return e.Eval(stmt.Expression, env)
 	}
 
-	return nil
+	return e.eval(stmt, env)
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
-	function, ok := fn.(*object.Function)
-	if !ok {
-		return newError("not a function: %s", fn.Type())
+// evalTailExpression evaluates an expression in tail position.  a call is not made, but returned as a tailCall once its function and arguments are evaluated
+func (e *Evaluator) evalTailExpression(exp ast.Expression, env *object.Environment) object.Object {
+	var eval func(ast.Node, *object.Environment) object.Object
+
+	switch exp := exp.(type) {
+	case *ast.CallExpression:
+		if isQuoteCall(exp) {
+			return e.Eval(exp, env)
+		}
+
+		eval = e.evalTailCall
+	case *ast.IfExpression:
+		eval = e.evalTailIf
+	default:
+		return e.Eval(exp, env)
+	}
+
+	if e.Hooks != nil {
+		return e.hooked(exp, env, eval)
+	}
+
+	return eval(exp, env)
+}
+
+// evalTailCall evaluates the function and arguments of a call in tail position, and returns the call to be made
+func (e *Evaluator) evalTailCall(node ast.Node, env *object.Environment) object.Object {
+	call := node.(*ast.CallExpression)
+
+	if err := e.step(); err != nil {
+		return err
 	}
 
-	extendedEnv := extendFunctionEnv(function, args)
-	evaluated := Eval(function.Body, extendedEnv)
+	function := e.Eval(call.Function, env)
+	if isError(function) {
+		return function
+	}
+
+	args := e.evalExpressions(call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
 
-	return unwrapReturnValue(evaluated)
+	return &tailCall{function: function, args: args}
+}
+
+func (e *Evaluator) evalTailIf(node ast.Node, env *object.Environment) object.Object {
+	if err := e.step(); err != nil {
+		return err
+	}
+
+	return e.evalTailIfExpression(node.(*ast.IfExpression), env, true)
+}
+
+// evalInnerIf evaluates an if expression inside a function body that is not in tail position.  neither are its branches, but a return statement in them still returns from the function
+func (e *Evaluator) evalInnerIf(node ast.Node, env *object.Environment) object.Object {
+	if err := e.step(); err != nil {
+		return err
+	}
+
+	return e.evalTailIfExpression(node.(*ast.IfExpression), env, false)
+}
+
+// evalTailIfExpression evaluates an if expression inside a function body.  the branch that is taken is in tail position if the if expression is
+func (e *Evaluator) evalTailIfExpression(ie *ast.IfExpression, env *object.Environment, tail bool) object.Object {
+	condition := e.Eval(ie.Condition, env)
+
+	if isError(condition) {
+		return condition
+	}
+
+	if isTruthy(condition) {
+		return e.evalTailBranch(ie.Consequence, env, tail)
+	} else if ie.Alternative != nil {
+		return e.evalTailBranch(ie.Alternative, env, tail)
+	} else {
+		return NULL
+	}
+}
+
+// evalTailBranch evaluates the branch of an if expression inside a function body.  the hooks are called for the block of the branch, the same as for a branch outside a function body
+func (e *Evaluator) evalTailBranch(block *ast.BlockStatement, env *object.Environment, tail bool) object.Object {
+	if e.Hooks != nil {
+		return e.hooked(block, env, func(ast.Node, *object.Environment) object.Object {
+			return e.evalTailBlock(block.Statements, env, tail)
+		})
+	}
+
+	return e.evalTailBlock(block.Statements, env, tail)
 }
 
 func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
@@ -132,11 +411,11 @@ func unwrapReturnValue(obj object.Object) object.Object {
 	return obj
 }
 
-func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+func (e *Evaluator) evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
-	for _, e := range exps {
-		evaluated := Eval(e, env)
+	for _, exp := range exps {
+		evaluated := e.Eval(exp, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -147,25 +426,28 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 }
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	val, ok := env.Get(node.Value)
-	if !ok {
-		return newError("identifier not found: " + node.Value)
+	if val, ok := env.Get(node.Value); ok {
+		return val
 	}
 
-	return val
+	if builtin, ok := builtins[node.Value]; ok {
+		return builtin
+	}
+
+	return newError("identifier not found: %s", node.Value)
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+func (e *Evaluator) evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.Eval(ie.Condition, env)
 
 	if isError(condition) {
 		return condition
 	}
 
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		return e.Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return e.Eval(ie.Alternative, env)
 	} else {
 		return NULL
 	}
@@ -186,11 +468,12 @@ func isTruthy(obj object.Object) bool {
 
 // evaluate a program
 // if a return value is detected from evaluating a statement, unwrap it and break
-func evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
-	var result object.Object
+// an empty program evaluates to NULL, the same as an empty block
+func (e *Evaluator) evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
+	var result object.Object = NULL
 
 	for _, statement := range stmts {
-		result = Eval(statement, env)
+		result = e.Eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
@@ -205,11 +488,11 @@ func evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
 
 // Block statements can be nested (ie. nexted if statements)
 // In this case, we don't want to unwrap the return value as it might be needed later by other block statements.
-func evalBlockStatement(stmts []ast.Statement, env *object.Environment) object.Object {
-	var result object.Object
+func (e *Evaluator) evalBlockStatement(stmts []ast.Statement, env *object.Environment) object.Object {
+	var result object.Object = NULL
 
 	for _, statement := range stmts {
-		result = Eval(statement, env)
+		result = e.Eval(statement, env)
 
 		// if err, return it and bubble up
 		// Check to see if the result is a return value object.
@@ -218,11 +501,8 @@ func evalBlockStatement(stmts []ast.Statement, env *object.Environment) object.O
 		// to evalProgram.  As it is returning a ReturnValue object, evalProgram will do the unwrapping.
 		// TODO: this will change when function calls are implemented as the function needs to unwrap the value and return it.
 
-		if result != nil {
-			rt := result.Type()
-			if rt == object.ERROR_OBJ || rt == object.RETURN_VALUE_OBJ {
-				return result
-			}
+		if rt := result.Type(); rt == object.ERROR_OBJ || rt == object.RETURN_VALUE_OBJ {
+			return result
 		}
 	}
 
@@ -274,6 +554,8 @@ func evalInfixExpression(operator string, left object.Object, right object.Objec
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left, right)
 	// integer comparison is handled higher, such that the below can work on booleans
 	case operator == "==":
 		return nativeBoolToBooleanObject(left == right)
@@ -299,6 +581,10 @@ func evalIntegerInfixExpression(operator string, left object.Object, right objec
 	case "*":
 		return &object.Integer{Value: leftValue * rightValue}
 	case "/":
+		if rightValue == 0 {
+			return newError("division by zero")
+		}
+
 		return &object.Integer{Value: leftValue / rightValue}
 
 	// Integer comparison
@@ -314,3 +600,19 @@ func evalIntegerInfixExpression(operator string, left object.Object, right objec
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
+
+func evalStringInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	leftValue := left.(*object.String).Value
+	rightValue := right.(*object.String).Value
+
+	switch operator {
+	case "+":
+		return &object.String{Value: leftValue + rightValue}
+	case "==":
+		return nativeBoolToBooleanObject(leftValue == rightValue)
+	case "!=":
+		return nativeBoolToBooleanObject(leftValue != rightValue)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
@@ -123,6 +123,18 @@ if (10 > 1) {
 			"foobar",
 			"identifier not found: foobar",
 		},
+		{
+			"let f = fn(x, y) { x }; f(1)",
+			"wrong number of arguments: want=2, got=1",
+		},
+		{
+			"5(1)",
+			"not a function: INTEGER",
+		},
+		{
+			"let zero = 0; 1 / zero",
+			"division by zero",
+		},
 	}
 
 	for _, tt := range tests {
@@ -316,3 +328,120 @@ func testNullObject(t *testing.T, obj object.Object) bool {
 
 	return true
 }
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{
+			`let loop = fn(n) { if (n == 0) { 0 } else { loop(n - 1) } }; loop(1000000)`,
+			0,
+		},
+		{
+			`let sum = fn(n, acc) { if (n == 0) { return acc; } return sum(n - 1, acc + n); }; sum(1000000, 0)`,
+			500000500000,
+		},
+		{
+			`
+let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+if (isEven(1000001)) { 1 } else { 0 }`,
+			0,
+		},
+		{
+			// the if expression is not in tail position, but the return inside it is
+			`let count = fn(n) { if (n > 0) { return count(n - 1); } 42; }; count(1000000)`,
+			42,
+		},
+		{
+			// calls that are not in tail position still work
+			`let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(15)`,
+			610,
+		},
+		{
+			`let apply = fn(f, x) { f(x) }; apply(fn(x) { x * 2 }, 21)`,
+			42,
+		},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestStringInfixExpressions(t *testing.T) {
+	tests := []struct {
+		left     string
+		operator string
+		right    string
+		expected object.Object
+	}{
+		{"Hello", "+", " World!", &object.String{Value: "Hello World!"}},
+		{"monkey", "==", "monkey", TRUE},
+		{"monkey", "==", "donkey", FALSE},
+		{"monkey", "!=", "donkey", TRUE},
+		{"monkey", "-", "key", newError("unknown operator: STRING - STRING")},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.Set("left", &object.String{Value: tt.left})
+		env.Set("right", &object.String{Value: tt.right})
+
+		evaluated := Eval(testParseProgram("left "+tt.operator+" right"), env)
+
+		switch expected := tt.expected.(type) {
+		case *object.String:
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected.Value {
+				t.Errorf("wrong result for %q %s %q. expected=%q, got=%T (%+v)", tt.left, tt.operator, tt.right, expected.Value, evaluated, evaluated)
+			}
+		case *object.Error:
+			err, ok := evaluated.(*object.Error)
+			if !ok || err.Message != expected.Message {
+				t.Errorf("wrong result for %q %s %q. expected=%q, got=%T (%+v)", tt.left, tt.operator, tt.right, expected.Message, evaluated, evaluated)
+			}
+		default:
+			if evaluated != expected {
+				t.Errorf("wrong result for %q %s %q. expected=%s, got=%T (%+v)", tt.left, tt.operator, tt.right, expected.Inspect(), evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("double", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments. got=%d, want=1", len(args))
+		}
+
+		return &object.Integer{Value: args[0].(*object.Integer).Value * 2}
+	}})
+
+	testIntegerObject(t, Eval(testParseProgram("double(21)"), env), 42)
+	testIntegerObject(t, Eval(testParseProgram("let f = fn(x) { double(x) }; f(4)"), env), 8)
+
+	evaluated := Eval(testParseProgram("double(1, 2)"), env)
+	if err, ok := evaluated.(*object.Error); !ok || err.Message != "wrong number of arguments. got=2, want=1" {
+		t.Errorf("expected an error from the builtin. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestStatementsWithoutValues(t *testing.T) {
+	tests := []string{
+		"let a = 5;",
+		"",
+		"fn() {}()",
+		"if (true) {}",
+		"let f = fn() { let x = 1; }; f()",
+	}
+
+	for _, input := range tests {
+		evaluated := testEval(input)
+		if evaluated != NULL {
+			t.Errorf("%q does not evaluate to NULL. got=%T (%+v)", input, evaluated, evaluated)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package evaluator
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/object"
+)
+
+// Hooks are functions an Evaluator calls as it evaluates a program, so that tools such as debuggers and profilers can follow along.  any of them may be nil
+// the hooks are called on the goroutine doing the evaluation, which waits for them to return
+type Hooks struct {
+	// BeforeNode is called before a statement or expression is evaluated in env
+	BeforeNode func(node ast.Node, env *object.Environment)
+
+	// AfterNode is called after a statement or expression has been evaluated in env, with its result.  the result is nil when the node ends in a call in tail position, as that call is only made once the function it is in has been left
+	AfterNode func(node ast.Node, env *object.Environment, result object.Object)
+
+	// EnterFunction is called when a monkey function is called with args.  env is the environment the body of the function is evaluated in, the one that holds its parameters
+	EnterFunction func(fn *object.Function, args []object.Object, env *object.Environment)
+
+	// ExitFunction is called when a call to a monkey function returns result.  result is nil when the function ends in a call in tail position, which takes the place of the function: EnterFunction is called for it right after
+	ExitFunction func(fn *object.Function, result object.Object)
+}
+
+// hooked evaluates node with eval, calling the hooks around it.  the context of the evaluation is checked once BeforeNode returns, so a hook that waits, such as a debugger stopped at a breakpoint, can end the evaluation by canceling it
+func (e *Evaluator) hooked(node ast.Node, env *object.Environment, eval func(ast.Node, *object.Environment) object.Object) object.Object {
+	if e.Hooks.BeforeNode != nil {
+		e.Hooks.BeforeNode(node, env)
+
+		if err := e.interrupted(); err != nil {
+			return err
+		}
+	}
+
+	result := eval(node, env)
+
+	if e.Hooks.AfterNode != nil {
+		if _, ok := result.(*tailCall); ok {
+			e.Hooks.AfterNode(node, env, nil)
+		} else {
+			e.Hooks.AfterNode(node, env, result)
+		}
+	}
+
+	return result
+}
+
+// ChainHooks returns hooks that call every one of hooks in turn, so that more than one tool, such as a profiler and coverage, can follow the same evaluation.  nil hooks are left out, and nil is returned when there are none left
+func ChainHooks(hooks ...*Hooks) *Hooks {
+	chained := []*Hooks{}
+	for _, h := range hooks {
+		if h != nil {
+			chained = append(chained, h)
+		}
+	}
+
+	switch len(chained) {
+	case 0:
+		return nil
+	case 1:
+		return chained[0]
+	}
+
+	// a hook is only set when one of the chained hooks sets it, evaluating without a hook is faster than calling one that does nothing
+	h := &Hooks{}
+
+	for _, c := range chained {
+		c := c
+
+		if before := h.BeforeNode; c.BeforeNode != nil {
+			h.BeforeNode = func(node ast.Node, env *object.Environment) {
+				if before != nil {
+					before(node, env)
+				}
+				c.BeforeNode(node, env)
+			}
+		}
+
+		if after := h.AfterNode; c.AfterNode != nil {
+			h.AfterNode = func(node ast.Node, env *object.Environment, result object.Object) {
+				if after != nil {
+					after(node, env, result)
+				}
+				c.AfterNode(node, env, result)
+			}
+		}
+
+		if enter := h.EnterFunction; c.EnterFunction != nil {
+			h.EnterFunction = func(fn *object.Function, args []object.Object, env *object.Environment) {
+				if enter != nil {
+					enter(fn, args, env)
+				}
+				c.EnterFunction(fn, args, env)
+			}
+		}
+
+		if exit := h.ExitFunction; c.ExitFunction != nil {
+			h.ExitFunction = func(fn *object.Function, result object.Object) {
+				if exit != nil {
+					exit(fn, result)
+				}
+				c.ExitFunction(fn, result)
+			}
+		}
+	}
+
+	return h
+}
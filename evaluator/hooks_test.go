@@ -0,0 +1,182 @@
+package evaluator
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/object"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// recordEvents evaluates input with hooks that record the function calls and the statements evaluated
+func recordEvents(input string) []string {
+	events := []string{}
+
+	e := New()
+	e.Hooks = &Hooks{
+		BeforeNode: func(node ast.Node, env *object.Environment) {
+			if stmt, ok := node.(*ast.ExpressionStatement); ok {
+				events = append(events, "before "+stmt.String())
+			}
+		},
+		AfterNode: func(node ast.Node, env *object.Environment, result object.Object) {
+			if stmt, ok := node.(*ast.ExpressionStatement); ok {
+				if result == nil {
+					events = append(events, "after "+stmt.String()+" = tail call")
+				} else {
+					events = append(events, "after "+stmt.String()+" = "+result.Inspect())
+				}
+			}
+		},
+		EnterFunction: func(fn *object.Function, args []object.Object, env *object.Environment) {
+			names := []string{}
+			for _, param := range fn.Parameters {
+				value, _ := env.Get(param.Value)
+				names = append(names, fmt.Sprintf("%s=%s", param.Value, value.Inspect()))
+			}
+			events = append(events, "enter "+strings.Join(names, " "))
+		},
+		ExitFunction: func(fn *object.Function, result object.Object) {
+			if result == nil {
+				events = append(events, "exit tail call")
+			} else {
+				events = append(events, "exit "+result.Inspect())
+			}
+		},
+	}
+
+	e.Eval(testParseProgram(input), object.NewEnvironment())
+
+	return events
+}
+
+func TestHooks(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"1 + 2", []string{"before (1 + 2)", "after (1 + 2) = 3"}},
+		{
+			"let double = fn(x) { x * 2 }; double(4)",
+			[]string{"before double(4)", "enter x=4", "before (x * 2)", "after (x * 2) = 8", "exit 8", "after double(4) = 8"},
+		},
+		{
+			// a call in tail position is made once the function it is in has been left
+			"let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(1)",
+			[]string{
+				"before f(1)", "enter n=1",
+				"before if (n == 0) 0elsef((n - 1))", "before f((n - 1))", "after f((n - 1)) = tail call", "after if (n == 0) 0elsef((n - 1)) = tail call",
+				"exit tail call", "enter n=0",
+				"before if (n == 0) 0elsef((n - 1))", "before 0", "after 0 = 0", "after if (n == 0) 0elsef((n - 1)) = 0",
+				"exit 0", "after f(1) = 0",
+			},
+		},
+		{
+			"let f = fn() { -true }; f()",
+			[]string{"before f()", "enter ", "before (-true)", "after (-true) = ERROR: unknown operator: -BOOLEAN", "exit ERROR: unknown operator: -BOOLEAN", "after f() = ERROR: unknown operator: -BOOLEAN"},
+		},
+	}
+
+	for _, tt := range tests {
+		events := recordEvents(tt.input)
+
+		if strings.Join(events, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("wrong events for %q.\nexpected=%q\ngot=%q", tt.input, tt.expected, events)
+		}
+	}
+}
+
+func TestHooksCancel(t *testing.T) {
+	// a hook can end the evaluation by canceling its context
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	statements := 0
+
+	e := New()
+	e.Hooks = &Hooks{
+		BeforeNode: func(node ast.Node, env *object.Environment) {
+			if _, ok := node.(*ast.ExpressionStatement); ok {
+				statements++
+				cancel()
+			}
+		},
+	}
+
+	evaluated := e.EvalContext(ctx, testParseProgram("1; 2; 3"), object.NewEnvironment())
+	if evaluated != ErrCanceled {
+		t.Errorf("expected ErrCanceled. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if statements != 1 {
+		t.Errorf("wrong number of statements evaluated. expected=1, got=%d", statements)
+	}
+}
+
+func TestHooksBranches(t *testing.T) {
+	// the block of the branch taken is seen by the hooks, whether the if expression is in tail position or not
+	inputs := []string{
+		"if (true) { 1 } else { 2 }",
+		"let f = fn() { if (false) { 1 } else { 2 } }; f()",
+		"let f = fn() { let x = if (true) { 1 }; x }; f()",
+	}
+
+	for _, input := range inputs {
+		before, after := 0, 0
+
+		e := New()
+		e.Hooks = &Hooks{
+			BeforeNode: func(node ast.Node, env *object.Environment) {
+				if _, ok := node.(*ast.BlockStatement); ok {
+					before++
+				}
+			},
+			AfterNode: func(node ast.Node, env *object.Environment, result object.Object) {
+				if _, ok := node.(*ast.BlockStatement); ok {
+					after++
+				}
+			},
+		}
+
+		e.Eval(testParseProgram(input), object.NewEnvironment())
+
+		if before != 1 || after != 1 {
+			t.Errorf("wrong number of blocks seen for %q. expected=1, got=%d before and %d after", input, before, after)
+		}
+	}
+}
+
+func TestChainHooks(t *testing.T) {
+	events := []string{}
+	record := func(name string) *Hooks {
+		return &Hooks{
+			BeforeNode: func(node ast.Node, env *object.Environment) {
+				if _, ok := node.(*ast.ExpressionStatement); ok {
+					events = append(events, name+" before")
+				}
+			},
+			ExitFunction: func(fn *object.Function, result object.Object) {
+				events = append(events, name+" exit")
+			},
+		}
+	}
+
+	e := New()
+	e.Hooks = ChainHooks(record("a"), nil, record("b"), &Hooks{})
+
+	e.Eval(testParseProgram("let f = fn() { 1 }; f()"), object.NewEnvironment())
+
+	expected := []string{"a before", "b before", "a before", "b before", "a exit", "b exit"}
+	if strings.Join(events, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong events.\nexpected=%q\ngot=%q", expected, events)
+	}
+
+	if e.Hooks.AfterNode != nil || e.Hooks.EnterFunction != nil {
+		t.Errorf("hooks that none of the chained hooks set are set")
+	}
+
+	if ChainHooks(nil, nil) != nil {
+		t.Errorf("chaining no hooks returned hooks")
+	}
+}
@@ -0,0 +1,99 @@
+package evaluator
+
+import (
+	"akdjr/monkey/object"
+	"sync/atomic"
+)
+
+// Limits bounds the resources a program may use while it is evaluated, so that programs that can not be trusted can be run safely.  a limit that is zero is not enforced
+type Limits struct {
+	// MaxDepth is how many function calls may be in progress at once.  calls in tail position take the place of the function that makes them and do not add to the depth
+	MaxDepth int
+
+	// MaxSteps is how many AST nodes may be evaluated
+	MaxSteps int64
+
+	// MaxAllocation is roughly how many bytes may be allocated for values and function environments.  memory that has been allocated is never given back to the budget, even after it is garbage collected
+	MaxAllocation int64
+}
+
+// the errors returned when evaluation exceeds one of its limits.  like NULL, TRUE and FALSE there is only ever one of each, so they can be told apart by comparing against them directly
+var (
+	ErrDepthLimit      = &object.Error{Message: "call depth limit exceeded"}
+	ErrStepLimit       = &object.Error{Message: "step limit exceeded"}
+	ErrAllocationLimit = &object.Error{Message: "allocation limit exceeded"}
+)
+
+// approximate sizes, in bytes, of the values the evaluator allocates.  these only need to be close enough to stop a program that allocates without bound
+const (
+	objectSize           = 16
+	pointerSize          = 8
+	environmentEntrySize = 32
+	environmentBaseSize  = 64
+)
+
+// budget holds the resources used so far.  an evaluator shares its budget with the tasks it spawns, so they are updated atomically
+type budget struct {
+	steps     atomic.Int64
+	allocated atomic.Int64
+}
+
+// step counts the evaluation of one node against Limits.MaxSteps
+func (e *Evaluator) step() *object.Error {
+	steps := e.budget.steps.Add(1)
+
+	if e.Limits.MaxSteps > 0 && steps > e.Limits.MaxSteps {
+		return ErrStepLimit
+	}
+
+	return nil
+}
+
+// allocate counts size bytes against Limits.MaxAllocation
+func (e *Evaluator) allocate(size int64) *object.Error {
+	allocated := e.budget.allocated.Add(size)
+
+	if e.Limits.MaxAllocation > 0 && allocated > e.Limits.MaxAllocation {
+		return ErrAllocationLimit
+	}
+
+	return nil
+}
+
+// track counts the size of a newly created value against Limits.MaxAllocation and returns it, or ErrAllocationLimit if it does not fit
+func (e *Evaluator) track(obj object.Object) object.Object {
+	if err := e.allocate(sizeOf(obj)); err != nil {
+		return err
+	}
+
+	return obj
+}
+
+// sizeOf approximates how many bytes were allocated to create obj.  the NULL, TRUE and FALSE singletons and errors are free
+func sizeOf(obj object.Object) int64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return objectSize
+	case *object.Function:
+		return objectSize + pointerSize*int64(len(obj.Parameters))
+	case *object.Quote:
+		return objectSize
+	case *object.String:
+		return objectSize + int64(len(obj.Value))
+	case *object.Array:
+		return objectSize + pointerSize*int64(len(obj.Elements))
+	case *object.Hash:
+		return objectSize + environmentEntrySize*int64(len(obj.Pairs))
+	case *object.Task:
+		return objectSize
+	case *object.Channel:
+		return objectSize + pointerSize*int64(cap(obj.Ch))
+	default:
+		return 0
+	}
+}
+
+// environmentSize approximates how many bytes are allocated for a function environment that holds entries names
+func environmentSize(entries int) int64 {
+	return environmentBaseSize + environmentEntrySize*int64(entries)
+}
@@ -0,0 +1,96 @@
+package evaluator
+
+import (
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/object"
+	"akdjr/monkey/parser"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input    string
+		limits   Limits
+		expected object.Object
+	}{
+		{
+			`let f = fn(n) { 1 + f(n + 1) }; f(0)`,
+			Limits{MaxDepth: 100},
+			ErrDepthLimit,
+		},
+		{
+			// tail calls do not add to the depth
+			`let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(1000)`,
+			Limits{MaxDepth: 10},
+			&object.Integer{Value: 0},
+		},
+		{
+			`let f = fn(n) { f(n + 1) }; f(0)`,
+			Limits{MaxSteps: 10000},
+			ErrStepLimit,
+		},
+		{
+			"1 + 2 + 3",
+			Limits{MaxSteps: 3},
+			ErrStepLimit,
+		},
+		{
+			"1 + 2 + 3",
+			Limits{MaxSteps: 7},
+			&object.Integer{Value: 6},
+		},
+		{
+			`let f = fn(n) { f(n + 1) }; f(0)`,
+			Limits{MaxAllocation: 1 << 20},
+			ErrAllocationLimit,
+		},
+		{
+			`let f = fn(x) { x * 2 }; f(21)`,
+			Limits{MaxDepth: 10, MaxSteps: 100, MaxAllocation: 1024},
+			&object.Integer{Value: 42},
+		},
+	}
+
+	for _, tt := range tests {
+		e := New()
+		e.Limits = tt.limits
+
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		evaluated := e.Eval(program, object.NewEnvironment())
+
+		switch expected := tt.expected.(type) {
+		case *object.Integer:
+			testIntegerObject(t, evaluated, expected.Value)
+		default:
+			if evaluated != expected {
+				t.Errorf("wrong result for %q. expected=%s, got=%s", tt.input, expected.Inspect(), typeOf(evaluated))
+			}
+		}
+	}
+}
+
+func TestLimitsInMacros(t *testing.T) {
+	input := `
+let forever = macro() { let f = fn(n) { f(n + 1) }; f(0) };
+forever();
+`
+
+	env := object.NewEnvironment()
+	program := testParseProgram(input)
+	DefineMacros(program, env)
+
+	e := New()
+	e.Limits = Limits{MaxSteps: 1000}
+
+	_, err := e.ExpandMacros(program, env)
+	if err == nil {
+		t.Fatalf("expected an error expanding a macro that never finishes")
+	}
+
+	expected := "error expanding macro forever: step limit exceeded"
+	if err.Error() != expected {
+		t.Errorf("wrong error. expected=%q, got=%q", expected, err.Error())
+	}
+}
@@ -0,0 +1,177 @@
+package evaluator
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/object"
+	"fmt"
+)
+
+// MaxMacroExpansionDepth is how deeply macro expansions may nest.  the code returned by a macro is expanded again, so a macro that (directly or indirectly) expands to a call of itself would otherwise never finish
+const MaxMacroExpansionDepth = 100
+
+// MaxMacroExpansions is how many macro calls may be expanded in one call to ExpandMacros.  the depth alone does not bound the work, a macro that expands to two calls of itself doubles it at every level
+const MaxMacroExpansions = 10000
+
+// DefineMacros finds all top-level let statements that bind a macro literal, defines the macros in env and removes those statements from the program
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	definitions := []int{}
+
+	for i, statement := range program.Statements {
+		if isMacroDefinition(statement) {
+			addMacro(statement, env)
+			definitions = append(definitions, i)
+		}
+	}
+
+	// remove from the back so the remaining indexes stay valid
+	for i := len(definitions) - 1; i >= 0; i-- {
+		definitionIndex := definitions[i]
+		program.Statements = append(program.Statements[:definitionIndex], program.Statements[definitionIndex+1:]...)
+	}
+}
+
+func isMacroDefinition(node ast.Statement) bool {
+	letStatement, ok := node.(*ast.LetStatement)
+	if !ok {
+		return false
+	}
+
+	_, ok = letStatement.Value.(*ast.MacroLiteral)
+	return ok
+}
+
+func addMacro(stmt ast.Statement, env *object.Environment) {
+	letStatement, _ := stmt.(*ast.LetStatement)
+	macroLiteral, _ := letStatement.Value.(*ast.MacroLiteral)
+
+	macro := &object.Macro{
+		Parameters: macroLiteral.Parameters,
+		Body:       macroLiteral.Body,
+		Env:        env,
+	}
+
+	env.Set(letStatement.Name.Value, macro)
+}
+
+// ExpandMacros replaces every call to a macro defined in env with the code the macro returns.  The macro arguments are passed to the macro unevaluated, as quoted code, and the macro must return quoted code
+// the returned code is expanded again, up to MaxMacroExpansionDepth levels deep and MaxMacroExpansions expansions in all
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, error) {
+	return New().ExpandMacros(program, env)
+}
+
+// ExpandMacros expands macros the same as the package level ExpandMacros, evaluating the macro bodies within e.Limits
+func (e *Evaluator) ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, error) {
+	expansions := 0
+	return e.expandMacros(program, env, 0, &expansions)
+}
+
+// expandMacros expands the macro calls in program, depth expansions deep.  expansions counts the macro calls expanded so far
+func (e *Evaluator) expandMacros(program ast.Node, env *object.Environment, depth int, expansions *int) (ast.Node, error) {
+	var err error
+
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		if err != nil {
+			return node
+		}
+
+		callExpression, ok := node.(*ast.CallExpression)
+		if !ok {
+			return node
+		}
+
+		macro, name, ok := isMacroCall(callExpression, env)
+		if !ok {
+			return node
+		}
+
+		if depth >= MaxMacroExpansionDepth {
+			err = fmt.Errorf("macro expansion of %s exceeded the maximum depth of %d", name, MaxMacroExpansionDepth)
+			return node
+		}
+
+		*expansions++
+		if *expansions > MaxMacroExpansions {
+			err = fmt.Errorf("macro expansion of %s exceeded the maximum of %d expansions", name, MaxMacroExpansions)
+			return node
+		}
+
+		if len(callExpression.Arguments) != len(macro.Parameters) {
+			err = fmt.Errorf("wrong number of arguments to macro %s: want=%d, got=%d", name, len(macro.Parameters), len(callExpression.Arguments))
+			return node
+		}
+
+		args := quoteArgs(callExpression)
+		evalEnv := extendMacroEnv(macro, args)
+
+		evaluated := unwrapReturnValue(e.Eval(macro.Body, evalEnv))
+		if isError(evaluated) {
+			err = fmt.Errorf("error expanding macro %s: %s", name, evaluated.(*object.Error).Message)
+			return node
+		}
+
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			err = fmt.Errorf("macro %s must return quoted code, got %s", name, typeOf(evaluated))
+			return node
+		}
+
+		var result ast.Node
+		result, err = e.expandMacros(quote.Node, env, depth+1, expansions)
+		return result
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return expanded, nil
+}
+
+// isMacroCall checks if the call expression calls a macro defined in env
+func isMacroCall(exp *ast.CallExpression, env *object.Environment) (*object.Macro, string, bool) {
+	identifier, ok := exp.Function.(*ast.Identifier)
+	if !ok {
+		return nil, "", false
+	}
+
+	obj, ok := env.Get(identifier.Value)
+	if !ok {
+		return nil, "", false
+	}
+
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		return nil, "", false
+	}
+
+	return macro, identifier.Value, true
+}
+
+func quoteArgs(exp *ast.CallExpression) []*object.Quote {
+	args := []*object.Quote{}
+
+	for _, a := range exp.Arguments {
+		args = append(args, &object.Quote{Node: a})
+	}
+
+	return args
+}
+
+func extendMacroEnv(macro *object.Macro, args []*object.Quote) *object.Environment {
+	extended := object.NewEnclosedEnvironment(macro.Env)
+
+	for paramIdx, param := range macro.Parameters {
+		extended.Set(param.Value, args[paramIdx])
+	}
+
+	return extended
+}
+
+// typeOf returns the type of obj for error messages, a missing result has no type
+func typeOf(obj object.Object) string {
+	if obj == nil {
+		return "nothing"
+	}
+
+	return string(obj.Type())
+}
@@ -0,0 +1,225 @@
+package evaluator
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/object"
+	"akdjr/monkey/parser"
+	"testing"
+)
+
+func TestDefineMacros(t *testing.T) {
+	input := `
+	let number = 1;
+	let function = fn(x, y) { x + y };
+	let mymacro = macro(x, y) { x + y; };
+	`
+
+	env := object.NewEnvironment()
+	program := testParseProgram(input)
+
+	DefineMacros(program, env)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("Wrong number of statements. got=%d", len(program.Statements))
+	}
+
+	_, ok := env.Get("number")
+	if ok {
+		t.Fatalf("number should not be defined")
+	}
+	_, ok = env.Get("function")
+	if ok {
+		t.Fatalf("function should not be defined")
+	}
+
+	obj, ok := env.Get("mymacro")
+	if !ok {
+		t.Fatalf("macro not in environment.")
+	}
+
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		t.Fatalf("object is not Macro. got=%T (%+v)", obj, obj)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("Wrong number of macro parameters. got=%d", len(macro.Parameters))
+	}
+
+	if macro.Parameters[0].String() != "x" {
+		t.Fatalf("parameter is not 'x'. got=%q", macro.Parameters[0])
+	}
+	if macro.Parameters[1].String() != "y" {
+		t.Fatalf("parameter is not 'y'. got=%q", macro.Parameters[1])
+	}
+
+	expectedBody := "(x + y)"
+
+	if macro.Body.String() != expectedBody {
+		t.Fatalf("body is not %q. got=%q", expectedBody, macro.Body.String())
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`
+			let infixExpression = macro() { quote(1 + 2); };
+
+			infixExpression();
+			`,
+			`(1 + 2)`,
+		},
+		{
+			`
+			let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+
+			reverse(2 + 2, 10 - 5);
+			`,
+			`(10 - 5) - (2 + 2)`,
+		},
+		{
+			`
+			let unless = macro(condition, consequence, alternative) {
+				quote(if (!(unquote(condition))) {
+					unquote(consequence);
+				} else {
+					unquote(alternative);
+				});
+			};
+
+			unless(10 > 5, a, b);
+			unless(1 > 2, c, d);
+			`,
+			`if (!(10 > 5)) { a } else { b }`,
+		},
+		{
+			// the code a macro returns is expanded again
+			`
+			let double = macro(x) { quote(unquote(x) * 2); };
+			let quadruple = macro(x) { quote(double(double(unquote(x)))); };
+
+			quadruple(y);
+			`,
+			`((y * 2) * 2)`,
+		},
+	}
+
+	for _, tt := range tests {
+		expected := testParseProgram(tt.expected)
+		program := testParseProgram(tt.input)
+
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+
+		expanded, err := ExpandMacros(program, env)
+		if err != nil {
+			t.Fatalf("ExpandMacros returned an error: %s", err)
+		}
+
+		// only compare the first statement, the rest is covered by the number of statements
+		actual := expanded.(*ast.Program).Statements[0].String()
+		if actual != expected.Statements[0].String() {
+			t.Errorf("not equal. want=%q, got=%q", expected.Statements[0].String(), actual)
+		}
+	}
+}
+
+func TestExpandMacrosRepeatedly(t *testing.T) {
+	input := `
+	let unless = macro(condition, consequence) { quote(if (!(unquote(condition))) { unquote(consequence) }) };
+	unless(a, b);
+	unless(c, d);
+	`
+
+	program := testParseProgram(input)
+	env := object.NewEnvironment()
+	DefineMacros(program, env)
+
+	expanded, err := ExpandMacros(program, env)
+	if err != nil {
+		t.Fatalf("ExpandMacros returned an error: %s", err)
+	}
+
+	expected := "if (!a) bif (!c) d"
+	if expanded.String() != expected {
+		t.Errorf("not equal. want=%q, got=%q", expected, expanded.String())
+	}
+}
+
+func TestExpandMacrosErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`let forever = macro(x) { quote(forever(unquote(x))) }; forever(1);`,
+			"macro expansion of forever exceeded the maximum depth of 100",
+		},
+		{
+			// every level expands to four calls of the next, far from the depth limit but 4^7 expansions at the last level alone
+			`let a = macro() { quote(b() + b() + b() + b()) };
+let b = macro() { quote(c() + c() + c() + c()) };
+let c = macro() { quote(d() + d() + d() + d()) };
+let d = macro() { quote(e() + e() + e() + e()) };
+let e = macro() { quote(f() + f() + f() + f()) };
+let f = macro() { quote(g() + g() + g() + g()) };
+let g = macro() { quote(h() + h() + h() + h()) };
+let h = macro() { quote(1) };
+a();`,
+			"macro expansion of h exceeded the maximum of 10000 expansions",
+		},
+		{
+			`let notQuoted = macro() { 5 }; notQuoted();`,
+			"macro notQuoted must return quoted code, got INTEGER",
+		},
+		{
+			`let two = macro(a, b) { quote(unquote(a) + unquote(b)) }; two(1);`,
+			"wrong number of arguments to macro two: want=2, got=1",
+		},
+		{
+			`let broken = macro() { missing }; broken();`,
+			"error expanding macro broken: identifier not found: missing",
+		},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+
+		_, err := ExpandMacros(program, env)
+		if err == nil {
+			t.Errorf("expected an error expanding %q", tt.input)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestMacroLiteralOutsideDefinition(t *testing.T) {
+	evaluated := testEval("let f = fn() { macro(x) { x } }; f()")
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := "macro literals can only be defined with a top-level let statement"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+}
+
+func testParseProgram(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
@@ -0,0 +1,80 @@
+package evaluator
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/object"
+)
+
+// evalSpawnExpression starts a task that calls a function on a goroutine of its own.  when the spawned expression is a call, its function and arguments are evaluated before the task starts, so the task does not see later changes to them
+func (e *Evaluator) evalSpawnExpression(node *ast.SpawnExpression, env *object.Environment) object.Object {
+	var function object.Object
+	var args []object.Object
+
+	if call, ok := node.Function.(*ast.CallExpression); ok && !isQuoteCall(call) {
+		function = e.Eval(call.Function, env)
+		if isError(function) {
+			return function
+		}
+
+		args = e.evalExpressions(call.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+	} else {
+		function = e.Eval(node.Function, env)
+		if isError(function) {
+			return function
+		}
+	}
+
+	switch function.(type) {
+	case *object.Function, *object.Builtin, *builtin:
+	default:
+		return newError("cannot spawn %s, it is not a function", typeOf(function))
+	}
+
+	task := object.NewTask()
+	child := e.fork()
+
+	go func() {
+		// a panic in a task would take down the whole program, not only the task.  it finishes the task with an error instead
+		defer func() {
+			if r := recover(); r != nil {
+				task.Finish(newError("internal error: %v", r))
+			}
+		}()
+
+		result := child.applyFunction(function, args)
+		if result == nil {
+			result = NULL
+		}
+
+		task.Finish(result)
+	}()
+
+	return task
+}
+
+// fork creates the evaluator for a spawned task.  the task is evaluated within the same limits and context as e, or within e.TaskContext when it is set, and the steps and allocations it makes count towards the same budget
+func (e *Evaluator) fork() *Evaluator {
+	ctx := e.ctx
+	if e.TaskContext != nil {
+		ctx = e.TaskContext
+	}
+
+	return &Evaluator{
+		Limits:      e.Limits,
+		TaskContext: e.TaskContext,
+		budget:      e.budget,
+		ctx:         ctx,
+	}
+}
+
+// done returns the channel that is closed when the context of the current evaluation is done, or nil when there is no context
+func (e *Evaluator) done() <-chan struct{} {
+	if e.ctx == nil {
+		return nil
+	}
+
+	return e.ctx.Done()
+}
@@ -0,0 +1,158 @@
+package evaluator
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/object"
+	"akdjr/monkey/parser"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSpawn(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"wait(spawn fn() { 42 })", 42},
+		{"let add = fn(a, b) { a + b }; wait(spawn add(20, 22))", 42},
+		{
+			`
+let results = channel(3);
+let worker = fn(n) { send(results, n * n) };
+wait(spawn worker(1), spawn worker(2), spawn worker(3));
+recv(results) + recv(results) + recv(results)`,
+			14,
+		},
+		{
+			// the arguments of a spawned call are evaluated before the task starts
+			`
+let c = channel();
+let n = 5;
+let t = spawn fn(x) { recv(c) + x }(n);
+let n = 100;
+send(c, 1);
+wait(t)`,
+			6,
+		},
+		{
+			`
+let jobs = channel();
+let results = channel(3);
+let worker = fn() { let job = recv(jobs); if (job) { send(results, job * 2); worker() } };
+let w1 = spawn worker();
+let w2 = spawn worker();
+send(jobs, 1); send(jobs, 2); send(jobs, 3);
+let sum = recv(results) + recv(results) + recv(results);
+close(jobs);
+sum`,
+			12,
+		},
+		{"let c = channel(1); send(c, 5); close(c); let a = recv(c); let b = recv(c); if (b) { 0 } else { a }", 5},
+		{"let a = channel(); let b = channel(1); send(b, 7); select(a, fn(v) { 1 }, b, fn(v) { v * 2 })", 14},
+		{"select(channel(), fn(v) { 1 }, fn() { 2 })", 2},
+		{"let c = channel(); close(c); select(c, fn(v) { if (v) { 1 } else { 2 } })", 2},
+		{"let t = spawn fn() { 1 + true }; wait(t); 5", "type mismatch: INTEGER + BOOLEAN"},
+		{"wait(spawn fn() { 1 }, spawn fn() { -true })", "unknown operator: -BOOLEAN"},
+		{"wait(spawn fn(x) { x })", "wrong number of arguments: want=1, got=0"},
+		{"spawn 5", "cannot spawn INTEGER, it is not a function"},
+		{"wait(5)", "argument to `wait` must be TASK, got INTEGER"},
+		{"send(1, 2)", "first argument to `send` must be CHANNEL, got INTEGER"},
+		{"recv(true)", "argument to `recv` must be CHANNEL, got BOOLEAN"},
+		{"channel(-1)", "argument to `channel` must not be negative, got -1"},
+		{"let c = channel(); close(c); close(c)", "close of closed channel"},
+		{"let c = channel(1); close(c); send(c, 1)", "send on closed channel"},
+		{"select(1, fn(v) { v })", "argument 1 to `select` must be CHANNEL, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := Eval(parseChecked(t, tt.input), object.NewEnvironment())
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			err, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if err.Message != expected {
+				t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, err.Message)
+			}
+		}
+	}
+}
+
+func TestSpawnLimits(t *testing.T) {
+	// tasks use the same budget as the evaluation that spawned them
+	e := New()
+	e.Limits = Limits{MaxSteps: 10000}
+
+	evaluated := e.Eval(parseChecked(t, "let f = fn(n) { f(n + 1) }; wait(spawn f(0), spawn f(0))"), object.NewEnvironment())
+	if evaluated != ErrStepLimit {
+		t.Errorf("expected ErrStepLimit. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	// blocking builtins and spawned tasks stop when the evaluation is canceled
+	inputs := []string{
+		"recv(channel())",
+		"send(channel(), 1)",
+		"select(channel(), fn(v) { v })",
+		"wait(spawn fn() { recv(channel()) })",
+		"let f = fn() { f() }; wait(spawn f())",
+	}
+
+	for _, input := range inputs {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+
+		evaluated := EvalContext(ctx, parseChecked(t, input), object.NewEnvironment())
+		if evaluated != ErrDeadlineExceeded {
+			t.Errorf("expected ErrDeadlineExceeded for %q. got=%T (%+v)", input, evaluated, evaluated)
+		}
+
+		cancel()
+	}
+}
+
+func TestTaskContext(t *testing.T) {
+	e := New()
+	e.TaskContext = context.Background()
+	env := object.NewEnvironment()
+
+	// the context of the evaluation that spawned the task is done before the task is waited for
+	ctx, cancel := context.WithCancel(context.Background())
+	e.EvalContext(ctx, parseChecked(t, "let c = channel(1); let t = spawn fn() { recv(c) * 2 };"), env)
+	cancel()
+
+	evaluated := e.EvalContext(context.Background(), parseChecked(t, "send(c, 21); wait(t)"), env)
+	testIntegerObject(t, evaluated, 42)
+
+	// without a task context, the task stops with the evaluation that spawned it
+	e.TaskContext = nil
+
+	ctx, cancel = context.WithCancel(context.Background())
+	e.EvalContext(ctx, parseChecked(t, "let c = channel(1); let t = spawn fn() { recv(c) * 2 };"), env)
+	cancel()
+
+	evaluated = e.EvalContext(context.Background(), parseChecked(t, "wait(t)"), env)
+	if evaluated != ErrCanceled {
+		t.Errorf("expected ErrCanceled. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+// parseChecked parses input and fails the test when it does not parse, so a program with a mistake in it can not pass by evaluating to the expected error by accident
+func parseChecked(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if errors := p.Errors(); len(errors) > 0 {
+		t.Fatalf("parser errors for %q: %v", input, errors)
+	}
+
+	return program
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"akdjr/monkey/diff"
+	"akdjr/monkey/format"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// fmtCommand implements "monkey fmt [-w] [-d] [files...]" and returns the process exit code
+// with no files, the program on stdin is formatted to stdout
+func fmtCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write the result to the source file instead of stdout")
+	showDiff := flags.Bool("d", false, "display a diff instead of the formatted source")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey fmt [-w] [-d] [files...]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "monkey fmt: cannot use -w with standard input")
+			return 2
+		}
+
+		if err := formatFile("<standard input>", os.Stdin, os.Stdout, false, *showDiff); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	exitCode := 0
+	for _, path := range flags.Args() {
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 1
+			continue
+		}
+
+		err = formatFile(path, file, os.Stdout, *write, *showDiff)
+		file.Close()
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 1
+		}
+	}
+
+	return exitCode
+}
+
+// formatFile formats the source read from in.  the result is written back to path when write is set, shown as a diff when showDiff is set, and printed to out otherwise
+func formatFile(path string, in io.Reader, out io.Writer, write bool, showDiff bool) error {
+	src, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	formatted, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("%s:\n%s", path, err)
+	}
+
+	if showDiff {
+		io.WriteString(out, diff.Unified(path+".orig", path, string(src), string(formatted)))
+	}
+
+	if write {
+		if bytes.Equal(src, formatted) {
+			return nil
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		return os.WriteFile(path, formatted, info.Mode().Perm())
+	}
+
+	if !showDiff {
+		_, err = out.Write(formatted)
+	}
+
+	return err
+}
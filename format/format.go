@@ -0,0 +1,480 @@
+package format
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/parser"
+	"akdjr/monkey/token"
+	"bytes"
+	"errors"
+	"strings"
+)
+
+// indent is the string used for each level of indentation
+const indent = "    "
+
+// Source parses src and returns it in canonical format.  Comments are preserved.  If src does not parse, the parser errors are returned joined in a single error
+func Source(src []byte) ([]byte, error) {
+	l := lexer.New(string(src))
+	l.KeepComments(true)
+
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if errs := p.Errors(); len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+
+	return []byte(Program(program)), nil
+}
+
+// Program returns the canonical source of program.  Comments in program.Comments are placed before the statement they precede, or after the statement that ends on their line
+func Program(program *ast.Program) string {
+	p := &printer{comments: program.Comments}
+
+	p.statements(program.Statements)
+
+	// anything left over comes after the last statement
+	p.commentsBefore(maxLine, 0)
+
+	if p.out.Len() > 0 {
+		p.out.WriteString("\n")
+	}
+
+	return p.out.String()
+}
+
+// Node returns the canonical source of a single node without any comments.  Statements are not terminated by a newline
+func Node(node ast.Node) string {
+	p := &printer{}
+
+	switch node := node.(type) {
+	case *ast.Program:
+		return Program(node)
+	case ast.Statement:
+		p.statement(node, nil)
+	case ast.Expression:
+		p.expression(node, parser.LOWEST)
+	}
+
+	return p.out.String()
+}
+
+// maxLine is a line number that is after every line in the source
+const maxLine = int(^uint(0) >> 1)
+
+// printer writes out an AST as formatted source
+// comments are kept in source order and printed as soon as the printer reaches their position
+type printer struct {
+	out      bytes.Buffer
+	depth    int
+	comments []token.Comment
+
+	// lastLine is the source line of the last statement or comment printed in the current block, or 0 at the start of a block
+	// it is used to keep a single blank line wherever the source had one or more
+	lastLine int
+
+	// closing is the token that closes the block or argument list being printed, the } or ), or the zero token at the top level.  comments after it belong to the statement around the block, not to the last one in it
+	closing token.Token
+}
+
+// newline ends the current line and indents the next one
+func (p *printer) newline() {
+	p.out.WriteString("\n")
+	p.out.WriteString(strings.Repeat(indent, p.depth))
+}
+
+// separate starts a new line for something that begins on line in the source, keeping a blank line if the source had one
+func (p *printer) separate(line int) {
+	// the very first line of the output is not preceded by a newline
+	if p.out.Len() == 0 {
+		p.out.WriteString(strings.Repeat(indent, p.depth))
+		return
+	}
+
+	if p.lastLine > 0 && line > p.lastLine+1 {
+		p.out.WriteString("\n")
+	}
+
+	p.newline()
+}
+
+// commentsBefore prints, each on their own line, all remaining comments that start before line and column
+func (p *printer) commentsBefore(line int, column int) {
+	for len(p.comments) > 0 {
+		c := p.comments[0]
+		if c.Line > line || (c.Line == line && c.Column >= column) {
+			return
+		}
+
+		p.comments = p.comments[1:]
+
+		p.separate(c.Line)
+		p.out.WriteString(c.Text)
+		p.lastLine = c.Line + strings.Count(c.Text, "\n")
+	}
+}
+
+// trailingComments prints all remaining comments that start on line, before the token that closes what is being printed, at the end of the current line
+func (p *printer) trailingComments(line int) {
+	for len(p.comments) > 0 && p.comments[0].Line == line && (p.closing.Line == 0 || p.hasCommentsBefore(p.closing)) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		p.out.WriteString(" ")
+		p.out.WriteString(c.Text)
+		p.lastLine = c.Line + strings.Count(c.Text, "\n")
+	}
+}
+
+// statements prints a list of statements, each on its own line along with their comments
+func (p *printer) statements(stmts []ast.Statement) {
+	for i, stmt := range stmts {
+		tok := startToken(stmt)
+		p.commentsBefore(tok.Line, tok.Column)
+
+		p.separate(tok.Line)
+
+		var next ast.Statement
+		if i+1 < len(stmts) {
+			next = stmts[i+1]
+		}
+
+		p.statement(stmt, next)
+
+		end := endLine(stmt)
+		p.lastLine = end
+		p.trailingComments(end)
+	}
+}
+
+// statement prints a single statement.  next is the statement that follows it, if any, which decides whether an if expression needs a semicolon
+func (p *printer) statement(stmt ast.Statement, next ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		p.out.WriteString("let ")
+		p.out.WriteString(stmt.Name.Value)
+		p.out.WriteString(" = ")
+		p.expression(stmt.Value, parser.LOWEST)
+		p.out.WriteString(";")
+	case *ast.ReturnStatement:
+		p.out.WriteString("return ")
+		p.expression(stmt.ReturnValue, parser.LOWEST)
+		p.out.WriteString(";")
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expression, parser.LOWEST)
+
+		if needsSemicolon(stmt, next) {
+			p.out.WriteString(";")
+		}
+	case *ast.BlockStatement:
+		p.block(stmt)
+	}
+}
+
+// needsSemicolon reports whether an expression statement must be terminated with a semicolon
+// an if expression reads best without one, but if the next statement starts with something that can continue an expression (such as '-' or '(') the semicolon is required to keep the two apart
+func needsSemicolon(stmt *ast.ExpressionStatement, next ast.Statement) bool {
+	if _, ok := stmt.Expression.(*ast.IfExpression); !ok {
+		return true
+	}
+
+	if next == nil {
+		return false
+	}
+
+	return parser.Precedence(startToken(next).Type) > parser.LOWEST
+}
+
+// block prints a block statement with its contents indented
+func (p *printer) block(block *ast.BlockStatement) {
+	if len(block.Statements) == 0 && !p.hasCommentsBefore(block.Rbrace) {
+		p.out.WriteString("{}")
+		return
+	}
+
+	p.out.WriteString("{")
+	p.depth++
+
+	outerLastLine, outerClosing := p.lastLine, p.closing
+	p.lastLine, p.closing = 0, block.Rbrace
+
+	p.statements(block.Statements)
+	p.commentsBefore(block.Rbrace.Line, block.Rbrace.Column)
+
+	p.depth--
+	p.newline()
+	p.out.WriteString("}")
+
+	p.lastLine, p.closing = outerLastLine, outerClosing
+}
+
+// hasCommentsBefore reports whether there are comments left that start before tok
+func (p *printer) hasCommentsBefore(tok token.Token) bool {
+	if len(p.comments) == 0 {
+		return false
+	}
+
+	c := p.comments[0]
+	return c.Line < tok.Line || (c.Line == tok.Line && c.Column < tok.Column)
+}
+
+// expression prints an expression that appears in a context with the given precedence.  the expression is wrapped in parentheses only if it binds more loosely than its context
+func (p *printer) expression(exp ast.Expression, precedence int) {
+	needsParens := precedenceOf(exp) < precedence
+	if needsParens {
+		p.out.WriteString("(")
+	}
+
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		p.out.WriteString(exp.Value)
+	case *ast.IntegerLiteral:
+		p.out.WriteString(exp.Token.Literal)
+	case *ast.Boolean:
+		p.out.WriteString(exp.Token.Literal)
+	case *ast.PrefixExpression:
+		p.out.WriteString(exp.Operator)
+		p.expression(exp.Right, parser.PREFIX)
+	case *ast.InfixExpression:
+		// operators are left associative, so a right operand of the same precedence needs parentheses
+		operator := parser.Precedence(exp.Token.Type)
+		p.expression(exp.Left, operator)
+		p.out.WriteString(" " + exp.Operator + " ")
+		p.expression(exp.Right, operator+1)
+	case *ast.IfExpression:
+		p.out.WriteString("if (")
+		p.expression(exp.Condition, parser.LOWEST)
+		p.out.WriteString(") ")
+		p.block(exp.Consequence)
+
+		if exp.Alternative != nil {
+			p.out.WriteString(" else ")
+			p.block(exp.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		p.out.WriteString("fn(")
+		for i, param := range exp.Parameters {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			p.out.WriteString(param.Value)
+		}
+		p.out.WriteString(") ")
+		p.block(exp.Body)
+	case *ast.MacroLiteral:
+		p.out.WriteString("macro(")
+		for i, param := range exp.Parameters {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			p.out.WriteString(param.Value)
+		}
+		p.out.WriteString(") ")
+		p.block(exp.Body)
+	case *ast.SpawnExpression:
+		p.out.WriteString("spawn ")
+		p.expression(exp.Function, parser.PREFIX)
+	case *ast.CallExpression:
+		p.expression(exp.Function, parser.CALL)
+		p.arguments(exp)
+	}
+
+	if needsParens {
+		p.out.WriteString(")")
+	}
+}
+
+// arguments prints the argument list of a call.  the arguments go on one line, unless there are comments between them, in which case every argument goes on a line of its own so the comments stay next to the argument they were written by
+func (p *printer) arguments(call *ast.CallExpression) {
+	if !p.hasCommentsBetweenArguments(call) {
+		p.out.WriteString("(")
+		for i, arg := range call.Arguments {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			p.expression(arg, parser.LOWEST)
+		}
+		p.out.WriteString(")")
+		return
+	}
+
+	p.out.WriteString("(")
+	p.depth++
+
+	outerLastLine, outerClosing := p.lastLine, p.closing
+	p.lastLine, p.closing = 0, call.Rparen
+
+	for i, arg := range call.Arguments {
+		start := startOf(arg)
+		p.commentsBefore(start.Line, start.Column)
+
+		p.separate(start.Line)
+		p.expression(arg, parser.LOWEST)
+		if i < len(call.Arguments)-1 {
+			p.out.WriteString(",")
+		}
+
+		end := endLine(arg)
+		p.lastLine = end
+		p.trailingComments(end)
+	}
+
+	p.commentsBefore(call.Rparen.Line, call.Rparen.Column)
+
+	p.depth--
+	p.newline()
+	p.out.WriteString(")")
+
+	p.lastLine, p.closing = outerLastLine, outerClosing
+}
+
+// hasCommentsBetweenArguments reports whether there are comments left inside the parentheses of call, other than the ones in the blocks of its arguments, which the blocks print themselves
+func (p *printer) hasCommentsBetweenArguments(call *ast.CallExpression) bool {
+	blocks := [][2]token.Token{}
+	for _, arg := range call.Arguments {
+		ast.Inspect(arg, func(node ast.Node) bool {
+			if block, ok := node.(*ast.BlockStatement); ok {
+				blocks = append(blocks, [2]token.Token{block.Token, block.Rbrace})
+			}
+			return true
+		})
+	}
+
+comments:
+	for _, c := range p.comments {
+		if !after(c, call.Token) {
+			continue
+		}
+
+		if after(c, call.Rparen) {
+			return false
+		}
+
+		for _, block := range blocks {
+			if after(c, block[0]) && !after(c, block[1]) {
+				continue comments
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// after reports whether comment c starts after tok
+func after(c token.Comment, tok token.Token) bool {
+	return c.Line > tok.Line || (c.Line == tok.Line && c.Column > tok.Column)
+}
+
+// precedenceOf returns how tightly an expression binds.  literals, identifiers and expressions that are closed off by braces never need parentheses
+func precedenceOf(exp ast.Expression) int {
+	switch exp := exp.(type) {
+	case *ast.PrefixExpression, *ast.SpawnExpression:
+		return parser.PREFIX
+	case *ast.InfixExpression:
+		return parser.Precedence(exp.Token.Type)
+	case *ast.CallExpression:
+		return parser.CALL
+	default:
+		return parser.CALL + 1
+	}
+}
+
+// startToken returns the first token of a statement
+func startToken(stmt ast.Statement) token.Token {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token
+	case *ast.ReturnStatement:
+		return stmt.Token
+	case *ast.ExpressionStatement:
+		return stmt.Token
+	case *ast.BlockStatement:
+		return stmt.Token
+	}
+
+	return token.Token{}
+}
+
+// startOf returns the first token of an expression
+func startOf(exp ast.Expression) token.Token {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		return exp.Token
+	case *ast.IntegerLiteral:
+		return exp.Token
+	case *ast.Boolean:
+		return exp.Token
+	case *ast.PrefixExpression:
+		return exp.Token
+	case *ast.InfixExpression:
+		return startOf(exp.Left)
+	case *ast.IfExpression:
+		return exp.Token
+	case *ast.FunctionLiteral:
+		return exp.Token
+	case *ast.MacroLiteral:
+		return exp.Token
+	case *ast.SpawnExpression:
+		return exp.Token
+	case *ast.CallExpression:
+		return startOf(exp.Function)
+	}
+
+	return token.Token{}
+}
+
+// endLine returns the last source line that node spans
+func endLine(node ast.Node) int {
+	line := 0
+	see := func(l int) {
+		if l > line {
+			line = l
+		}
+	}
+
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		see(node.Token.Line)
+		see(endLine(node.Value))
+	case *ast.ReturnStatement:
+		see(node.Token.Line)
+		see(endLine(node.ReturnValue))
+	case *ast.ExpressionStatement:
+		see(node.Token.Line)
+		see(endLine(node.Expression))
+	case *ast.BlockStatement:
+		see(node.Rbrace.Line)
+	case *ast.Identifier:
+		see(node.Token.Line)
+	case *ast.IntegerLiteral:
+		see(node.Token.Line)
+	case *ast.Boolean:
+		see(node.Token.Line)
+	case *ast.PrefixExpression:
+		see(endLine(node.Right))
+	case *ast.SpawnExpression:
+		see(endLine(node.Function))
+	case *ast.InfixExpression:
+		see(endLine(node.Right))
+	case *ast.IfExpression:
+		see(endLine(node.Consequence))
+		if node.Alternative != nil {
+			see(endLine(node.Alternative))
+		}
+	case *ast.FunctionLiteral:
+		see(endLine(node.Body))
+	case *ast.MacroLiteral:
+		see(endLine(node.Body))
+	case *ast.CallExpression:
+		see(endLine(node.Function))
+		for _, arg := range node.Arguments {
+			see(endLine(arg))
+		}
+		see(node.Rparen.Line)
+	}
+
+	return line
+}
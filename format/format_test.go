@@ -0,0 +1,141 @@
+package format
+
+import (
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let x=5",
+			"let x = 5;\n",
+		},
+		{
+			"let add = fn(x,y){return x+y;}; let result=add(1,   2)",
+			"let add = fn(x, y) {\n    return x + y;\n};\nlet result = add(1, 2);\n",
+		},
+		{
+			"((a + b) * (c - d)) / -(-e)",
+			"(a + b) * (c - d) / --e;\n",
+		},
+		{
+			"a - (b - c); (a - b) - c; a * (b + c); !(a == b); (f + g)(x); -f(x)",
+			"a - (b - c);\na - b - c;\na * (b + c);\n!(a == b);\n(f + g)(x);\n-f(x);\n",
+		},
+		{
+			"if (x < 10) { x } else { if (x > 20) { 20 } else { 10 } }",
+			"if (x < 10) {\n    x;\n} else {\n    if (x > 20) {\n        20;\n    } else {\n        10;\n    }\n}\n",
+		},
+		{
+			// the second statement would continue the if expression without a semicolon
+			"if (a) { 1 }; -1; if (b) { 2 } let c = 3",
+			"if (a) {\n    1;\n};\n-1;\nif (b) {\n    2;\n}\nlet c = 3;\n",
+		},
+		{
+			"let m = macro(a,b){quote(unquote(a)+unquote(b))}",
+			"let m = macro(a, b) {\n    quote(unquote(a) + unquote(b));\n};\n",
+		},
+		{
+			"let t = spawn   worker(1,2); (spawn f)(1); -(spawn g)",
+			"let t = spawn worker(1, 2);\n(spawn f)(1);\n-spawn g;\n",
+		},
+		{
+			"let noop = fn() {}; noop()",
+			"let noop = fn() {};\nnoop();\n",
+		},
+		{
+			"let a = 1;\n\n\n\nlet b = 2;\nlet c = 3;",
+			"let a = 1;\n\nlet b = 2;\nlet c = 3;\n",
+		},
+		{
+			`// leading comment
+let x = 1; // trailing comment
+
+/* block
+   comment */
+let f = fn() {
+  // inside the body
+  x
+  // at the end of the body
+};
+let g = fn() { /* only a comment */ };
+// final comment`,
+			`// leading comment
+let x = 1; // trailing comment
+
+/* block
+   comment */
+let f = fn() {
+    // inside the body
+    x;
+    // at the end of the body
+};
+let g = fn() {
+    /* only a comment */
+};
+// final comment
+`,
+		},
+		{
+			// comments between the arguments of a call stay by the arguments
+			"let x = f(1, // one\n2);\ng( // first\na,\n\n/* b */ b // last\n) // after",
+			"let x = f(\n    1, // one\n    2\n);\ng(\n    // first\n    a,\n\n    /* b */\n    b // last\n); // after\n",
+		},
+		{
+			"f(fn() { // inside\n1 }, 2)",
+			"f(fn() {\n    // inside\n    1;\n}, 2);\n",
+		},
+		{
+			// a comment after the closing brace of a block belongs to the statement around the block
+			"let add = fn(a, b) { a + b }; // trailing",
+			"let add = fn(a, b) {\n    a + b;\n}; // trailing\n",
+		},
+		{
+			"let g = fn(x) { if (x) { return 1; } /* end */ };",
+			"let g = fn(x) {\n    if (x) {\n        return 1;\n    } /* end */\n};\n",
+		},
+		{
+			"f(1, // one\ng(2)) // after",
+			"f(\n    1, // one\n    g(2)\n); // after\n",
+		},
+		{
+			"",
+			"",
+		},
+	}
+
+	for i, tt := range tests {
+		actual, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error: %s", i, err)
+		}
+
+		if string(actual) != tt.expected {
+			t.Errorf("tests[%d] - formatted source wrong.\nexpected=%q\ngot=%q", i, tt.expected, string(actual))
+		}
+
+		again, err := Source(actual)
+		if err != nil {
+			t.Fatalf("tests[%d] - formatted source does not parse: %s", i, err)
+		}
+
+		if string(again) != string(actual) {
+			t.Errorf("tests[%d] - formatting is not idempotent.\nfirst=%q\nsecond=%q", i, string(actual), string(again))
+		}
+	}
+}
+
+func TestSourceParseError(t *testing.T) {
+	_, err := Source([]byte("let = 5;"))
+	if err == nil {
+		t.Fatalf("expected an error for invalid source")
+	}
+
+	expected := "expected next token to be IDENTIFIER, got ASSIGN instead\nno prefix parse function for 'ASSIGN' found"
+	if err.Error() != expected {
+		t.Errorf("wrong error. expected=%q, got=%q", expected, err.Error())
+	}
+}
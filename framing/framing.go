@@ -0,0 +1,99 @@
+package framing
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MaxContentLength is the largest message a Reader accepts.  a Content-Length above it is an error rather than an allocation of that size, so a broken or hostile client can not run the server out of memory
+const MaxContentLength = 32 << 20
+
+// Reader reads messages that are each preceded by a header with their Content-Length, the framing used by the Language Server Protocol and the Debug Adapter Protocol
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader creates a Reader that reads messages from r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read reads the next message and returns its content.  it returns io.EOF when the input ends between messages, and io.ErrUnexpectedEOF when it ends in the middle of one
+func (r *Reader) Read() ([]byte, error) {
+	length := -1
+
+	for first := true; ; first = false {
+		line, err := r.r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && (!first || line != "") {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("framing: malformed header %q", line)
+		}
+
+		// other headers, such as Content-Type, are allowed but not needed
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || length < 0 {
+				return nil, fmt.Errorf("framing: invalid Content-Length %q", strings.TrimSpace(value))
+			}
+		}
+	}
+
+	if length < 0 {
+		return nil, errors.New("framing: message has no Content-Length")
+	}
+
+	if length > MaxContentLength {
+		return nil, fmt.Errorf("framing: Content-Length %d is larger than the limit of %d", length, MaxContentLength)
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r.r, content); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return content, nil
+}
+
+// Writer writes messages preceded by a header with their Content-Length.  it can be used from many goroutines at once, each message is written out whole
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriter creates a Writer that writes messages to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes content as a single message
+func (w *Writer) Write(content []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := fmt.Fprintf(w.w, "Content-Length: %d\r\n\r\n", len(content)); err != nil {
+		return err
+	}
+
+	_, err := w.w.Write(content)
+	return err
+}
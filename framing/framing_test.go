@@ -0,0 +1,73 @@
+package framing
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	messages := []string{`{"id":1}`, "", `{"method":"exit","params":"héllo"}`}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, msg := range messages {
+		if err := w.Write([]byte(msg)); err != nil {
+			t.Fatalf("Write returned an error: %s", err)
+		}
+	}
+
+	r := NewReader(&buf)
+	for _, expected := range messages {
+		msg, err := r.Read()
+		if err != nil {
+			t.Fatalf("Read returned an error: %s", err)
+		}
+
+		if string(msg) != expected {
+			t.Errorf("wrong message. expected=%q, got=%q", expected, string(msg))
+		}
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("expected io.EOF after the last message. got=%v", err)
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		err      string
+	}{
+		{"Content-Length: 2\r\nContent-Type: application/json\r\n\r\n{}", "{}", ""},
+		{"content-length:2\n\n{}", "{}", ""},
+		{"Content-Type: application/json\r\n\r\n{}", "", "framing: message has no Content-Length"},
+		{"Content-Length: two\r\n\r\n{}", "", `framing: invalid Content-Length "two"`},
+		{"Content-Length 2\r\n\r\n{}", "", `framing: malformed header "Content-Length 2"`},
+		{"Content-Length: 1000000000000\r\n\r\n{}", "", "framing: Content-Length 1000000000000 is larger than the limit of 33554432"},
+		{"Content-Length: 10\r\n\r\n{}", "", "unexpected EOF"},
+		{"Content-Length: 2\r\n", "", "unexpected EOF"},
+	}
+
+	for _, tt := range tests {
+		msg, err := NewReader(strings.NewReader(tt.input)).Read()
+
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("wrong error for %q. expected=%q, got=%v", tt.input, tt.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Read returned an error for %q: %s", tt.input, err)
+			continue
+		}
+
+		if string(msg) != tt.expected {
+			t.Errorf("wrong message for %q. expected=%q, got=%q", tt.input, tt.expected, string(msg))
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package interpreter
+
+import (
+	"akdjr/monkey/evaluator"
+	"akdjr/monkey/object"
+	"fmt"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Register binds the Go function fn with Bind and defines it as the global name
+func (i *Interpreter) Register(name string, fn interface{}) error {
+	builtin, err := i.Bind(fn)
+	if err != nil {
+		return err
+	}
+
+	return i.Set(name, builtin)
+}
+
+// Bind turns an arbitrary Go function into a builtin that can be called from monkey code, such as func(int64, string) (bool, error)
+// the arguments of a call are converted to the parameter types of fn, the reverse of ToObject, and a call with the wrong number or types of arguments results in an error.  fn may return nothing, a value, an error, or a value and an error.  the value is converted with ToObject and an error that is not nil becomes a monkey error, as does a panic in fn
+func (i *Interpreter) Bind(fn interface{}) (*object.Builtin, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("interpreter: can not bind %T, it is not a function", fn)
+	}
+
+	return i.bind(v)
+}
+
+func (i *Interpreter) bind(fn reflect.Value) (*object.Builtin, error) {
+	t := fn.Type()
+
+	returnsError := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+	values := t.NumOut()
+	if returnsError {
+		values--
+	}
+
+	if values > 1 {
+		return nil, fmt.Errorf("interpreter: can not bind %s, it must return at most one value and an error", t)
+	}
+
+	builtin := func(args ...object.Object) (result object.Object) {
+		in, err := convertArguments(t, args)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				result = &object.Error{Message: fmt.Sprintf("panic: %v", r)}
+			}
+		}()
+
+		out := fn.Call(in)
+
+		if returnsError {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return &object.Error{Message: err.Error()}
+			}
+		}
+
+		if values == 0 {
+			return evaluator.NULL
+		}
+
+		obj, convertErr := i.toObject(out[0])
+		if convertErr != nil {
+			return &object.Error{Message: convertErr.Error()}
+		}
+
+		return obj
+	}
+
+	return &object.Builtin{Fn: builtin}, nil
+}
+
+// convertArguments converts the arguments of a call to the parameter types of the function type t
+func convertArguments(t reflect.Type, args []object.Object) ([]reflect.Value, *object.Error) {
+	required := t.NumIn()
+	if t.IsVariadic() {
+		required--
+
+		if len(args) < required {
+			return nil, &object.Error{Message: fmt.Sprintf("wrong number of arguments: want at least %d, got=%d", required, len(args))}
+		}
+	} else if len(args) != required {
+		return nil, &object.Error{Message: fmt.Sprintf("wrong number of arguments: want=%d, got=%d", required, len(args))}
+	}
+
+	in := make([]reflect.Value, 0, len(args))
+
+	for idx, arg := range args {
+		var paramType reflect.Type
+		if idx < required {
+			paramType = t.In(idx)
+		} else {
+			paramType = t.In(required).Elem()
+		}
+
+		value, err := fromObject(arg, paramType)
+		if err != nil {
+			return nil, &object.Error{Message: fmt.Sprintf("wrong type of argument %d: %s", idx+1, err)}
+		}
+
+		in = append(in, value)
+	}
+
+	return in, nil
+}
+
+// fromObject converts obj to a value of type t
+func fromObject(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	// object.Object parameters, or parameters of one of the object types, get obj as it is.  interface{} parameters get the Go value obj represents below
+	isEmptyInterface := t.Kind() == reflect.Interface && t.NumMethod() == 0
+	if !isEmptyInterface && reflect.TypeOf(obj).AssignableTo(t) {
+		return reflect.ValueOf(obj), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if b, ok := obj.(*object.Boolean); ok {
+			return reflect.ValueOf(b.Value).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if integer, ok := obj.(*object.Integer); ok {
+			value := reflect.New(t).Elem()
+			if value.OverflowInt(integer.Value) {
+				return value, fmt.Errorf("%d does not fit in %s", integer.Value, t)
+			}
+
+			value.SetInt(integer.Value)
+			return value, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if integer, ok := obj.(*object.Integer); ok {
+			value := reflect.New(t).Elem()
+			if integer.Value < 0 || value.OverflowUint(uint64(integer.Value)) {
+				return value, fmt.Errorf("%d does not fit in %s", integer.Value, t)
+			}
+
+			value.SetUint(uint64(integer.Value))
+			return value, nil
+		}
+	case reflect.String:
+		if str, ok := obj.(*object.String); ok {
+			return reflect.ValueOf(str.Value).Convert(t), nil
+		}
+	case reflect.Slice:
+		if array, ok := obj.(*object.Array); ok {
+			value := reflect.MakeSlice(t, 0, len(array.Elements))
+
+			for _, e := range array.Elements {
+				element, err := fromObject(e, t.Elem())
+				if err != nil {
+					return value, err
+				}
+
+				value = reflect.Append(value, element)
+			}
+
+			return value, nil
+		}
+	case reflect.Map:
+		if hash, ok := obj.(*object.Hash); ok {
+			value := reflect.MakeMapWithSize(t, len(hash.Pairs))
+
+			for _, pair := range hash.Pairs {
+				key, err := fromObject(pair.Key, t.Key())
+				if err != nil {
+					return value, err
+				}
+
+				element, err := fromObject(pair.Value, t.Elem())
+				if err != nil {
+					return value, err
+				}
+
+				value.SetMapIndex(key, element)
+			}
+
+			return value, nil
+		}
+	case reflect.Interface:
+		if isEmptyInterface {
+			if _, ok := obj.(*object.Null); ok {
+				return reflect.Zero(t), nil
+			}
+
+			// any value will do, convert it to the Go value it represents
+			var element reflect.Type
+			switch obj.(type) {
+			case *object.Array:
+				element = reflect.TypeOf([]interface{}{})
+			case *object.Hash:
+				element = reflect.TypeOf(map[interface{}]interface{}{})
+			}
+
+			if element != nil {
+				value, err := fromObject(obj, element)
+				if err != nil {
+					return value, err
+				}
+
+				return value.Convert(t), nil
+			}
+
+			if value, ok := nativeValue(obj); ok {
+				return reflect.ValueOf(value).Convert(t), nil
+			}
+		}
+	}
+
+	return reflect.Value{}, fmt.Errorf("want=%s, got=%s", typeName(t), obj.Type())
+}
+
+// nativeValue returns the Go value of a BOOLEAN, INTEGER or STRING
+func nativeValue(obj object.Object) (interface{}, bool) {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value, true
+	case *object.Integer:
+		return obj.Value, true
+	case *object.String:
+		return obj.Value, true
+	default:
+		return nil, false
+	}
+}
+
+// typeName names the monkey type that converts to t in errors, or t itself when there is none
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return object.BOOLEAN_OBJ
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return object.INTEGER_OBJ
+	case reflect.String:
+		return object.STRING_OBJ
+	case reflect.Slice:
+		return object.ARRAY_OBJ
+	case reflect.Map:
+		return object.HASH_OBJ
+	default:
+		return t.String()
+	}
+}
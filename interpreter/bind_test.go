@@ -0,0 +1,115 @@
+package interpreter
+
+import (
+	"akdjr/monkey/object"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	i := New()
+
+	var logged []string
+
+	functions := map[string]interface{}{
+		"repeat": func(s string, n int64) (string, error) {
+			if n < 0 {
+				return "", errors.New("repeat count must not be negative")
+			}
+
+			return strings.Repeat(s, int(n)), nil
+		},
+		"small": func(n int8) int8 { return n },
+		"count": func(n uint) uint { return n },
+		"not":   func(b bool) bool { return !b },
+		"sum": func(first int, rest ...int) int {
+			for _, n := range rest {
+				first += n
+			}
+			return first
+		},
+		"join":   func(words []string, separator string) string { return strings.Join(words, separator) },
+		"lookup": func(m map[string]int64, key string) int64 { return m[key] },
+		"log":    func(v interface{}) { logged = append(logged, v.(string)) },
+		"typeOf": func(obj object.Object) string { return string(obj.Type()) },
+		"fail":   func() error { return errors.New("failed") },
+		"boom":   func() int { panic("boom") },
+		"any":    func(values ...interface{}) int { return len(values) },
+	}
+
+	for name, fn := range functions {
+		if err := i.Register(name, fn); err != nil {
+			t.Fatalf("Register(%q) returned an error: %s", name, err)
+		}
+	}
+
+	i.Set("ha", "ha")
+	i.Set("words", []string{"a", "b", "c"})
+	i.Set("dash", "-")
+	i.Set("table", map[string]int{"x": 10})
+	i.Set("x", "x")
+	i.Set("message", "hello")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"repeat(ha, 3)", "hahaha"},
+		{"small(-128)", "-128"},
+		{"count(7)", "7"},
+		{"not(false)", "true"},
+		{"sum(1)", "1"},
+		{"sum(1, 2, 3)", "6"},
+		{"join(words, dash)", "a-b-c"},
+		{"lookup(table, x)", "10"},
+		{"log(message)", "null"},
+		{"typeOf(fn(x) { x })", "FUNCTION"},
+		{"any(1, true, words, table, if (false) { 1 })", "5"},
+		{"repeat(ha)", "ERROR: wrong number of arguments: want=2, got=1"},
+		{"sum()", "ERROR: wrong number of arguments: want at least 1, got=0"},
+		{"repeat(3, ha)", "ERROR: wrong type of argument 1: want=STRING, got=INTEGER"},
+		{"join(table, dash)", "ERROR: wrong type of argument 1: want=ARRAY, got=HASH"},
+		{"small(128)", "ERROR: wrong type of argument 1: 128 does not fit in int8"},
+		{"count(-1)", "ERROR: wrong type of argument 1: -1 does not fit in uint"},
+		{"repeat(ha, -1)", "ERROR: repeat count must not be negative"},
+		{"fail()", "ERROR: failed"},
+		{"boom()", "ERROR: panic: boom"},
+	}
+
+	for _, tt := range tests {
+		obj, err := i.Run(tt.input)
+
+		var got string
+		var runtimeErr *RuntimeError
+		switch {
+		case errors.As(err, &runtimeErr):
+			got = runtimeErr.Object.Inspect()
+		case err != nil:
+			t.Fatalf("Run(%q) returned an error: %s", tt.input, err)
+		default:
+			got = obj.Inspect()
+		}
+
+		if got != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	if len(logged) != 1 || logged[0] != "hello" {
+		t.Errorf("log was not called with hello. got=%v", logged)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	i := New()
+
+	if _, err := i.Bind(5); err == nil || err.Error() != "interpreter: can not bind int, it is not a function" {
+		t.Errorf("wrong error binding a non-function. got=%v", err)
+	}
+
+	var missing func()
+	if _, err := i.Bind(missing); err == nil {
+		t.Errorf("expected an error binding a nil function")
+	}
+}
@@ -0,0 +1,43 @@
+package interpreter
+
+import (
+	"akdjr/monkey/evaluator"
+	"akdjr/monkey/object"
+	"io"
+)
+
+// defineBuiltins defines the builtins that belong to this interpreter as globals.  they write to whatever Stdout and Stderr are when they are called
+func (i *Interpreter) defineBuiltins() {
+	defineBuiltins(i.env, func() io.Writer { return i.Stdout }, func() io.Writer { return i.Stderr })
+}
+
+// DefineBuiltins defines the builtins every interpreter defines, puts and eputs, in env, writing to stdout and stderr.  it is for Go programs that evaluate monkey code in environments of their own rather than with an Interpreter, such as the REPL
+func DefineBuiltins(env *object.Environment, stdout io.Writer, stderr io.Writer) {
+	defineBuiltins(env, func() io.Writer { return stdout }, func() io.Writer { return stderr })
+}
+
+func defineBuiltins(env *object.Environment, stdout func() io.Writer, stderr func() io.Writer) {
+	env.Set("puts", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return printLines(stdout(), args)
+	}})
+
+	env.Set("eputs", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return printLines(stderr(), args)
+	}})
+}
+
+// printLines writes every arg to w on a line of its own
+func printLines(w io.Writer, args []object.Object) object.Object {
+	for _, arg := range args {
+		if _, err := io.WriteString(w, arg.Inspect()+"\n"); err != nil {
+			return &object.Error{Message: err.Error()}
+		}
+	}
+
+	return evaluator.NULL
+}
+
+// Builtins returns the names of the builtins every interpreter defines, on top of the builtins that are part of the language
+func Builtins() []string {
+	return []string{"eputs", "puts"}
+}
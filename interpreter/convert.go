@@ -0,0 +1,183 @@
+package interpreter
+
+import (
+	"akdjr/monkey/evaluator"
+	"akdjr/monkey/object"
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// ToObject converts a Go value to the monkey value it represents:
+//
+//	nil                                    NULL
+//	bool                                   BOOLEAN
+//	int, int8 ... int64, uint ... uint64   INTEGER
+//	string                                 STRING
+//	slices and arrays                      ARRAY
+//	maps                                   HASH, the keys must convert to an INTEGER, BOOLEAN or STRING
+//	func(...object.Object) object.Object   BUILTIN
+//	any other func                         BUILTIN, see Bind
+//
+// pointers are converted to the value they point to, a nil pointer is NULL.  Values that already are an object.Object are returned as they are
+func (i *Interpreter) ToObject(value interface{}) (object.Object, error) {
+	return i.toObject(reflect.ValueOf(value))
+}
+
+func (i *Interpreter) toObject(v reflect.Value) (object.Object, error) {
+	if !v.IsValid() {
+		return evaluator.NULL, nil
+	}
+
+	if v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+	}
+
+	if v.CanInterface() {
+		switch value := v.Interface().(type) {
+		case object.Object:
+			return value, nil
+		case object.BuiltinFunction:
+			return &object.Builtin{Fn: value}, nil
+		case func(...object.Object) object.Object:
+			return &object.Builtin{Fn: value}, nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		return i.toObject(v.Elem())
+	case reflect.Func:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+
+		return i.bind(v)
+	case reflect.Bool:
+		if v.Bool() {
+			return evaluator.TRUE, nil
+		}
+
+		return evaluator.FALSE, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("interpreter: %d does not fit in an integer", v.Uint())
+		}
+
+		return &object.Integer{Value: int64(v.Uint())}, nil
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+	case reflect.Slice, reflect.Array:
+		elements := make([]object.Object, 0, v.Len())
+
+		for idx := 0; idx < v.Len(); idx++ {
+			element, err := i.toObject(v.Index(idx))
+			if err != nil {
+				return nil, err
+			}
+
+			elements = append(elements, element)
+		}
+
+		return &object.Array{Elements: elements}, nil
+	case reflect.Map:
+		pairs := make(map[object.HashKey]object.HashPair, v.Len())
+
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := i.toObject(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+
+			hashable, ok := key.(object.Hashable)
+			if !ok {
+				return nil, fmt.Errorf("interpreter: %s can not be used as a hash key", key.Type())
+			}
+
+			value, err := i.toObject(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+
+			pairs[hashable.HashKey()] = object.HashPair{Key: key, Value: value}
+		}
+
+		return &object.Hash{Pairs: pairs}, nil
+	}
+
+	return nil, fmt.Errorf("interpreter: can not convert %s to a monkey value", v.Type())
+}
+
+// FromObject converts a monkey value to the Go value it represents:
+//
+//	NULL                 nil
+//	BOOLEAN              bool
+//	INTEGER              int64
+//	STRING               string
+//	ARRAY                []interface{}
+//	HASH                 map[interface{}]interface{}
+//	FUNCTION, BUILTIN    func(args ...interface{}) (interface{}, error)
+//
+// the arguments and result of a converted function are converted the same as the arguments and result of Call, and the function is called within the limits of this interpreter.  An ERROR is returned as a *RuntimeError
+func (i *Interpreter) FromObject(obj object.Object) (interface{}, error) {
+	switch obj := obj.(type) {
+	case nil, *object.Null:
+		return nil, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.Integer:
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+	case *object.Array:
+		elements := make([]interface{}, 0, len(obj.Elements))
+
+		for _, e := range obj.Elements {
+			element, err := i.FromObject(e)
+			if err != nil {
+				return nil, err
+			}
+
+			elements = append(elements, element)
+		}
+
+		return elements, nil
+	case *object.Hash:
+		pairs := make(map[interface{}]interface{}, len(obj.Pairs))
+
+		for _, pair := range obj.Pairs {
+			key, err := i.FromObject(pair.Key)
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := i.FromObject(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			pairs[key] = value
+		}
+
+		return pairs, nil
+	case *object.Function, *object.Builtin:
+		return func(args ...interface{}) (interface{}, error) {
+			result, err := i.call(context.Background(), obj, args)
+			if err != nil {
+				return nil, err
+			}
+
+			return i.FromObject(result)
+		}, nil
+	case *object.Error:
+		return nil, &RuntimeError{Object: obj}
+	}
+
+	return nil, fmt.Errorf("interpreter: can not convert %s to a Go value", obj.Type())
+}
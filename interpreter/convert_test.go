@@ -0,0 +1,142 @@
+package interpreter
+
+import (
+	"akdjr/monkey/evaluator"
+	"akdjr/monkey/object"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestToObject(t *testing.T) {
+	seven := 7
+	var missing *int
+
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, "null"},
+		{true, "true"},
+		{int8(-5), "-5"},
+		{uint64(42), "42"},
+		{"monkey", "monkey"},
+		{&seven, "7"},
+		{missing, "null"},
+		{[]int{1, 2, 3}, "[1, 2, 3]"},
+		{[2]interface{}{"a", nil}, "[a, null]"},
+		{map[string]int{"b": 2, "a": 1}, "{a: 1, b: 2}"},
+		{map[int][]bool{1: {true}}, "{1: [true]}"},
+		{&object.Integer{Value: 3}, "3"},
+	}
+
+	i := New()
+
+	for _, tt := range tests {
+		obj, err := i.ToObject(tt.value)
+		if err != nil {
+			t.Errorf("ToObject(%#v) returned an error: %s", tt.value, err)
+			continue
+		}
+
+		if obj.Inspect() != tt.expected {
+			t.Errorf("wrong conversion of %#v. expected=%q, got=%q", tt.value, tt.expected, obj.Inspect())
+		}
+	}
+
+	if obj, _ := i.ToObject(false); obj != evaluator.FALSE {
+		t.Errorf("false is not converted to FALSE. got=%T (%+v)", obj, obj)
+	}
+}
+
+func TestToObjectErrors(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{uint64(math.MaxUint64), "interpreter: 18446744073709551615 does not fit in an integer"},
+		{3.5, "interpreter: can not convert float64 to a monkey value"},
+		{map[interface{}]int{nil: 1}, "interpreter: NULL can not be used as a hash key"},
+		{[]interface{}{struct{}{}}, "interpreter: can not convert struct {} to a monkey value"},
+		{func() (int, int) { return 1, 2 }, "interpreter: can not bind func() (int, int), it must return at most one value and an error"},
+	}
+
+	i := New()
+
+	for _, tt := range tests {
+		_, err := i.ToObject(tt.value)
+		if err == nil {
+			t.Errorf("expected an error converting %#v", tt.value)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error converting %#v. expected=%q, got=%q", tt.value, tt.expected, err.Error())
+		}
+	}
+}
+
+func TestFromObject(t *testing.T) {
+	i := New()
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"if (false) { 1 }", nil},
+		{"5 * 5", int64(25)},
+		{"1 < 2", true},
+		{"word", "monkey"},
+		{"list", []interface{}{int64(1), "two", nil}},
+		{"table", map[interface{}]interface{}{"a": int64(1), true: []interface{}{}}},
+	}
+
+	i.Set("word", "monkey")
+	i.Set("list", []interface{}{1, "two", nil})
+	i.Set("table", map[interface{}]interface{}{"a": 1, true: []int{}})
+
+	for _, tt := range tests {
+		obj, err := i.Run(tt.input)
+		if err != nil {
+			t.Fatalf("Run(%q) returned an error: %s", tt.input, err)
+		}
+
+		value, err := i.FromObject(obj)
+		if err != nil {
+			t.Errorf("FromObject(%s) returned an error: %s", obj.Inspect(), err)
+			continue
+		}
+
+		if !reflect.DeepEqual(value, tt.expected) {
+			t.Errorf("wrong conversion of %s. expected=%#v, got=%#v", obj.Inspect(), tt.expected, value)
+		}
+	}
+}
+
+func TestFromObjectFunction(t *testing.T) {
+	i := New()
+
+	obj, err := i.Run("fn(a, b) { a * b }")
+	if err != nil {
+		t.Fatalf("Run returned an error: %s", err)
+	}
+
+	value, err := i.FromObject(obj)
+	if err != nil {
+		t.Fatalf("FromObject returned an error: %s", err)
+	}
+
+	multiply, ok := value.(func(args ...interface{}) (interface{}, error))
+	if !ok {
+		t.Fatalf("function is not converted to a Go func. got=%T", value)
+	}
+
+	product, err := multiply(6, 7)
+	if err != nil || product != int64(42) {
+		t.Errorf("wrong result calling the function. expected=42, got=%#v, err=%v", product, err)
+	}
+
+	if _, err := multiply(true, 7); err == nil || err.Error() != "type mismatch: BOOLEAN * INTEGER" {
+		t.Errorf("wrong error calling the function. got=%v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package interpreter
+
+import (
+	"akdjr/monkey/object"
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+const forkBase = `
+let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+let twice = macro(x) { quote(unquote(x) + unquote(x)) };
+let offset = 100;
+`
+
+func TestFork(t *testing.T) {
+	base := New()
+	if _, err := base.Run(forkBase); err != nil {
+		t.Fatalf("Run returned an error: %s", err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+
+		go func(g int) {
+			defer wg.Done()
+
+			var stdout bytes.Buffer
+			fork := base.Fork()
+			fork.Stdout = &stdout
+			fork.Set("n", g)
+
+			// every fork shadows offset in its own scope
+			result, err := fork.Run(fmt.Sprintf("let offset = %d; puts(n); twice(fib(n)) + offset", g))
+			if err != nil {
+				t.Errorf("Run returned an error: %s", err)
+				return
+			}
+
+			expected := 2*fib(g) + int64(g)
+			if integer, ok := result.(*object.Integer); !ok || integer.Value != expected {
+				t.Errorf("wrong result in fork %d. expected=%d, got=%s", g, expected, result.Inspect())
+			}
+
+			if stdout.String() != fmt.Sprintf("%d\n", g) {
+				t.Errorf("wrong stdout in fork %d. got=%q", g, stdout.String())
+			}
+		}(g)
+	}
+
+	wg.Wait()
+
+	if offset, _ := base.Get("offset"); offset.Inspect() != "100" {
+		t.Errorf("a fork changed offset in the base. got=%s", offset.Inspect())
+	}
+
+	if _, err := base.Run("let x = 1;"); !errors.Is(err, ErrFrozen) {
+		t.Errorf("expected ErrFrozen running on a forked interpreter. got=%v", err)
+	}
+
+	if err := base.Set("x", 1); !errors.Is(err, ErrFrozen) {
+		t.Errorf("expected ErrFrozen setting a global of a forked interpreter. got=%v", err)
+	}
+
+	if result, err := base.Call("fib", 10); err != nil || result.Inspect() != "55" {
+		t.Errorf("wrong result calling fib on a forked interpreter. got=%v, err=%v", result, err)
+	}
+}
+
+func fib(n int) int64 {
+	if n < 2 {
+		return int64(n)
+	}
+
+	return fib(n-1) + fib(n-2)
+}
+
+func BenchmarkForkParallel(b *testing.B) {
+	base := New()
+	if _, err := base.Run(forkBase); err != nil {
+		b.Fatalf("Run returned an error: %s", err)
+	}
+
+	base.Fork()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := base.Fork().Run("fib(15) + offset"); err != nil {
+				b.Fatalf("Run returned an error: %s", err)
+			}
+		}
+	})
+}
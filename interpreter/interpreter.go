@@ -0,0 +1,215 @@
+package interpreter
+
+import (
+	"akdjr/monkey/evaluator"
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/object"
+	"akdjr/monkey/parser"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Interpreter runs monkey programs for a Go program that embeds monkey.  Globals and macros defined by one run stay defined for the next, the same as in the REPL
+type Interpreter struct {
+	// Stdout and Stderr are where the puts and eputs builtins write to
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Limits bounds the resources used by every call to Run and Call separately
+	Limits evaluator.Limits
+
+	// Hooks, when set, are called as every run and call is evaluated, such as by a debugger.  macro expansion is not followed by them, and forks do not inherit them
+	Hooks *evaluator.Hooks
+
+	env      *object.Environment
+	macroEnv *object.Environment
+}
+
+// New creates an Interpreter with no globals defined besides the builtins, that writes to os.Stdout and os.Stderr
+func New() *Interpreter {
+	i := &Interpreter{
+		Stdout:   os.Stdout,
+		Stderr:   os.Stderr,
+		env:      object.NewEnvironment(),
+		macroEnv: object.NewEnvironment(),
+	}
+
+	i.defineBuiltins()
+
+	return i
+}
+
+// ErrFrozen is returned when a program is run on, or a global is set in, an interpreter that has been forked.  the globals of a forked interpreter are shared by its forks and can no longer change
+var ErrFrozen = errors.New("interpreter: the interpreter has been forked, its globals are frozen")
+
+// Fork creates an interpreter that sees the globals and macros of i, but defines its own in a scope of its own.  The fork starts out with the same Stdout, Stderr and Limits as i
+// i is frozen by the first call to Fork, after which its globals can no longer change.  Run and Set return ErrFrozen from then on, but functions defined in i can still be called.  Many forks of the same interpreter can be used at once, each from a goroutine of its own, and they share the globals of i without copying them
+func (i *Interpreter) Fork() *Interpreter {
+	i.env.Freeze()
+	i.macroEnv.Freeze()
+
+	fork := &Interpreter{
+		Stdout:   i.Stdout,
+		Stderr:   i.Stderr,
+		Limits:   i.Limits,
+		env:      object.NewEnclosedEnvironment(i.env),
+		macroEnv: object.NewEnclosedEnvironment(i.macroEnv),
+	}
+
+	// the builtins of i write to the Stdout and Stderr of i, the fork needs its own
+	fork.defineBuiltins()
+
+	return fork
+}
+
+// ParseError is returned when a program can not be parsed.  Errors holds every lexer and parser error, in order
+type ParseError struct {
+	Errors []string
+}
+
+func (e *ParseError) Error() string {
+	return strings.Join(e.Errors, "\n")
+}
+
+// RuntimeError is returned when evaluating a program results in an error.  Object is the error as monkey code sees it, which can be compared to the evaluator errors for exceeded limits, such as evaluator.ErrStepLimit
+type RuntimeError struct {
+	Object *object.Error
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Object.Message
+}
+
+// Run runs source and returns the value of its last statement
+func (i *Interpreter) Run(source string) (object.Object, error) {
+	return i.run(context.Background(), lexer.New(source))
+}
+
+// RunContext runs source the same as Run, but stops once ctx is done
+func (i *Interpreter) RunContext(ctx context.Context, source string) (object.Object, error) {
+	return i.run(ctx, lexer.New(source))
+}
+
+// RunReader runs the program read from r the same as Run.  The program is streamed from r, it is never read into memory all at once
+func (i *Interpreter) RunReader(r io.Reader) (object.Object, error) {
+	return i.run(context.Background(), lexer.NewReader(r))
+}
+
+func (i *Interpreter) run(ctx context.Context, l *lexer.Lexer) (obj object.Object, err error) {
+	defer recovered(&obj, &err)
+
+	if i.env.Frozen() {
+		return nil, ErrFrozen
+	}
+
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if errors := p.Errors(); len(errors) > 0 {
+		return nil, &ParseError{Errors: errors}
+	}
+
+	e := i.evaluator()
+
+	// the hooks follow the program as it runs, not the macros that are expanded before it does
+	e.Hooks = nil
+
+	evaluator.DefineMacros(program, i.macroEnv)
+	expanded, err := e.ExpandMacrosContext(ctx, program, i.macroEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	e.Hooks = i.Hooks
+
+	return result(e.EvalContext(ctx, expanded, i.env))
+}
+
+// Call calls the global function name with args, converted with ToObject, and returns its result
+func (i *Interpreter) Call(name string, args ...interface{}) (object.Object, error) {
+	return i.CallContext(context.Background(), name, args...)
+}
+
+// CallContext calls the global function name the same as Call, but stops once ctx is done
+func (i *Interpreter) CallContext(ctx context.Context, name string, args ...interface{}) (object.Object, error) {
+	fn, ok := i.env.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("interpreter: %s is not defined", name)
+	}
+
+	return i.call(ctx, fn, args)
+}
+
+// CallFunction calls fn, a function such as one passed to a builtin, with args, converted with ToObject, and returns its result the same as Call
+func (i *Interpreter) CallFunction(fn object.Object, args ...interface{}) (object.Object, error) {
+	return i.call(context.Background(), fn, args)
+}
+
+func (i *Interpreter) call(ctx context.Context, fn object.Object, args []interface{}) (obj object.Object, err error) {
+	defer recovered(&obj, &err)
+
+	objects := make([]object.Object, 0, len(args))
+
+	for _, arg := range args {
+		obj, err := i.ToObject(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, obj)
+	}
+
+	return result(i.evaluator().ApplyContext(ctx, fn, objects))
+}
+
+// Set defines the global name with value, converted with ToObject
+func (i *Interpreter) Set(name string, value interface{}) error {
+	if i.env.Frozen() {
+		return ErrFrozen
+	}
+
+	obj, err := i.ToObject(value)
+	if err != nil {
+		return err
+	}
+
+	i.env.Set(name, obj)
+
+	return nil
+}
+
+// Get returns the value of the global name
+func (i *Interpreter) Get(name string) (object.Object, bool) {
+	return i.env.Get(name)
+}
+
+// evaluator creates the evaluator for one run or call
+func (i *Interpreter) evaluator() *evaluator.Evaluator {
+	e := evaluator.New()
+	e.Limits = i.Limits
+	e.Hooks = i.Hooks
+
+	return e
+}
+
+// recovered turns a panic during a run or a call into a RuntimeError, so a bug in the evaluator or in a builtin defined by the Go program fails the run instead of taking down the whole program.  it must be deferred
+func recovered(obj *object.Object, err *error) {
+	if r := recover(); r != nil {
+		*obj = nil
+		*err = &RuntimeError{Object: &object.Error{Message: fmt.Sprintf("internal error: %v", r)}}
+	}
+}
+
+// result turns the result of an evaluation into the result of a run or a call
+func result(obj object.Object) (object.Object, error) {
+	switch obj := obj.(type) {
+	case *object.Error:
+		return nil, &RuntimeError{Object: obj}
+	default:
+		return obj, nil
+	}
+}
@@ -0,0 +1,184 @@
+package interpreter
+
+import (
+	"akdjr/monkey/evaluator"
+	"akdjr/monkey/object"
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	i := New()
+	i.Stdout = &stdout
+	i.Stderr = &stderr
+
+	if _, err := i.Run(`let add = fn(a, b) { a + b }; let unless = macro(c, body) { quote(if (!(unquote(c))) { unquote(body) }) };`); err != nil {
+		t.Fatalf("Run returned an error: %s", err)
+	}
+
+	// globals and macros stay defined between runs
+	result, err := i.Run(`puts(add(1, 2), true); eputs(unless(false, 3)); add(20, 22)`)
+	if err != nil {
+		t.Fatalf("Run returned an error: %s", err)
+	}
+
+	if integer, ok := result.(*object.Integer); !ok || integer.Value != 42 {
+		t.Errorf("wrong result. expected=42, got=%T (%+v)", result, result)
+	}
+
+	if stdout.String() != "3\ntrue\n" {
+		t.Errorf("wrong stdout. expected=%q, got=%q", "3\ntrue\n", stdout.String())
+	}
+
+	if stderr.String() != "3\n" {
+		t.Errorf("wrong stderr. expected=%q, got=%q", "3\n", stderr.String())
+	}
+
+	result, err = i.Run("let x = 5;")
+	if err != nil || result != evaluator.NULL {
+		t.Errorf("expected NULL from a let statement. got=%T (%+v), err=%v", result, result, err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	i := New()
+
+	_, err := i.Run("let = 5;")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a *ParseError. got=%T (%v)", err, err)
+	}
+
+	if len(parseErr.Errors) == 0 {
+		t.Errorf("ParseError has no errors")
+	}
+
+	_, err = i.Run("5 + true")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("expected a *RuntimeError. got=%T (%v)", err, err)
+	}
+
+	if err.Error() != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong error message. got=%q", err.Error())
+	}
+
+	i.Limits = evaluator.Limits{MaxSteps: 1000}
+
+	_, err = i.Run("let f = fn(n) { f(n + 1) }; f(0)")
+	if !errors.As(err, &runtimeErr) || runtimeErr.Object != evaluator.ErrStepLimit {
+		t.Errorf("expected the step limit error. got=%T (%v)", err, err)
+	}
+
+	// the limits apply to every run separately
+	if _, err := i.Run("1 + 1"); err != nil {
+		t.Errorf("Run returned an error: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	i.Limits = evaluator.Limits{}
+	_, err = i.RunContext(ctx, "let f = fn(n) { f(n + 1) }; f(0)")
+	if !errors.As(err, &runtimeErr) || runtimeErr.Object != evaluator.ErrDeadlineExceeded {
+		t.Errorf("expected the deadline error. got=%T (%v)", err, err)
+	}
+}
+
+func TestCall(t *testing.T) {
+	i := New()
+	i.Set("separator", ", ")
+
+	if _, err := i.Run(`let greet = fn(greeting, name) { greeting + separator + name }`); err != nil {
+		t.Fatalf("Run returned an error: %s", err)
+	}
+
+	result, err := i.Call("greet", "Hello", "monkey")
+	if err != nil {
+		t.Fatalf("Call returned an error: %s", err)
+	}
+
+	if str, ok := result.(*object.String); !ok || str.Value != "Hello, monkey" {
+		t.Errorf("wrong result. expected=%q, got=%T (%+v)", "Hello, monkey", result, result)
+	}
+
+	if _, err := i.Call("missing"); err == nil || err.Error() != "interpreter: missing is not defined" {
+		t.Errorf("wrong error calling an undefined function. got=%v", err)
+	}
+
+	if _, err := i.Call("greet", "Hello"); err == nil || err.Error() != "wrong number of arguments: want=2, got=1" {
+		t.Errorf("wrong error calling with too few arguments. got=%v", err)
+	}
+
+	greet, _ := i.Get("greet")
+	result, err = i.CallFunction(greet, "Hi", "monkey")
+	if str, ok := result.(*object.String); err != nil || !ok || str.Value != "Hi, monkey" {
+		t.Errorf("wrong result of CallFunction. expected=%q, got=%T (%+v), %v", "Hi, monkey", result, result, err)
+	}
+}
+
+func TestPanics(t *testing.T) {
+	i := New()
+	i.Set("boom", &object.Builtin{Fn: func(args ...object.Object) object.Object { panic("boom") }})
+
+	if _, err := i.Run("let f = fn() { boom() }; 1 / 0"); err == nil || err.Error() != "division by zero" {
+		t.Errorf("wrong error dividing by zero. got=%v", err)
+	}
+
+	if _, err := i.Run("boom()"); err == nil || err.Error() != "internal error: boom" {
+		t.Errorf("wrong error for a panic in Run. got=%v", err)
+	}
+
+	if _, err := i.Call("f"); err == nil || err.Error() != "internal error: boom" {
+		t.Errorf("wrong error for a panic in Call. got=%v", err)
+	}
+
+	if _, err := i.Run("wait(spawn boom())"); err == nil || err.Error() != "internal error: boom" {
+		t.Errorf("wrong error for a panic in a task. got=%v", err)
+	}
+}
+
+func TestSetAndGet(t *testing.T) {
+	i := New()
+
+	if err := i.Set("config", map[string]interface{}{"port": 8080, "debug": true}); err != nil {
+		t.Fatalf("Set returned an error: %s", err)
+	}
+
+	if err := i.Set("double", func(args ...object.Object) object.Object {
+		return &object.Integer{Value: args[0].(*object.Integer).Value * 2}
+	}); err != nil {
+		t.Fatalf("Set returned an error: %s", err)
+	}
+
+	if _, err := i.Run("let doubled = double(21);"); err != nil {
+		t.Fatalf("Run returned an error: %s", err)
+	}
+
+	doubled, ok := i.Get("doubled")
+	if !ok {
+		t.Fatalf("doubled is not defined")
+	}
+
+	if integer, ok := doubled.(*object.Integer); !ok || integer.Value != 42 {
+		t.Errorf("wrong value. expected=42, got=%T (%+v)", doubled, doubled)
+	}
+
+	config, _ := i.Get("config")
+	if config.Inspect() != "{debug: true, port: 8080}" {
+		t.Errorf("wrong value. got=%s", config.Inspect())
+	}
+
+	if _, ok := i.Get("missing"); ok {
+		t.Errorf("missing is defined")
+	}
+
+	if err := i.Set("bad", make(chan int)); err == nil {
+		t.Errorf("expected an error setting an unsupported value")
+	}
+}
@@ -2,45 +2,101 @@ package lexer
 
 import (
 	"akdjr/monkey/token"
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-// Lexer represents an instance of the lexer.  It holds the input source as a string and maintains the current position in the input which points to the current char and the position of the next character to read
+// bufferSize is the number of bytes of input the lexer holds at a time.  Only the current token is ever kept in memory, so programs of any size can be lexed
+const bufferSize = 4096
+
+// Lexer represents an instance of the lexer.  It reads the input source from a buffered reader and maintains the current character and enough state to peek at the character after it
+// The input is decoded as UTF-8, so currentChar is always a full rune
 type Lexer struct {
-	input        string
-	position     int  // points to where we are currently reading
-	readPosition int  // points to where we will read next
-	currentChar  byte // character at the position where we are currently reading
+	reader       *bufio.Reader
+	currentChar  rune   // character at the position where we are currently reading
+	currentWidth int    // width in bytes of currentChar, used to tell invalid UTF-8 apart from an encoded U+FFFD
+	currentRaw   string // the raw bytes of currentChar when it is not valid UTF-8
+	done         bool   // set once the reader is exhausted, nothing more is read after that
+
+	keepComments bool // when set, comments are attached to the token that follows them instead of being dropped
+
+	line   int // line of currentChar, starting at 1
+	column int // column of currentChar in runes, starting at 1
+
+	errors []*Error
+}
+
+// Error represents a problem found in the input source, such as invalid UTF-8.  Line and Column point at the offending character
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
 }
 
 // New creates a new instance of the lexer from an input string.  input contains monkey source
 func New(input string) *Lexer {
-	l := &Lexer{
-		input: input,
-	}
+	return NewReader(strings.NewReader(input))
+}
 
-	l.readChar()
+// NewReader creates a new instance of the lexer that reads monkey source from r as tokens are requested.  The lexer buffers at most bufferSize bytes of r at a time
+func NewReader(r io.Reader) *Lexer {
+	l := &Lexer{}
+	l.reset(r)
 
 	return l
 }
 
 // Load loads a new set of input source into the lexer and resets it
 func (l *Lexer) Load(input string) {
-	l.input = input
-	l.position = 0
-	l.readPosition = 0
+	l.reset(strings.NewReader(input))
+}
+
+// reset points the lexer at the beginning of r and reads the first character
+func (l *Lexer) reset(r io.Reader) {
+	if l.reader == nil {
+		l.reader = bufio.NewReaderSize(r, bufferSize)
+	} else {
+		l.reader.Reset(r)
+	}
+
 	l.currentChar = 0
+	l.currentWidth = 0
+	l.currentRaw = ""
+	l.done = false
+	l.line = 1
+	l.column = 0
+	l.errors = nil
 
 	l.readChar()
 }
 
+// KeepComments controls whether comments are retained.  When keep is true, every comment is attached to the Comments of the token that follows it, so tools such as a formatter can preserve them
+func (l *Lexer) KeepComments(keep bool) {
+	l.keepComments = keep
+}
+
+// Errors returns all errors encountered in the input so far
+func (l *Lexer) Errors() []*Error {
+	return l.errors
+}
+
 // NextToken converts the current char into a token
 func (l *Lexer) NextToken() token.Token {
 	var t token.Token
 
-	l.skipWhitespace()
+	comments := l.skipWhitespaceAndComments()
+
+	// remember where the token starts, reading the token will advance the lexer past it
+	line, column := l.line, l.column
 
-	// currentChar is ASCII byte
-	// TODO: Full unicode support instead of simple ASCII
 	// TODO: Add additional two character operators and refactor
 	switch l.currentChar {
 	case '=':
@@ -106,17 +162,35 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.currentChar) {
 			t.Literal = l.readIdentifier()
 			t.Type = token.LookupIdentifier(t.Literal)
+			t.Line, t.Column = line, column
+			t.Comments = comments
 			return t
 		} else if isDigit(l.currentChar) {
 			t.Literal = l.readNumber()
 			t.Type = token.INT
+			t.Line, t.Column = line, column
+			t.Comments = comments
 			return t
+		} else if l.currentChar == utf8.RuneError && l.currentWidth == 1 {
+			// the input is not valid UTF-8.  keep the raw byte as the literal so the source can still be shown
+			t = token.Token{
+				Type:    token.ILLEGAL,
+				Literal: l.currentRaw,
+			}
+			l.errors = append(l.errors, &Error{
+				Line:    line,
+				Column:  column,
+				Message: fmt.Sprintf("invalid UTF-8 encoding %q", t.Literal),
+			})
 		} else {
 			// current character is not a letter or a valid single character token
 			t = token.New(token.ILLEGAL, l.currentChar)
 		}
 	}
 
+	t.Line, t.Column = line, column
+	t.Comments = comments
+
 	// read the next character into the lexer
 	l.readChar()
 	return t
@@ -129,69 +203,192 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
+// skipWhitespaceAndComments advances past all whitespace and comments in front of the next token
+// the skipped comments are returned if the lexer keeps comments, otherwise nil is returned
+func (l *Lexer) skipWhitespaceAndComments() []token.Comment {
+	var comments []token.Comment
+
+	for {
+		l.skipWhitespace()
+
+		if l.currentChar != '/' {
+			return comments
+		}
+
+		var comment token.Comment
+
+		switch l.peekChar() {
+		case '/':
+			comment = l.readLineComment()
+		case '*':
+			comment = l.readBlockComment()
+		default:
+			// a lone '/' is the SLASH operator
+			return comments
+		}
+
+		if l.keepComments {
+			comments = append(comments, comment)
+		}
+	}
+}
+
+// readLineComment reads a // comment up to, but not including, the end of the line
+func (l *Lexer) readLineComment() token.Comment {
+	comment := token.Comment{Line: l.line, Column: l.column}
+
+	var out strings.Builder
+	for l.currentChar != '\n' && !l.atEOF() {
+		out.WriteRune(l.currentChar)
+		l.readChar()
+	}
+
+	comment.Text = strings.TrimSuffix(out.String(), "\r")
+	return comment
+}
+
+// readBlockComment reads a /* */ comment.  block comments nest, so every /* inside the comment needs its own */
+// reaching the end of the input before the comment is closed is reported as an error at the start of the comment
+func (l *Lexer) readBlockComment() token.Comment {
+	comment := token.Comment{Line: l.line, Column: l.column}
+
+	var out strings.Builder
+	depth := 0
+
+	for !l.atEOF() {
+		if l.currentChar == '/' && l.peekChar() == '*' {
+			depth++
+			out.WriteString("/*")
+			l.readChar()
+			l.readChar()
+			continue
+		}
+
+		if l.currentChar == '*' && l.peekChar() == '/' {
+			depth--
+			out.WriteString("*/")
+			l.readChar()
+			l.readChar()
+
+			if depth == 0 {
+				comment.Text = out.String()
+				return comment
+			}
+			continue
+		}
+
+		out.WriteRune(l.currentChar)
+		l.readChar()
+	}
+
+	l.errors = append(l.errors, &Error{
+		Line:    comment.Line,
+		Column:  comment.Column,
+		Message: "unterminated block comment",
+	})
+
+	comment.Text = out.String()
+	return comment
+}
+
+// atEOF reports whether the lexer has consumed all of the input
+func (l *Lexer) atEOF() bool {
+	return l.currentChar == 0 && l.currentWidth == 0
+}
+
 // read the next character in input
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
-		//  readPosition has reached the end of the input string
+	// the character we are leaving behind determines where the next one sits
+	if l.currentChar == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
+	if l.done {
+		l.currentChar = 0
+		l.currentWidth = 0
+		return
+	}
+
+	ch, width, err := l.reader.ReadRune()
+	if err != nil {
+		if err != io.EOF {
+			l.errors = append(l.errors, &Error{
+				Line:    l.line,
+				Column:  l.column,
+				Message: fmt.Sprintf("could not read input: %s", err),
+			})
+		}
+
+		// we have reached the end of the input (or can not read any more of it)
 		// set the current char to ASCII 0 (NUL), this will be returned as token.EOF
+		l.done = true
 		l.currentChar = 0
-	} else {
-		// read the character from the input and assign it to currentChar for the lexer to examine
-		l.currentChar = l.input[l.readPosition]
+		l.currentWidth = 0
+		return
 	}
 
-	// set position to be the location of this current character
-	l.position = l.readPosition
+	l.currentChar = ch
+	l.currentWidth = width
+	l.currentRaw = ""
 
-	// advance the read position by 1 character
-	l.readPosition++
+	if ch == utf8.RuneError && width == 1 {
+		// invalid UTF-8 decodes as utf8.RuneError with a width of 1
+		// step back over it to keep the offending byte for error reporting
+		l.reader.UnreadRune()
+		b, _ := l.reader.ReadByte()
+		l.currentRaw = string([]byte{b})
+	}
 }
 
-// peek at the next char in the input and return it, but do not advance the read position
-// readPosition always points to the next character after the current one
-func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
+// peek at the next char in the input and return it, but do not advance the lexer
+func (l *Lexer) peekChar() rune {
+	// a rune is at most utf8.UTFMax bytes, Peek returns fewer bytes near the end of the input
+	buf, _ := l.reader.Peek(utf8.UTFMax)
+	if len(buf) == 0 {
 		return 0
 	}
 
-	return l.input[l.readPosition]
+	ch, _ := utf8.DecodeRune(buf)
+	return ch
 }
 
-// readIdentifier reads characters until it hits a non-letter and then returns the string
+// readIdentifier reads characters until it hits a character that can not be part of an identifier and then returns the string
+// identifiers start with a letter and may continue with letters or digits
 func (l *Lexer) readIdentifier() string {
-	// start at the current character position
-	position := l.position
+	var out strings.Builder
 
-	// keep advancing forward until we hit a non-letter
-	for isLetter(l.currentChar) {
+	// keep advancing forward until we hit a non-letter, non-digit
+	for isLetter(l.currentChar) || unicode.IsDigit(l.currentChar) {
+		out.WriteRune(l.currentChar)
 		l.readChar()
 	}
 
-	// l.currentCharacter and l.position represent the character after the identifier
-	// return the slice for this identifier
-	return l.input[position:l.position]
+	// l.currentChar is now the character after the identifier
+	return out.String()
 }
 
 // readNumber reads characters until it hits a non-digit
 // TODO: allow floating point numbers as this only supports integers
 // TODO: almost identical to readIdentifier - REFACTOR
 func (l *Lexer) readNumber() string {
-	position := l.position
+	var out strings.Builder
 
 	for isDigit(l.currentChar) {
+		out.WriteRune(l.currentChar)
 		l.readChar()
 	}
 
-	return l.input[position:l.position]
+	return out.String()
 }
 
-// isLetter checks if ch is a valid identifier character
-// TODO: Full unicode support instead of simple ASCII
-func isLetter(ch byte) bool {
-	return ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || (ch == '_')
+// isLetter checks if ch is a valid identifier character.  follows the Go spec, a letter is any unicode letter or '_'
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
 }
 
-// isDigit checks if ch is a valid digit
-func isDigit(ch byte) bool {
+// isDigit checks if ch is a valid digit for an integer literal.  only ASCII decimal digits are accepted
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
@@ -2,7 +2,11 @@ package lexer
 
 import (
 	"akdjr/monkey/token"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestNextToken(t *testing.T) {
@@ -89,7 +93,7 @@ func TestNextToken(t *testing.T) {
 			},
 		},
 		{
-			input: `!-/*5;
+			input: `!-/ *5;
 			5 < 10 > 5;
 			=`,
 			expectedTokens: []expectedTokenType{
@@ -198,3 +202,239 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	input := `let größe = 5;
+let 変数 = größe;
+let x1 = _ñ;
+€`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENTIFIER, "größe"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.LET, "let"},
+		{token.IDENTIFIER, "変数"},
+		{token.ASSIGN, "="},
+		{token.IDENTIFIER, "größe"},
+		{token.SEMICOLON, ";"},
+		{token.LET, "let"},
+		{token.IDENTIFIER, "x1"},
+		{token.ASSIGN, "="},
+		{token.IDENTIFIER, "_ñ"},
+		{token.SEMICOLON, ";"},
+		{token.ILLEGAL, "€"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	if len(l.Errors()) != 0 {
+		t.Fatalf("lexer has %d errors, expected none. first=%q", len(l.Errors()), l.Errors()[0])
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let ä = 5;\n  ä == 10"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"let", 1, 1},
+		{"ä", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"ä", 2, 3},
+		{"==", 2, 5},
+		{"10", 2, 8},
+		{"", 2, 10},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position of %q wrong. expected=%d:%d, got=%d:%d", i, tok.Literal, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	l := New("let a\xffb = 1;\n\xfe")
+
+	expectedTypes := []token.TokenType{
+		token.LET,
+		token.IDENTIFIER,
+		token.ILLEGAL,
+		token.IDENTIFIER,
+		token.ASSIGN,
+		token.INT,
+		token.SEMICOLON,
+		token.ILLEGAL,
+		token.EOF,
+	}
+
+	for i, expected := range expectedTypes {
+		tok := l.NextToken()
+
+		if tok.Type != expected {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, expected, tok.Type)
+		}
+	}
+
+	errors := l.Errors()
+	if len(errors) != 2 {
+		t.Fatalf("wrong number of errors. expected=2, got=%d", len(errors))
+	}
+
+	if errors[0].Line != 1 || errors[0].Column != 6 {
+		t.Errorf("errors[0] has wrong position. expected=1:6, got=%d:%d", errors[0].Line, errors[0].Column)
+	}
+
+	if errors[1].Line != 2 || errors[1].Column != 1 {
+		t.Errorf("errors[1] has wrong position. expected=2:1, got=%d:%d", errors[1].Line, errors[1].Column)
+	}
+
+	expectedMessage := `line 1, column 6: invalid UTF-8 encoding "\xff"`
+	if errors[0].Error() != expectedMessage {
+		t.Errorf("errors[0].Error() wrong. expected=%q, got=%q", expectedMessage, errors[0].Error())
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	// repeat the program enough times that it is much larger than the lexer's buffer
+	program := `let größe = fn(x, y) {
+	return x + y * 10 / 2;
+};
+if (größe(1, 2) != 5) { !true } else { -1 == 1 }
+` + "\xff\n"
+	input := strings.Repeat(program, (bufferSize/len(program))*3)
+
+	expected := New(input)
+	actual := NewReader(iotest.OneByteReader(strings.NewReader(input)))
+
+	for i := 0; ; i++ {
+		want := expected.NextToken()
+		got := actual.NextToken()
+
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("token[%d] wrong. expected=%+v, got=%+v", i, want, got)
+		}
+
+		if want.Type == token.EOF {
+			break
+		}
+	}
+
+	if len(actual.Errors()) != len(expected.Errors()) {
+		t.Fatalf("wrong number of errors. expected=%d, got=%d", len(expected.Errors()), len(actual.Errors()))
+	}
+}
+
+func TestNewReaderError(t *testing.T) {
+	l := NewReader(iotest.DataErrReader(iotest.ErrReader(errors.New("broken pipe"))))
+
+	tok := l.NextToken()
+	if tok.Type != token.EOF {
+		t.Fatalf("tokentype wrong. expected=%q, got=%q", token.EOF, tok.Type)
+	}
+
+	// asking again must not read from the broken reader again
+	l.NextToken()
+
+	errors := l.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("wrong number of errors. expected=1, got=%d", len(errors))
+	}
+
+	if errors[0].Message != "could not read input: broken pipe" {
+		t.Errorf("wrong error message. got=%q", errors[0].Message)
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading comment
+let x = 10 / 2; // trailing
+/* block /* nested */ still comment */ x
+/* unterminated /* */`
+
+	tests := []struct {
+		expectedType     token.TokenType
+		expectedLiteral  string
+		expectedComments []token.Comment
+	}{
+		{token.LET, "let", []token.Comment{{Text: "// leading comment", Line: 1, Column: 1}}},
+		{token.IDENTIFIER, "x", nil},
+		{token.ASSIGN, "=", nil},
+		{token.INT, "10", nil},
+		{token.SLASH, "/", nil},
+		{token.INT, "2", nil},
+		{token.SEMICOLON, ";", nil},
+		{token.IDENTIFIER, "x", []token.Comment{
+			{Text: "// trailing", Line: 2, Column: 17},
+			{Text: "/* block /* nested */ still comment */", Line: 3, Column: 1},
+		}},
+		{token.EOF, "", []token.Comment{{Text: "/* unterminated /* */", Line: 4, Column: 1}}},
+	}
+
+	for _, keep := range []bool{false, true} {
+		l := New(input)
+		l.KeepComments(keep)
+
+		for i, tt := range tests {
+			tok := l.NextToken()
+
+			if tok.Type != tt.expectedType {
+				t.Fatalf("keep=%t tests[%d] - tokentype wrong. expected=%q, got=%q", keep, i, tt.expectedType, tok.Type)
+			}
+
+			if tok.Literal != tt.expectedLiteral {
+				t.Fatalf("keep=%t tests[%d] - literal wrong. expected=%q, got=%q", keep, i, tt.expectedLiteral, tok.Literal)
+			}
+
+			expectedComments := tt.expectedComments
+			if !keep {
+				expectedComments = nil
+			}
+
+			if !reflect.DeepEqual(tok.Comments, expectedComments) {
+				t.Fatalf("keep=%t tests[%d] - comments wrong. expected=%+v, got=%+v", keep, i, expectedComments, tok.Comments)
+			}
+		}
+
+		errors := l.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("keep=%t - wrong number of errors. expected=1, got=%d", keep, len(errors))
+		}
+
+		if errors[0].Error() != "line 4, column 1: unterminated block comment" {
+			t.Errorf("keep=%t - wrong error. got=%q", keep, errors[0].Error())
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ErrInterrupted is returned by ReadLine when Ctrl-C is pressed
+var ErrInterrupted = errors.New("lineedit: interrupted")
+
+// Editor reads lines from a terminal, with cursor movement, history and tab completion.  While a line is being read the terminal is put in raw mode, so the editor sees every key as it is pressed
+// an Editor reading from anything other than a terminal reads the keys from it all the same, without changing any terminal modes
+type Editor struct {
+	// History holds the lines that can be recalled with the up and down keys and searched with Ctrl-R
+	History *History
+
+	// Complete returns the candidates for completing word, the word before the cursor when tab is pressed.  candidates that do not start with word are ignored
+	Complete func(word string) []string
+
+	// Highlight returns the line being edited as it should be shown, such as with syntax highlighting.  it may only add escape sequences, never change the text itself
+	Highlight func(line string) string
+
+	in       *bufio.Reader
+	out      io.Writer
+	fd       int
+	terminal bool
+}
+
+// New creates an editor that reads keys from in and draws the line being edited on out
+func New(in io.Reader, out io.Writer) *Editor {
+	e := &Editor{
+		History: NewHistory(DefaultHistorySize),
+		in:      bufio.NewReader(in),
+		out:     out,
+	}
+
+	if f, ok := in.(*os.File); ok && IsTerminal(int(f.Fd())) {
+		e.fd = int(f.Fd())
+		e.terminal = true
+	}
+
+	return e
+}
+
+// state is the line being edited
+type state struct {
+	prompt string
+	buf    []rune
+	pos    int
+
+	// history is the index of the history entry being shown, or len(entries) for the line being typed, which is kept in saved while the history is browsed
+	history int
+	saved   []rune
+}
+
+func ctrl(r rune) rune {
+	return r & 0x1f
+}
+
+// ReadLine shows prompt and reads a line.  it returns io.EOF when Ctrl-D is pressed on an empty line, or when the input ends, and ErrInterrupted when Ctrl-C is pressed
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if e.terminal {
+		restore, err := makeRaw(e.fd)
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+
+	s := &state{prompt: prompt, history: len(e.History.Entries())}
+	e.refresh(s)
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			if err == io.EOF && len(s.buf) > 0 {
+				e.write("\r\n")
+				return string(s.buf), nil
+			}
+
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			e.write("\r\n")
+			return string(s.buf), nil
+		case ctrl('C'):
+			e.write("^C\r\n")
+			return "", ErrInterrupted
+		case ctrl('D'):
+			if len(s.buf) == 0 {
+				e.write("\r\n")
+				return "", io.EOF
+			}
+
+			s.deleteForward()
+		case ctrl('A'):
+			s.pos = 0
+		case ctrl('E'):
+			s.pos = len(s.buf)
+		case ctrl('B'):
+			s.left()
+		case ctrl('F'):
+			s.right()
+		case ctrl('H'), 127:
+			s.backspace()
+		case ctrl('K'):
+			s.buf = s.buf[:s.pos]
+		case ctrl('U'):
+			s.buf = append([]rune{}, s.buf[s.pos:]...)
+			s.pos = 0
+		case ctrl('W'):
+			s.deleteWord()
+		case ctrl('P'):
+			e.previous(s)
+		case ctrl('N'):
+			e.next(s)
+		case ctrl('R'):
+			if e.search(s) {
+				e.write("\r\n")
+				return string(s.buf), nil
+			}
+		case '\t':
+			e.complete(s)
+		case 27:
+			e.escape(s)
+		default:
+			if unicode.IsPrint(r) {
+				s.insert(r)
+			}
+		}
+
+		e.refresh(s)
+	}
+}
+
+// escape handles an escape sequence, such as the ones sent by the arrow keys
+func (e *Editor) escape(s *state) {
+	next, _, err := e.in.ReadRune()
+	if err != nil || (next != '[' && next != 'O') {
+		return
+	}
+
+	// the sequence is a number of parameters followed by a final letter or ~
+	var params strings.Builder
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return
+		}
+
+		if r >= 0x40 && r <= 0x7e {
+			e.escapeKey(s, r, params.String())
+			return
+		}
+
+		params.WriteRune(r)
+	}
+}
+
+func (e *Editor) escapeKey(s *state, final rune, params string) {
+	switch final {
+	case 'A':
+		e.previous(s)
+	case 'B':
+		e.next(s)
+	case 'C':
+		s.right()
+	case 'D':
+		s.left()
+	case 'H':
+		s.pos = 0
+	case 'F':
+		s.pos = len(s.buf)
+	case '~':
+		switch params {
+		case "1", "7":
+			s.pos = 0
+		case "4", "8":
+			s.pos = len(s.buf)
+		case "3":
+			s.deleteForward()
+		}
+	}
+}
+
+func (s *state) insert(r rune) {
+	s.buf = append(s.buf, 0)
+	copy(s.buf[s.pos+1:], s.buf[s.pos:])
+	s.buf[s.pos] = r
+	s.pos++
+}
+
+func (s *state) insertString(str string) {
+	for _, r := range str {
+		s.insert(r)
+	}
+}
+
+func (s *state) left() {
+	if s.pos > 0 {
+		s.pos--
+	}
+}
+
+func (s *state) right() {
+	if s.pos < len(s.buf) {
+		s.pos++
+	}
+}
+
+func (s *state) backspace() {
+	if s.pos > 0 {
+		s.buf = append(s.buf[:s.pos-1], s.buf[s.pos:]...)
+		s.pos--
+	}
+}
+
+func (s *state) deleteForward() {
+	if s.pos < len(s.buf) {
+		s.buf = append(s.buf[:s.pos], s.buf[s.pos+1:]...)
+	}
+}
+
+// deleteWord deletes the word before the cursor, and the spaces between it and the cursor
+func (s *state) deleteWord() {
+	start := s.pos
+	for start > 0 && unicode.IsSpace(s.buf[start-1]) {
+		start--
+	}
+	for start > 0 && !unicode.IsSpace(s.buf[start-1]) {
+		start--
+	}
+
+	s.buf = append(s.buf[:start], s.buf[s.pos:]...)
+	s.pos = start
+}
+
+func (s *state) set(line string) {
+	s.buf = []rune(line)
+	s.pos = len(s.buf)
+}
+
+// previous shows the history entry before the one being shown
+func (e *Editor) previous(s *state) {
+	entries := e.History.Entries()
+	if s.history == 0 {
+		return
+	}
+
+	if s.history == len(entries) {
+		s.saved = s.buf
+	}
+
+	s.history--
+	s.set(entries[s.history])
+}
+
+// next shows the history entry after the one being shown, or the line that was being typed after the last one
+func (e *Editor) next(s *state) {
+	entries := e.History.Entries()
+	if s.history >= len(entries) {
+		return
+	}
+
+	s.history++
+	if s.history == len(entries) {
+		s.buf = s.saved
+		s.pos = len(s.buf)
+		return
+	}
+
+	s.set(entries[s.history])
+}
+
+// search searches the history for lines that contain what is typed, newest first.  Ctrl-R finds the next older match, Ctrl-G gives up and restores the line.  it reports true when enter is pressed, the line should then be returned right away
+// any other key ends the search with the match in the line, and is then handled as usual
+func (e *Editor) search(s *state) bool {
+	entries := e.History.Entries()
+	original := append([]rune{}, s.buf...)
+	query := []rune{}
+	match := -1
+
+	find := func(from int) int {
+		for i := from; i >= 0; i-- {
+			if strings.Contains(entries[i], string(query)) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for {
+		found := ""
+		if match >= 0 {
+			found = entries[match]
+		}
+		fmt.Fprintf(e.out, "\r(reverse-i-search)`%s': %s\x1b[K", string(query), found)
+
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return false
+		}
+
+		switch {
+		case r == ctrl('R'):
+			if match > 0 {
+				if older := find(match - 1); older >= 0 {
+					match = older
+				}
+			}
+		case r == ctrl('H') || r == 127:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				match = find(len(entries) - 1)
+			}
+		case r == ctrl('G'):
+			s.buf = original
+			s.pos = len(s.buf)
+			return false
+		case r == '\r' || r == '\n':
+			if match >= 0 {
+				s.set(entries[match])
+			}
+			return true
+		case unicode.IsPrint(r):
+			query = append(query, r)
+			match = find(len(entries) - 1)
+		default:
+			if match >= 0 {
+				s.set(entries[match])
+			}
+			e.in.UnreadRune()
+			return false
+		}
+	}
+}
+
+// isWordRune reports whether r can be part of a word that is completed with tab
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == ':'
+}
+
+// complete completes the word before the cursor.  a word with a single candidate is completed in full, otherwise it is completed as far as all the candidates agree, and when that adds nothing the candidates are listed
+func (e *Editor) complete(s *state) {
+	if e.Complete == nil {
+		return
+	}
+
+	start := s.pos
+	for start > 0 && isWordRune(s.buf[start-1]) {
+		start--
+	}
+
+	word := string(s.buf[start:s.pos])
+
+	seen := map[string]bool{}
+	candidates := []string{}
+	for _, c := range e.Complete(word) {
+		if strings.HasPrefix(c, word) && !seen[c] {
+			seen[c] = true
+			candidates = append(candidates, c)
+		}
+	}
+
+	if len(candidates) == 0 {
+		e.write("\a")
+		return
+	}
+
+	sort.Strings(candidates)
+
+	// the prefix is cut a rune at a time, so a multibyte character is never split
+	prefix := []rune(candidates[0])
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, string(prefix)) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	if len(prefix) > len([]rune(word)) {
+		s.insertString(string(prefix[len([]rune(word)):]))
+		return
+	}
+
+	if len(candidates) > 1 {
+		e.write("\r\n" + strings.Join(candidates, "  ") + "\r\n")
+	}
+}
+
+// refresh redraws the line being edited and puts the cursor where it belongs
+func (e *Editor) refresh(s *state) {
+	text := string(s.buf)
+	if e.Highlight != nil {
+		text = e.Highlight(text)
+	}
+
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", s.prompt, text)
+
+	// the cursor moves by columns, which wide characters take two of
+	if back := width(s.buf[s.pos:]); back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+func (e *Editor) write(str string) {
+	io.WriteString(e.out, str)
+}
@@ -0,0 +1,199 @@
+package lineedit
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     string
+		expected string
+	}{
+		{"typing", "let x = 5;\r", "let x = 5;"},
+		{"newline", "abc\n", "abc"},
+		{"backspace", "abd\x7fc\r", "abc"},
+		{"arrows", "ac\x1b[Db\x1b[C!\r", "abc!"},
+		{"ctrl-b and ctrl-f", "ac\x02b\x06!\r", "abc!"},
+		{"home and end", "bc\x1b[Ha\x1b[Fd\r", "abcd"},
+		{"home and end with tilde", "bc\x1b[1~a\x1b[4~d\r", "abcd"},
+		{"ctrl-a and ctrl-e", "bc\x01a\x05d\r", "abcd"},
+		{"delete", "abxc\x1b[D\x1b[D\x1b[3~\r", "abc"},
+		{"ctrl-d deletes under the cursor", "abxc\x1b[D\x1b[D\x04\r", "abc"},
+		{"ctrl-k", "abcdef\x1b[D\x1b[D\x1b[D\x0b\r", "abc"},
+		{"ctrl-u", "xyzabc\x1b[D\x1b[D\x1b[D\x15\r", "abc"},
+		{"ctrl-w", "let x = foo bar  \x17baz\r", "let x = foo baz"},
+		{"unicode", "héllo\x1b[D\x1b[D\x1b[D\x7fe\r", "hello"},
+		{"unknown escape", "a\x1b[5~b\r", "ab"},
+		{"end of input", "abc", "abc"},
+	}
+
+	for _, tt := range tests {
+		e := New(strings.NewReader(tt.keys), io.Discard)
+
+		line, err := e.ReadLine(">>")
+		if err != nil {
+			t.Errorf("%s: ReadLine returned an error: %s", tt.name, err)
+			continue
+		}
+
+		if line != tt.expected {
+			t.Errorf("%s: wrong line. expected=%q, got=%q", tt.name, tt.expected, line)
+		}
+	}
+}
+
+func TestReadLineEnds(t *testing.T) {
+	tests := []struct {
+		keys     string
+		expected error
+	}{
+		{"", io.EOF},
+		{"\x04", io.EOF},
+		{"abc\x03", ErrInterrupted},
+	}
+
+	for _, tt := range tests {
+		e := New(strings.NewReader(tt.keys), io.Discard)
+
+		if _, err := e.ReadLine(">>"); err != tt.expected {
+			t.Errorf("wrong error for %q. expected=%v, got=%v", tt.keys, tt.expected, err)
+		}
+	}
+}
+
+func TestHistoryKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     string
+		expected string
+	}{
+		{"up", "\x1b[A\r", "third"},
+		{"up twice", "\x1b[A\x1b[A\r", "second"},
+		{"up past the oldest", "\x1b[A\x1b[A\x1b[A\x1b[A\r", "first"},
+		{"up and down", "\x1b[A\x1b[A\x1b[B\r", "third"},
+		{"down restores the typed line", "typed\x1b[A\x1b[B\r", "typed"},
+		{"ctrl-p and ctrl-n", "\x10\x10\x0e\r", "third"},
+		{"edit a recalled line", "\x1b[A!\r", "third!"},
+	}
+
+	for _, tt := range tests {
+		e := New(strings.NewReader(tt.keys), io.Discard)
+		for _, line := range []string{"first", "second", "third"} {
+			e.History.Add(line)
+		}
+
+		line, err := e.ReadLine(">>")
+		if err != nil {
+			t.Errorf("%s: ReadLine returned an error: %s", tt.name, err)
+			continue
+		}
+
+		if line != tt.expected {
+			t.Errorf("%s: wrong line. expected=%q, got=%q", tt.name, tt.expected, line)
+		}
+	}
+}
+
+func TestReverseSearch(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     string
+		expected string
+	}{
+		{"enter runs the match", "\x12add\r", "add(3, 4)"},
+		{"ctrl-r finds older matches", "\x12add\x12\r", "let add = fn(x, y) { x + y };"},
+		{"ctrl-r stops at the oldest match", "\x12add\x12\x12\x12\r", "let add = fn(x, y) { x + y };"},
+		{"backspace widens the search", "\x12addx\x7f\r", "add(3, 4)"},
+		{"no match", "\x12nothing\r", ""},
+		{"ctrl-g restores the line", "typed\x12add\x07\r", "typed"},
+		{"other keys edit the match", "\x12let a\x1b[D\x7f\r", "let add = fn(x, y) { x + y ;"},
+	}
+
+	for _, tt := range tests {
+		e := New(strings.NewReader(tt.keys), io.Discard)
+		for _, line := range []string{"let add = fn(x, y) { x + y };", "let z = 1;", "add(3, 4)"} {
+			e.History.Add(line)
+		}
+
+		line, err := e.ReadLine(">>")
+		if err != nil {
+			t.Errorf("%s: ReadLine returned an error: %s", tt.name, err)
+			continue
+		}
+
+		if line != tt.expected {
+			t.Errorf("%s: wrong line. expected=%q, got=%q", tt.name, tt.expected, line)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	tests := []struct {
+		keys     string
+		expected string
+	}{
+		{"abc\x1b[D\x1b[D\r", "\x1b[2D"},
+		{"日本語\x1b[D\x1b[D\r", "\x1b[4D"},
+		{"e\u0301a\x1b[D\x1b[D\r", "\x1b[1D"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		e := New(strings.NewReader(tt.keys), &out)
+
+		if _, err := e.ReadLine(">>"); err != nil {
+			t.Fatalf("ReadLine returned an error for %q: %s", tt.keys, err)
+		}
+
+		// the last redraw is the one before enter is pressed
+		redraws := strings.Split(out.String(), "\r>>")
+		if last := redraws[len(redraws)-1]; !strings.HasSuffix(strings.TrimSuffix(last, "\r\n"), "\x1b[K"+tt.expected) {
+			t.Errorf("wrong cursor movement for %q. expected=%q, got=%q", tt.keys, tt.expected, last)
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	words := []string{"let", "len", "length", "fn", "false", ":help", ":history", "größe", "grün"}
+
+	tests := []struct {
+		name     string
+		keys     string
+		expected string
+		listed   string
+	}{
+		{"single candidate", "fa\t\r", "false", ""},
+		{"common prefix", "le\t\r", "le", "len  length  let"},
+		{"extends to the common prefix", "lengt\t\r", "length", ""},
+		{"word in the middle of a line", "let x = f\tn\r", "let x = fn", "false  fn"},
+		{"completes before the cursor", "fa)\x1b[D\t\r", "false)", ""},
+		{"commands", ":he\t\r", ":help", ""},
+		{"no candidates", "xyz\t\r", "xyz", ""},
+		{"common prefix of multibyte characters", "g\t\t\r", "gr", "größe  grün"},
+		{"multibyte characters", "grö\t\r", "größe", ""},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		e := New(strings.NewReader(tt.keys), &out)
+		e.Complete = func(string) []string { return words }
+
+		line, err := e.ReadLine(">>")
+		if err != nil {
+			t.Errorf("%s: ReadLine returned an error: %s", tt.name, err)
+			continue
+		}
+
+		if line != tt.expected {
+			t.Errorf("%s: wrong line. expected=%q, got=%q", tt.name, tt.expected, line)
+		}
+
+		if tt.listed != "" && !strings.Contains(out.String(), "\r\n"+tt.listed+"\r\n") {
+			t.Errorf("%s: candidates are not listed. expected=%q, got=%q", tt.name, tt.listed, out.String())
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// DefaultHistorySize is how many lines a history keeps by default
+const DefaultHistorySize = 1000
+
+// History is a list of lines that were read before, oldest first.  A history can be kept in a file, so it carries over to the next session
+type History struct {
+	entries []string
+	max     int
+	path    string
+}
+
+// NewHistory creates an empty history that keeps up to max lines
+func NewHistory(max int) *History {
+	return &History{max: max}
+}
+
+// Entries returns the lines in the history, oldest first
+func (h *History) Entries() []string {
+	return h.entries
+}
+
+// Load reads the history kept in the file at path, if it exists, and keeps every line added from now on in that file as well
+func (h *History) Load(path string) error {
+	h.path = path
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lines := 0
+	for scanner.Scan() {
+		h.add(scanner.Text())
+		lines++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// the file only ever grows while lines are added, it is cut back down to size here
+	if lines > h.max {
+		return os.WriteFile(path, []byte(strings.Join(h.entries, "\n")+"\n"), 0600)
+	}
+
+	return nil
+}
+
+// Add adds line to the history, and to the history file if there is one.  blank lines and repeats of the last line are not added
+func (h *History) Add(line string) error {
+	if !h.add(line) || h.path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.WriteString(line + "\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func (h *History) add(line string) bool {
+	if strings.TrimSpace(line) == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == line) {
+		return false
+	}
+
+	h.entries = append(h.entries, line)
+	if len(h.entries) > h.max {
+		h.entries = h.entries[len(h.entries)-h.max:]
+	}
+
+	return true
+}
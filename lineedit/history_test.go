@@ -0,0 +1,56 @@
+package lineedit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	h := NewHistory(3)
+
+	for _, line := range []string{"a", "", "  ", "b", "b", "c", "d"} {
+		h.Add(line)
+	}
+
+	expected := []string{"b", "c", "d"}
+	if !reflect.DeepEqual(h.Entries(), expected) {
+		t.Errorf("wrong entries. expected=%q, got=%q", expected, h.Entries())
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	h := NewHistory(3)
+	if err := h.Load(path); err != nil {
+		t.Fatalf("loading a missing history file returned an error: %s", err)
+	}
+
+	for _, line := range []string{"a", "b", "c", "d"} {
+		if err := h.Add(line); err != nil {
+			t.Fatalf("Add returned an error: %s", err)
+		}
+	}
+
+	loaded := NewHistory(3)
+	if err := loaded.Load(path); err != nil {
+		t.Fatalf("Load returned an error: %s", err)
+	}
+
+	expected := []string{"b", "c", "d"}
+	if !reflect.DeepEqual(loaded.Entries(), expected) {
+		t.Errorf("wrong entries loaded. expected=%q, got=%q", expected, loaded.Entries())
+	}
+
+	// loading cuts the file back down to size
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading the history file: %s", err)
+	}
+
+	if string(data) != "b\nc\nd\n" {
+		t.Errorf("history file is not trimmed. got=%q", string(data))
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package lineedit
+
+import "errors"
+
+// IsTerminal reports whether fd is a terminal.  terminals are only supported on unix systems, everywhere else there are none
+func IsTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (restore func(), err error) {
+	return nil, errors.New("lineedit: terminals are not supported on this system")
+}
+
+// Width returns the number of columns of the terminal fd, or 0 when it is not known
+func Width(fd int) int {
+	return 0
+}
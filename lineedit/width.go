@@ -0,0 +1,46 @@
+package lineedit
+
+import "unicode"
+
+// wide are the ranges of runes that take up two columns in a terminal: the East Asian wide and fullwidth characters, and emoji
+var wide = []struct{ first, last rune }{
+	{0x1100, 0x115f},
+	{0x2e80, 0x303e},
+	{0x3041, 0x33ff},
+	{0x3400, 0x4dbf},
+	{0x4e00, 0x9fff},
+	{0xa000, 0xa4cf},
+	{0xac00, 0xd7a3},
+	{0xf900, 0xfaff},
+	{0xfe30, 0xfe4f},
+	{0xff00, 0xff60},
+	{0xffe0, 0xffe6},
+	{0x1f300, 0x1f64f},
+	{0x1f900, 0x1f9ff},
+	{0x20000, 0x3fffd},
+}
+
+// runeWidth returns the number of columns r takes up in a terminal: none for combining marks and other characters that are not shown on their own, two for wide characters, and one otherwise
+func runeWidth(r rune) int {
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+
+	for _, w := range wide {
+		if r >= w.first && r <= w.last {
+			return 2
+		}
+	}
+
+	return 1
+}
+
+// width returns the number of columns runes take up in a terminal
+func width(runes []rune) int {
+	n := 0
+	for _, r := range runes {
+		n += runeWidth(r)
+	}
+
+	return n
+}
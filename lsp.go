@@ -0,0 +1,23 @@
+package main
+
+import (
+	"akdjr/monkey/lsp"
+	"fmt"
+	"os"
+)
+
+// lspCommand implements "monkey lsp" and returns the process exit code
+// the language server talks to the editor over stdin and stdout, so it has no arguments of its own
+func lspCommand(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: monkey lsp")
+		return 2
+	}
+
+	if err := lsp.New(os.Stdin, os.Stdout).Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,261 @@
+package lsp
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/token"
+	"reflect"
+	"sort"
+)
+
+// symbolKind is the kind of thing that binds a name
+type symbolKind int
+
+const (
+	letSymbol symbolKind = iota
+	parameterSymbol
+)
+
+// symbol is a name bound by a let statement or a function or macro parameter
+type symbol struct {
+	name  string
+	kind  symbolKind
+	ident *ast.Identifier
+
+	// let is the let statement of a let symbol, function the function or macro literal a parameter belongs to
+	let      *ast.LetStatement
+	function ast.Expression
+
+	scope *scope
+	order int
+}
+
+// scope is where names are bound: the program, or the body of a function or macro.  if and else blocks do not have scopes of their own, the same as when they are evaluated
+type scope struct {
+	parent   *scope
+	function ast.Expression
+	symbols  map[string][]*symbol
+
+	// start and end are the first and last positions in the scope, as lines and columns in runes.  the program scope covers everything
+	start, end [2]int
+}
+
+// use is an identifier that refers to a symbol, yet to be resolved
+type use struct {
+	ident *ast.Identifier
+	scope *scope
+	order int
+}
+
+// analysis finds which symbol each identifier in a program refers to
+// an identifier refers to the last binding of its name made before it in the innermost scope that binds the name at all, or to the first one made after it when there is none before, as a function can call a function defined after it
+type analysis struct {
+	global  *scope
+	scopes  []*scope
+	symbols []*symbol
+
+	// idents has every identifier in the program in the order they were found, and refers the ones that are bound to their symbol.  unbound identifiers are builtins or undefined
+	idents []*ast.Identifier
+	refers map[*ast.Identifier]*symbol
+
+	uses  []use
+	order int
+}
+
+func analyze(program *ast.Program) *analysis {
+	a := &analysis{refers: map[*ast.Identifier]*symbol{}}
+	a.global = a.newScope(nil, nil, [2]int{0, 0}, [2]int{maxInt, maxInt})
+
+	a.visit(program, a.global)
+
+	for _, u := range a.uses {
+		if sym := a.resolve(u); sym != nil {
+			a.refers[u.ident] = sym
+		}
+	}
+
+	return a
+}
+
+const maxInt = int(^uint(0) >> 1)
+
+func (a *analysis) newScope(parent *scope, function ast.Expression, start [2]int, end [2]int) *scope {
+	s := &scope{parent: parent, function: function, symbols: map[string][]*symbol{}, start: start, end: end}
+	a.scopes = append(a.scopes, s)
+	return s
+}
+
+func (a *analysis) define(s *scope, ident *ast.Identifier, kind symbolKind, let *ast.LetStatement, function ast.Expression) {
+	a.order++
+	sym := &symbol{name: ident.Value, kind: kind, ident: ident, let: let, function: function, scope: s, order: a.order}
+
+	s.symbols[sym.name] = append(s.symbols[sym.name], sym)
+	a.symbols = append(a.symbols, sym)
+	a.idents = append(a.idents, ident)
+	a.refers[ident] = sym
+}
+
+func (a *analysis) resolve(u use) *symbol {
+	for s := u.scope; s != nil; s = s.parent {
+		symbols := s.symbols[u.ident.Value]
+		if len(symbols) == 0 {
+			continue
+		}
+
+		found := symbols[0]
+		for _, sym := range symbols {
+			if sym.order < u.order {
+				found = sym
+			}
+		}
+
+		return found
+	}
+
+	return nil
+}
+
+// visit visits node in scope s.  the value of a let statement is visited before its name is bound, so in let x = x + 1 the second x refers to an earlier x
+func (a *analysis) visit(node ast.Node, s *scope) {
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return
+	}
+
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, stmt := range node.Statements {
+			a.visit(stmt, s)
+		}
+	case *ast.BlockStatement:
+		for _, stmt := range node.Statements {
+			a.visit(stmt, s)
+		}
+	case *ast.LetStatement:
+		a.visit(node.Value, s)
+		if node.Name != nil {
+			a.define(s, node.Name, letSymbol, node, nil)
+		}
+	case *ast.ReturnStatement:
+		a.visit(node.ReturnValue, s)
+	case *ast.ExpressionStatement:
+		a.visit(node.Expression, s)
+	case *ast.Identifier:
+		a.order++
+		a.idents = append(a.idents, node)
+		a.uses = append(a.uses, use{ident: node, scope: s, order: a.order})
+	case *ast.PrefixExpression:
+		a.visit(node.Right, s)
+	case *ast.InfixExpression:
+		a.visit(node.Left, s)
+		a.visit(node.Right, s)
+	case *ast.IfExpression:
+		a.visit(node.Condition, s)
+		a.visit(node.Consequence, s)
+		a.visit(node.Alternative, s)
+	case *ast.FunctionLiteral:
+		a.visitFunction(node, node.Token, node.Parameters, node.Body, s)
+	case *ast.MacroLiteral:
+		a.visitFunction(node, node.Token, node.Parameters, node.Body, s)
+	case *ast.SpawnExpression:
+		a.visit(node.Function, s)
+	case *ast.CallExpression:
+		a.visit(node.Function, s)
+		for _, arg := range node.Arguments {
+			a.visit(arg, s)
+		}
+	}
+}
+
+// visitFunction visits a function or macro literal, binding its parameters in a new scope for its body
+func (a *analysis) visitFunction(function ast.Expression, tok token.Token, params []*ast.Identifier, body *ast.BlockStatement, s *scope) {
+	end := [2]int{maxInt, maxInt}
+	if body != nil && body.Rbrace.Line > 0 {
+		end = [2]int{body.Rbrace.Line, body.Rbrace.Column}
+	}
+
+	inner := a.newScope(s, function, [2]int{tok.Line, tok.Column}, end)
+
+	for _, param := range params {
+		if param != nil {
+			a.define(inner, param, parameterSymbol, nil, function)
+		}
+	}
+
+	if body != nil {
+		a.visit(body, inner)
+	}
+}
+
+// identAt returns the identifier at line and column, counted in runes from 1.  a position just after an identifier counts as on it, the same as a cursor at the end of a word
+func (a *analysis) identAt(line int, column int) *ast.Identifier {
+	for _, ident := range a.idents {
+		start := ident.Token.Column
+		end := start + len([]rune(ident.Value))
+
+		if ident.Token.Line == line && column >= start && column <= end {
+			return ident
+		}
+	}
+
+	return nil
+}
+
+// references returns every identifier that refers to sym, in source order, its own definition included
+func (a *analysis) references(sym *symbol) []*ast.Identifier {
+	idents := []*ast.Identifier{}
+	for _, ident := range a.idents {
+		if a.refers[ident] == sym {
+			idents = append(idents, ident)
+		}
+	}
+
+	sortIdents(idents)
+	return idents
+}
+
+// scopeAt returns the innermost scope that contains line and column
+func (a *analysis) scopeAt(line int, column int) *scope {
+	found := a.global
+	at := [2]int{line, column}
+
+	for _, s := range a.scopes {
+		if before(s.start, at) && before(at, s.end) && s.contains(found) {
+			found = s
+		}
+	}
+
+	return found
+}
+
+// contains reports whether other is s or one of the scopes s is nested in
+func (s *scope) contains(other *scope) bool {
+	for ; s != nil; s = s.parent {
+		if s == other {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scopeOf returns the scope of the body of function, or nil
+func (a *analysis) scopeOf(function ast.Expression) *scope {
+	for _, s := range a.scopes {
+		if s.function == function {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// before reports whether position p is at or before q
+func before(p [2]int, q [2]int) bool {
+	return p[0] < q[0] || (p[0] == q[0] && p[1] <= q[1])
+}
+
+func sortIdents(idents []*ast.Identifier) {
+	sort.Slice(idents, func(i, j int) bool {
+		p, q := idents[i].Token, idents[j].Token
+		return p.Line < q.Line || (p.Line == q.Line && p.Column < q.Column)
+	})
+}
@@ -0,0 +1,409 @@
+package lsp
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/evaluator"
+	"akdjr/monkey/format"
+	"akdjr/monkey/interpreter"
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/parser"
+	"akdjr/monkey/tester"
+	"akdjr/monkey/token"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// document is an open document, parsed and analyzed every time it changes
+type document struct {
+	uri      string
+	text     string
+	lines    []string
+	program  *ast.Program
+	errors   []*parser.Error
+	analysis *analysis
+}
+
+func newDocument(uri string, text string) *document {
+	p := parser.New(lexer.New(text))
+	program := p.ParseProgram()
+
+	return &document{
+		uri:      uri,
+		text:     text,
+		lines:    strings.Split(text, "\n"),
+		program:  program,
+		errors:   p.ErrorList(),
+		analysis: analyze(program),
+	}
+}
+
+// position converts a line and column counted in runes from 1, as the lexer counts them, into a protocol position
+func (d *document) position(line int, column int) Position {
+	if line < 1 {
+		return Position{}
+	}
+
+	if line > len(d.lines) {
+		return d.end()
+	}
+
+	runes := []rune(d.lines[line-1])
+	if column-1 > len(runes) {
+		column = len(runes) + 1
+	}
+	if column < 1 {
+		column = 1
+	}
+
+	return Position{Line: line - 1, Character: len(utf16.Encode(runes[:column-1]))}
+}
+
+// location converts a protocol position into a line and column counted in runes from 1
+func (d *document) location(pos Position) (line int, column int) {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return pos.Line + 1, pos.Character + 1
+	}
+
+	units := 0
+	column = 1
+	for _, r := range d.lines[pos.Line] {
+		if units >= pos.Character {
+			break
+		}
+
+		units += len(utf16.Encode([]rune{r}))
+		column++
+	}
+
+	return pos.Line + 1, column
+}
+
+// offset converts a protocol position into a byte offset in the text
+func (d *document) offset(pos Position) int {
+	line, column := d.location(pos)
+	if line > len(d.lines) {
+		return len(d.text)
+	}
+
+	offset := 0
+	for _, l := range d.lines[:line-1] {
+		offset += len(l) + 1
+	}
+
+	runes := []rune(d.lines[line-1])
+	if column-1 > len(runes) {
+		column = len(runes) + 1
+	}
+
+	return offset + len(string(runes[:column-1]))
+}
+
+// end returns the position at the end of the document
+func (d *document) end() Position {
+	last := d.lines[len(d.lines)-1]
+	return Position{Line: len(d.lines) - 1, Character: len(utf16.Encode([]rune(last)))}
+}
+
+// identRange returns the range of an identifier
+func (d *document) identRange(ident *ast.Identifier) Range {
+	return Range{
+		Start: d.position(ident.Token.Line, ident.Token.Column),
+		End:   d.position(ident.Token.Line, ident.Token.Column+len([]rune(ident.Value))),
+	}
+}
+
+// nodeRange returns the range from the first token of node to the end of its last token
+func (d *document) nodeRange(node ast.Node, start token.Token) Range {
+	end := start
+	ast.Inspect(node, func(n ast.Node) bool {
+		for _, tok := range tokensOf(n) {
+			if tok.Line > end.Line || (tok.Line == end.Line && tok.Column > end.Column) {
+				end = tok
+			}
+		}
+		return true
+	})
+
+	return Range{
+		Start: d.position(start.Line, start.Column),
+		End:   d.position(end.Line, end.Column+len([]rune(end.Literal))),
+	}
+}
+
+// tokensOf returns the tokens a node holds itself, not those of the nodes inside it
+func tokensOf(node ast.Node) []token.Token {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		return []token.Token{node.Token}
+	case *ast.ReturnStatement:
+		return []token.Token{node.Token}
+	case *ast.ExpressionStatement:
+		return []token.Token{node.Token}
+	case *ast.BlockStatement:
+		return []token.Token{node.Token, node.Rbrace}
+	case *ast.Identifier:
+		return []token.Token{node.Token}
+	case *ast.IntegerLiteral:
+		return []token.Token{node.Token}
+	case *ast.Boolean:
+		return []token.Token{node.Token}
+	case *ast.PrefixExpression:
+		return []token.Token{node.Token}
+	case *ast.InfixExpression:
+		return []token.Token{node.Token}
+	case *ast.IfExpression:
+		return []token.Token{node.Token}
+	case *ast.FunctionLiteral:
+		return []token.Token{node.Token}
+	case *ast.MacroLiteral:
+		return []token.Token{node.Token}
+	case *ast.SpawnExpression:
+		return []token.Token{node.Token}
+	case *ast.CallExpression:
+		return []token.Token{node.Token}
+	}
+
+	return nil
+}
+
+// diagnostics returns the lexer and parser errors in the document
+func (d *document) diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	for _, err := range d.errors {
+		start := d.position(err.Line, err.Column)
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    Range{Start: start, End: d.position(err.Line, err.Column+1)},
+			Severity: SeverityError,
+			Source:   "monkey",
+			Message:  err.Message,
+		})
+	}
+
+	return diagnostics
+}
+
+// symbolAt returns the identifier at pos and the symbol it refers to, which is nil for builtins and undefined names
+func (d *document) symbolAt(pos Position) (*ast.Identifier, *symbol) {
+	ident := d.analysis.identAt(d.location(pos))
+	if ident == nil {
+		return nil, nil
+	}
+
+	return ident, d.analysis.refers[ident]
+}
+
+// hover describes the binding of the identifier at pos, or returns nil when there is nothing there to describe
+func (d *document) hover(pos Position) *Hover {
+	ident, sym := d.symbolAt(pos)
+	if ident == nil {
+		return nil
+	}
+
+	var code, detail string
+	switch {
+	case sym != nil && sym.kind == letSymbol:
+		code = "let " + sym.name + " = " + d.describe(sym.let.Value)
+	case sym != nil && sym.kind == parameterSymbol:
+		code = sym.name
+		detail = "parameter of " + d.functionName(sym.function)
+	case d.isBuiltin(ident.Value):
+		code = ident.Value
+		detail = "builtin function"
+	default:
+		return nil
+	}
+
+	value := "```monkey\n" + code + "\n```"
+	if detail != "" {
+		value += "\n" + detail
+	}
+
+	r := d.identRange(ident)
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: value}, Range: &r}
+}
+
+// describe returns the source of the value of a let statement.  functions and macros are described by their parameters, their bodies would say too much
+func (d *document) describe(value ast.Expression) string {
+	switch value := value.(type) {
+	case *ast.FunctionLiteral:
+		return "fn(" + joinParameters(value.Parameters) + ")"
+	case *ast.MacroLiteral:
+		return "macro(" + joinParameters(value.Parameters) + ")"
+	case nil:
+		return ""
+	default:
+		return format.Node(value)
+	}
+}
+
+// functionName returns the name a function or macro is bound to by a let statement, or describes it when it is not bound to a name
+func (d *document) functionName(function ast.Expression) string {
+	for _, sym := range d.analysis.symbols {
+		if sym.kind == letSymbol && sym.let.Value == function {
+			return sym.name
+		}
+	}
+
+	return d.describe(function)
+}
+
+func joinParameters(params []*ast.Identifier) string {
+	names := []string{}
+	for _, p := range params {
+		names = append(names, p.Value)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// isBuiltin reports whether name is a builtin of the language or of the interpreter that runs programs
+func (d *document) isBuiltin(name string) bool {
+	for _, b := range d.builtins() {
+		if b == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// builtins returns the names of the builtins, along with the assertions of the test runner when the document is a test file
+func (d *document) builtins() []string {
+	names := append(evaluator.Builtins(), interpreter.Builtins()...)
+	if strings.HasSuffix(d.uri, tester.Suffix) {
+		names = append(names, tester.Builtins()...)
+	}
+
+	return names
+}
+
+// definition returns where the identifier at pos is bound, or nil
+func (d *document) definition(pos Position) *Location {
+	_, sym := d.symbolAt(pos)
+	if sym == nil {
+		return nil
+	}
+
+	return &Location{URI: d.uri, Range: d.identRange(sym.ident)}
+}
+
+// references returns everything that refers to the same binding as the identifier at pos
+func (d *document) references(pos Position, includeDeclaration bool) []Location {
+	locations := []Location{}
+
+	_, sym := d.symbolAt(pos)
+	if sym == nil {
+		return locations
+	}
+
+	for _, ident := range d.analysis.references(sym) {
+		if ident == sym.ident && !includeDeclaration {
+			continue
+		}
+
+		locations = append(locations, Location{URI: d.uri, Range: d.identRange(ident)})
+	}
+
+	return locations
+}
+
+// symbols returns the let bindings of the program, with the bindings made inside functions as their children
+func (d *document) symbols() []DocumentSymbol {
+	return d.scopeSymbols(d.analysis.global)
+}
+
+func (d *document) scopeSymbols(s *scope) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+
+	for _, sym := range d.analysis.symbols {
+		if sym.scope != s || sym.kind != letSymbol {
+			continue
+		}
+
+		ds := DocumentSymbol{
+			Name:           sym.name,
+			Detail:         d.describe(sym.let.Value),
+			Kind:           SymbolVariable,
+			Range:          d.nodeRange(sym.let, sym.let.Token),
+			SelectionRange: d.identRange(sym.ident),
+		}
+
+		switch sym.let.Value.(type) {
+		case *ast.FunctionLiteral, *ast.MacroLiteral:
+			ds.Kind = SymbolFunction
+			if inner := d.analysis.scopeOf(sym.let.Value); inner != nil {
+				if children := d.scopeSymbols(inner); len(children) > 0 {
+					ds.Children = children
+				}
+			}
+		}
+
+		symbols = append(symbols, ds)
+	}
+
+	return symbols
+}
+
+// completions returns the keywords, the builtins and the names bound in the scope at pos
+func (d *document) completions(pos Position) []CompletionItem {
+	items := []CompletionItem{}
+
+	for _, keyword := range token.Keywords() {
+		items = append(items, CompletionItem{Label: keyword, Kind: CompletionKeyword})
+	}
+
+	for _, name := range d.builtins() {
+		items = append(items, CompletionItem{Label: name, Kind: CompletionFunction, Detail: "builtin function"})
+	}
+
+	// names bound in inner scopes hide the same names bound further out
+	seen := map[string]bool{}
+	for s := d.analysis.scopeAt(d.location(pos)); s != nil; s = s.parent {
+		names := []string{}
+		for name := range s.symbols {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			symbols := s.symbols[name]
+			sym := symbols[len(symbols)-1]
+
+			item := CompletionItem{Label: name, Kind: CompletionVariable}
+			if sym.kind == letSymbol {
+				item.Detail = d.describe(sym.let.Value)
+				switch sym.let.Value.(type) {
+				case *ast.FunctionLiteral, *ast.MacroLiteral:
+					item.Kind = CompletionFunction
+				}
+			} else {
+				item.Detail = "parameter of " + d.functionName(sym.function)
+			}
+
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// formatting returns the edit that formats the whole document, or no edits when it is already formatted.  a document that does not parse can not be formatted
+func (d *document) formatting() ([]TextEdit, error) {
+	formatted, err := format.Source([]byte(d.text))
+	if err != nil {
+		return nil, err
+	}
+
+	if string(formatted) == d.text {
+		return []TextEdit{}, nil
+	}
+
+	return []TextEdit{{Range: Range{End: d.end()}, NewText: string(formatted)}}, nil
+}
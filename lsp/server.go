@@ -0,0 +1,263 @@
+package lsp
+
+import (
+	"akdjr/monkey/framing"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrExitWithoutShutdown is returned by Run when the client sends exit without asking the server to shut down first
+var ErrExitWithoutShutdown = errors.New("lsp: exit without shutdown")
+
+// Server is a language server for monkey.  It serves a single client, whose requests it reads from one stream and answers on another
+type Server struct {
+	in  *framing.Reader
+	out *framing.Writer
+
+	documents   map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+// New creates a server that reads the messages of the client from in and writes its own to out
+func New(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:        framing.NewReader(in),
+		out:       framing.NewWriter(out),
+		documents: map[string]*document{},
+	}
+}
+
+// Run serves the client until it sends exit, or until its messages end.  it returns ErrExitWithoutShutdown when the client exits without shutting the server down first, an error reading or writing messages, or nil
+func (s *Server) Run() error {
+	for {
+		data, err := s.in.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			if err := s.respond(json.RawMessage("null"), nil, &ResponseError{Code: CodeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+
+		if msg.ID == nil {
+			if err := s.notification(msg.Method, msg.Params); err != nil {
+				return err
+			}
+			continue
+		}
+
+		result, err := s.request(msg.Method, msg.Params)
+		if err := s.respond(*msg.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+// request handles a request and returns its result
+func (s *Server) request(method string, params json.RawMessage) (interface{}, error) {
+	if method == "initialize" {
+		s.initialized = true
+		return s.initialize(), nil
+	}
+
+	if !s.initialized {
+		return nil, &ResponseError{Code: CodeServerNotInitialized, Message: "the server has not been initialized"}
+	}
+
+	if s.shutdown {
+		return nil, &ResponseError{Code: CodeInvalidRequest, Message: "the server has been shut down"}
+	}
+
+	switch method {
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/hover":
+		var p TextDocumentPositionParams
+		return s.withDocument(params, &p, &p.TextDocument, func(d *document) (interface{}, error) {
+			return d.hover(p.Position), nil
+		})
+	case "textDocument/definition":
+		var p TextDocumentPositionParams
+		return s.withDocument(params, &p, &p.TextDocument, func(d *document) (interface{}, error) {
+			return d.definition(p.Position), nil
+		})
+	case "textDocument/references":
+		var p ReferenceParams
+		return s.withDocument(params, &p, &p.TextDocument, func(d *document) (interface{}, error) {
+			return d.references(p.Position, p.Context.IncludeDeclaration), nil
+		})
+	case "textDocument/documentSymbol":
+		var p DocumentParams
+		return s.withDocument(params, &p, &p.TextDocument, func(d *document) (interface{}, error) {
+			return d.symbols(), nil
+		})
+	case "textDocument/completion":
+		var p TextDocumentPositionParams
+		return s.withDocument(params, &p, &p.TextDocument, func(d *document) (interface{}, error) {
+			return d.completions(p.Position), nil
+		})
+	case "textDocument/formatting":
+		var p DocumentParams
+		return s.withDocument(params, &p, &p.TextDocument, func(d *document) (interface{}, error) {
+			edits, err := d.formatting()
+			if err != nil {
+				return nil, &ResponseError{Code: CodeRequestFailed, Message: err.Error()}
+			}
+			return edits, nil
+		})
+	}
+
+	return nil, &ResponseError{Code: CodeMethodNotFound, Message: fmt.Sprintf("method not found: %s", method)}
+}
+
+// withDocument decodes params into p and calls f with the open document that id names
+func (s *Server) withDocument(params json.RawMessage, p interface{}, id *TextDocumentIdentifier, f func(d *document) (interface{}, error)) (interface{}, error) {
+	if err := json.Unmarshal(params, p); err != nil {
+		return nil, &ResponseError{Code: CodeInvalidParams, Message: err.Error()}
+	}
+
+	d, ok := s.documents[id.URI]
+	if !ok {
+		return nil, &ResponseError{Code: CodeInvalidParams, Message: fmt.Sprintf("document is not open: %s", id.URI)}
+	}
+
+	return f(d)
+}
+
+func (s *Server) initialize() *InitializeResult {
+	result := &InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:           textDocumentSyncFull,
+			HoverProvider:              true,
+			DefinitionProvider:         true,
+			ReferencesProvider:         true,
+			DocumentSymbolProvider:     true,
+			CompletionProvider:         &CompletionOptions{},
+			DocumentFormattingProvider: true,
+		},
+	}
+	result.ServerInfo.Name = "monkey"
+
+	return result
+}
+
+// notification handles a notification.  notifications the server does not know are ignored, as the protocol asks.  only an error writing to the client is returned
+func (s *Server) notification(method string, params json.RawMessage) error {
+	if !s.initialized {
+		return nil
+	}
+
+	switch method {
+	case "textDocument/didOpen":
+		var p DidOpenTextDocumentParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil
+		}
+
+		return s.update(p.TextDocument.URI, p.TextDocument.Text)
+	case "textDocument/didChange":
+		var p DidChangeTextDocumentParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil
+		}
+
+		d, ok := s.documents[p.TextDocument.URI]
+		if !ok {
+			return nil
+		}
+
+		// changes are applied one after another, each to the text left by the one before
+		text := d.text
+		for _, change := range p.ContentChanges {
+			if change.Range == nil {
+				text = change.Text
+				continue
+			}
+
+			current := newDocument(d.uri, text)
+			start, end := current.offset(change.Range.Start), current.offset(change.Range.End)
+			if start > end {
+				start, end = end, start
+			}
+			text = text[:start] + change.Text + text[end:]
+		}
+
+		return s.update(p.TextDocument.URI, text)
+	case "textDocument/didClose":
+		var p DidCloseTextDocumentParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil
+		}
+
+		delete(s.documents, p.TextDocument.URI)
+		return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+	}
+
+	return nil
+}
+
+// update replaces the text of a document and publishes its diagnostics
+func (s *Server) update(uri string, text string) error {
+	d := newDocument(uri, text)
+	s.documents[uri] = d
+
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: d.diagnostics()})
+}
+
+// respond answers the request with id, with either its result or the error it failed with
+func (s *Server) respond(id json.RawMessage, result interface{}, err error) error {
+	msg := message{JSONRPC: "2.0", ID: &id}
+
+	if err != nil {
+		var responseErr *ResponseError
+		if !errors.As(err, &responseErr) {
+			responseErr = &ResponseError{Code: CodeInternalError, Message: err.Error()}
+		}
+		msg.Error = responseErr
+	} else {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		msg.Result = data
+	}
+
+	return s.send(msg)
+}
+
+// notify sends a notification to the client
+func (s *Server) notify(method string, params interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	return s.send(message{JSONRPC: "2.0", Method: method, Params: data})
+}
+
+func (s *Server) send(msg message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return s.out.Write(data)
+}
@@ -0,0 +1,510 @@
+package lsp
+
+import (
+	"akdjr/monkey/framing"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// client is a fake editor that talks to a server running in the same process
+type client struct {
+	t        *testing.T
+	w        *framing.Writer
+	in       io.WriteCloser
+	messages chan message
+	done     chan error
+	nextID   int
+
+	// notifications are the notifications received while waiting for responses
+	notifications []message
+}
+
+func newClient(t *testing.T, initialize bool) *client {
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+
+	c := &client{
+		t:        t,
+		w:        framing.NewWriter(clientOut),
+		in:       clientOut,
+		messages: make(chan message, 100),
+		done:     make(chan error, 1),
+	}
+
+	go func() {
+		c.done <- New(serverIn, serverOut).Run()
+		serverOut.Close()
+	}()
+
+	go func() {
+		r := framing.NewReader(clientIn)
+		for {
+			data, err := r.Read()
+			if err != nil {
+				close(c.messages)
+				return
+			}
+
+			var msg message
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Errorf("the server sent invalid JSON: %s", data)
+			}
+			c.messages <- msg
+		}
+	}()
+
+	t.Cleanup(func() { clientOut.Close() })
+
+	if initialize {
+		var result InitializeResult
+		if err := c.call("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}}, &result); err != nil {
+			t.Fatalf("initialize failed: %s", err)
+		}
+		c.notify("initialized", map[string]interface{}{})
+	}
+
+	return c
+}
+
+func (c *client) send(msg message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatalf("encoding a message: %s", err)
+	}
+
+	if err := c.w.Write(data); err != nil {
+		c.t.Fatalf("writing a message: %s", err)
+	}
+}
+
+func (c *client) notify(method string, params interface{}) {
+	data, _ := json.Marshal(params)
+	c.send(message{JSONRPC: "2.0", Method: method, Params: data})
+}
+
+// call sends a request and decodes its result into result, or returns the error it failed with
+func (c *client) call(method string, params interface{}, result interface{}) *ResponseError {
+	c.nextID++
+	id := json.RawMessage(strings.TrimSpace(string(mustMarshal(c.nextID))))
+
+	data, _ := json.Marshal(params)
+	c.send(message{JSONRPC: "2.0", ID: &id, Method: method, Params: data})
+
+	for {
+		msg := c.receive()
+		if msg.ID == nil {
+			c.notifications = append(c.notifications, msg)
+			continue
+		}
+
+		if string(*msg.ID) != string(id) {
+			c.t.Fatalf("response to the wrong request. expected=%s, got=%s", id, *msg.ID)
+		}
+
+		if msg.Error != nil {
+			return msg.Error
+		}
+
+		if result != nil {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				c.t.Fatalf("decoding the result of %s: %s", method, err)
+			}
+		}
+
+		return nil
+	}
+}
+
+func (c *client) receive() message {
+	select {
+	case msg, ok := <-c.messages:
+		if !ok {
+			c.t.Fatalf("the server closed the connection")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatalf("timed out waiting for the server")
+	}
+
+	return message{}
+}
+
+// diagnostics returns the diagnostics published next for uri
+func (c *client) diagnostics(uri string) []Diagnostic {
+	for {
+		var msg message
+		if len(c.notifications) > 0 {
+			msg, c.notifications = c.notifications[0], c.notifications[1:]
+		} else {
+			msg = c.receive()
+		}
+
+		if msg.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+
+		var params PublishDiagnosticsParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			c.t.Fatalf("decoding diagnostics: %s", err)
+		}
+
+		if params.URI == uri {
+			return params.Diagnostics
+		}
+	}
+}
+
+func (c *client) open(uri string, text string) []Diagnostic {
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uri, LanguageID: "monkey", Version: 1, Text: text}})
+	return c.diagnostics(uri)
+}
+
+func mustMarshal(v interface{}) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+const uri = "file:///test.monkey"
+
+const source = `let add = fn(x, y) {
+    let sum = x + y;
+    sum
+};
+let answer = add(20, 22);
+let answer = answer + 1;
+puts(answer);
+`
+
+func at(line int, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: line, Character: character}}
+}
+
+func span(line int, start int, end int) Range {
+	return Range{Start: Position{Line: line, Character: start}, End: Position{Line: line, Character: end}}
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := newClient(t, true)
+
+	if diagnostics := c.open(uri, source); len(diagnostics) != 0 {
+		t.Errorf("expected no diagnostics. got=%+v", diagnostics)
+	}
+
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "let x = 1;\nlet = 5;"}},
+	})
+
+	expected := []Diagnostic{
+		{Range: span(1, 4, 5), Severity: SeverityError, Source: "monkey", Message: "expected next token to be IDENTIFIER, got ASSIGN instead"},
+		{Range: span(1, 4, 5), Severity: SeverityError, Source: "monkey", Message: "no prefix parse function for 'ASSIGN' found"},
+	}
+	if diagnostics := c.diagnostics(uri); !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("wrong diagnostics.\nexpected=%+v\ngot=%+v", expected, diagnostics)
+	}
+
+	// a change to part of the document
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: 3},
+		ContentChanges: []TextDocumentContentChangeEvent{{Range: &Range{Start: Position{Line: 1, Character: 4}, End: Position{Line: 1, Character: 4}}, Text: "y "}},
+	})
+
+	if diagnostics := c.diagnostics(uri); len(diagnostics) != 0 {
+		t.Errorf("expected no diagnostics after fixing the error. got=%+v", diagnostics)
+	}
+
+	c.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+	if diagnostics := c.diagnostics(uri); len(diagnostics) != 0 {
+		t.Errorf("expected the diagnostics to be cleared on close. got=%+v", diagnostics)
+	}
+
+	var hover *Hover
+	if err := c.call("textDocument/hover", at(0, 0), &hover); err == nil || err.Code != CodeInvalidParams {
+		t.Errorf("expected an error for a closed document. got=%v", err)
+	}
+}
+
+func TestHover(t *testing.T) {
+	c := newClient(t, true)
+	c.open(uri, source)
+
+	tests := []struct {
+		position TextDocumentPositionParams
+		expected string
+		r        Range
+	}{
+		{at(4, 13), "```monkey\nlet add = fn(x, y)\n```", span(4, 13, 16)},
+		{at(4, 16), "```monkey\nlet add = fn(x, y)\n```", span(4, 13, 16)},
+		{at(1, 14), "```monkey\nx\n```\nparameter of add", span(1, 14, 15)},
+		{at(2, 5), "```monkey\nlet sum = x + y\n```", span(2, 4, 7)},
+		{at(5, 15), "```monkey\nlet answer = add(20, 22)\n```", span(5, 13, 19)},
+		{at(6, 1), "```monkey\nputs\n```\nbuiltin function", span(6, 0, 4)},
+	}
+
+	for _, tt := range tests {
+		var hover *Hover
+		if err := c.call("textDocument/hover", tt.position, &hover); err != nil {
+			t.Fatalf("hover failed: %s", err)
+		}
+
+		if hover == nil {
+			t.Errorf("no hover at %+v", tt.position.Position)
+			continue
+		}
+
+		if hover.Contents.Value != tt.expected || hover.Contents.Kind != "markdown" {
+			t.Errorf("wrong hover at %+v. expected=%q, got=%+v", tt.position.Position, tt.expected, hover.Contents)
+		}
+
+		if hover.Range == nil || *hover.Range != tt.r {
+			t.Errorf("wrong hover range at %+v. expected=%+v, got=%+v", tt.position.Position, tt.r, hover.Range)
+		}
+	}
+
+	var hover *Hover
+	if err := c.call("textDocument/hover", at(0, 8), &hover); err != nil || hover != nil {
+		t.Errorf("expected no hover on a keyword. got=%+v, %v", hover, err)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	c := newClient(t, true)
+	c.open(uri, source)
+
+	tests := []struct {
+		position TextDocumentPositionParams
+		expected *Location
+	}{
+		{at(2, 4), &Location{URI: uri, Range: span(1, 8, 11)}},
+		{at(1, 18), &Location{URI: uri, Range: span(0, 16, 17)}},
+		{at(4, 14), &Location{URI: uri, Range: span(0, 4, 7)}},
+		// each answer refers to the one bound before it
+		{at(5, 13), &Location{URI: uri, Range: span(4, 4, 10)}},
+		{at(6, 5), &Location{URI: uri, Range: span(5, 4, 10)}},
+		{at(6, 0), nil},
+		{at(3, 0), nil},
+	}
+
+	for _, tt := range tests {
+		var location *Location
+		if err := c.call("textDocument/definition", tt.position, &location); err != nil {
+			t.Fatalf("definition failed: %s", err)
+		}
+
+		if !reflect.DeepEqual(location, tt.expected) {
+			t.Errorf("wrong definition at %+v. expected=%+v, got=%+v", tt.position.Position, tt.expected, location)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	c := newClient(t, true)
+	c.open(uri, `let f = fn(n) { if (n > 0) { f(n - 1) } else { n } };
+let n = 5;
+f(n);
+`)
+
+	tests := []struct {
+		position    TextDocumentPositionParams
+		declaration bool
+		expected    []Range
+	}{
+		{at(0, 11), true, []Range{span(0, 11, 12), span(0, 20, 21), span(0, 31, 32), span(0, 47, 48)}},
+		{at(0, 20), false, []Range{span(0, 20, 21), span(0, 31, 32), span(0, 47, 48)}},
+		{at(2, 0), true, []Range{span(0, 4, 5), span(0, 29, 30), span(2, 0, 1)}},
+		{at(2, 2), true, []Range{span(1, 4, 5), span(2, 2, 3)}},
+		{at(0, 0), true, []Range{}},
+	}
+
+	for _, tt := range tests {
+		params := ReferenceParams{TextDocumentPositionParams: tt.position}
+		params.Context.IncludeDeclaration = tt.declaration
+
+		var locations []Location
+		if err := c.call("textDocument/references", params, &locations); err != nil {
+			t.Fatalf("references failed: %s", err)
+		}
+
+		ranges := []Range{}
+		for _, l := range locations {
+			if l.URI != uri {
+				t.Errorf("reference in the wrong document: %s", l.URI)
+			}
+			ranges = append(ranges, l.Range)
+		}
+
+		if !reflect.DeepEqual(ranges, tt.expected) {
+			t.Errorf("wrong references at %+v.\nexpected=%+v\ngot=%+v", tt.position.Position, tt.expected, ranges)
+		}
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	c := newClient(t, true)
+	c.open(uri, source)
+
+	var symbols []DocumentSymbol
+	if err := c.call("textDocument/documentSymbol", DocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}}, &symbols); err != nil {
+		t.Fatalf("documentSymbol failed: %s", err)
+	}
+
+	expected := []DocumentSymbol{
+		{
+			Name: "add", Detail: "fn(x, y)", Kind: SymbolFunction,
+			Range:          Range{Start: Position{Line: 0, Character: 0}, End: Position{Line: 3, Character: 1}},
+			SelectionRange: span(0, 4, 7),
+			Children: []DocumentSymbol{
+				{Name: "sum", Detail: "x + y", Kind: SymbolVariable, Range: span(1, 4, 19), SelectionRange: span(1, 8, 11)},
+			},
+		},
+		{Name: "answer", Detail: "add(20, 22)", Kind: SymbolVariable, Range: span(4, 0, 23), SelectionRange: span(4, 4, 10)},
+		{Name: "answer", Detail: "answer + 1", Kind: SymbolVariable, Range: span(5, 0, 23), SelectionRange: span(5, 4, 10)},
+	}
+
+	if !reflect.DeepEqual(symbols, expected) {
+		t.Errorf("wrong symbols.\nexpected=%+v\ngot=%+v", expected, symbols)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c := newClient(t, true)
+	c.open(uri, source)
+
+	labels := func(position TextDocumentPositionParams) map[string]CompletionItem {
+		var items []CompletionItem
+		if err := c.call("textDocument/completion", position, &items); err != nil {
+			t.Fatalf("completion failed: %s", err)
+		}
+
+		found := map[string]CompletionItem{}
+		for _, item := range items {
+			found[item.Label] = item
+		}
+		return found
+	}
+
+	inside := labels(at(2, 4))
+	for _, name := range []string{"let", "fn", "spawn", "puts", "channel", "add", "answer", "x", "y", "sum"} {
+		if _, ok := inside[name]; !ok {
+			t.Errorf("%s is not completed inside the function", name)
+		}
+	}
+
+	if item := inside["add"]; item.Kind != CompletionFunction || item.Detail != "fn(x, y)" {
+		t.Errorf("wrong completion for add. got=%+v", item)
+	}
+
+	if item := inside["x"]; item.Kind != CompletionVariable || item.Detail != "parameter of add" {
+		t.Errorf("wrong completion for x. got=%+v", item)
+	}
+
+	outside := labels(at(6, 0))
+	for _, name := range []string{"x", "y", "sum"} {
+		if _, ok := outside[name]; ok {
+			t.Errorf("%s is completed outside the function", name)
+		}
+	}
+
+	if _, ok := outside["assert_eq"]; ok {
+		t.Errorf("assert_eq is completed outside of a test file")
+	}
+
+	// the assertions of the test runner are builtins in test files
+	testURI := "file:///add_test.monkey"
+	c.open(testURI, source)
+
+	position := at(6, 0)
+	position.TextDocument.URI = testURI
+
+	if item, ok := labels(position)["assert_eq"]; !ok || item.Detail != "builtin function" {
+		t.Errorf("assert_eq is not completed in a test file. got=%+v", item)
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	c := newClient(t, true)
+	c.open(uri, "let  x=1;\nlet y = fn(a){a*2};")
+
+	var edits []TextEdit
+	if err := c.call("textDocument/formatting", DocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}}, &edits); err != nil {
+		t.Fatalf("formatting failed: %s", err)
+	}
+
+	expected := []TextEdit{{Range: Range{End: Position{Line: 1, Character: 19}}, NewText: "let x = 1;\nlet y = fn(a) {\n    a * 2;\n};\n"}}
+	if !reflect.DeepEqual(edits, expected) {
+		t.Errorf("wrong edits.\nexpected=%+v\ngot=%+v", expected, edits)
+	}
+
+	c.open(uri, "let x = 1;\n")
+	if err := c.call("textDocument/formatting", DocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}}, &edits); err != nil || len(edits) != 0 {
+		t.Errorf("expected no edits for a formatted document. got=%+v, %v", edits, err)
+	}
+
+	c.open(uri, "let = 1;")
+	if err := c.call("textDocument/formatting", DocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}}, &edits); err == nil || err.Code != CodeRequestFailed {
+		t.Errorf("expected formatting a broken document to fail. got=%v", err)
+	}
+}
+
+func TestUTF16Positions(t *testing.T) {
+	c := newClient(t, true)
+
+	// é is one UTF-16 code unit, 😀 is two
+	diagnostics := c.open(uri, "/* é😀 */ let z = 1; z @")
+	if len(diagnostics) == 0 || diagnostics[0].Range != span(0, 23, 24) {
+		t.Errorf("wrong diagnostic range. got=%+v", diagnostics)
+	}
+
+	var location *Location
+	if err := c.call("textDocument/definition", at(0, 21), &location); err != nil {
+		t.Fatalf("definition failed: %s", err)
+	}
+
+	if location == nil || location.Range != span(0, 14, 15) {
+		t.Errorf("wrong definition. got=%+v", location)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	c := newClient(t, false)
+
+	if err := c.call("textDocument/hover", at(0, 0), nil); err == nil || err.Code != CodeServerNotInitialized {
+		t.Errorf("expected an error before initialize. got=%v", err)
+	}
+
+	var result InitializeResult
+	if err := c.call("initialize", map[string]interface{}{}, &result); err != nil {
+		t.Fatalf("initialize failed: %s", err)
+	}
+
+	if result.Capabilities.TextDocumentSync != textDocumentSyncFull || !result.Capabilities.HoverProvider || result.Capabilities.CompletionProvider == nil {
+		t.Errorf("wrong capabilities. got=%+v", result.Capabilities)
+	}
+
+	if err := c.call("textDocument/nonsense", map[string]interface{}{}, nil); err == nil || err.Code != CodeMethodNotFound {
+		t.Errorf("expected method not found. got=%v", err)
+	}
+
+	if err := c.call("shutdown", nil, nil); err != nil {
+		t.Fatalf("shutdown failed: %s", err)
+	}
+
+	if err := c.call("textDocument/hover", at(0, 0), nil); err == nil || err.Code != CodeInvalidRequest {
+		t.Errorf("expected an error after shutdown. got=%v", err)
+	}
+
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("Run returned an error after exit: %s", err)
+	}
+
+	c = newClient(t, true)
+	c.notify("exit", nil)
+	if err := <-c.done; err != ErrExitWithoutShutdown {
+		t.Errorf("expected ErrExitWithoutShutdown. got=%v", err)
+	}
+}
@@ -1,13 +1,93 @@
 package main
 
 import (
+	"akdjr/monkey/coverage"
+	"akdjr/monkey/evaluator"
+	"akdjr/monkey/interpreter"
+	"akdjr/monkey/profiler"
 	"akdjr/monkey/repl"
+	"bytes"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
+	"path/filepath"
+	"strings"
 )
 
+const usage = `usage:
+	monkey [--session file] [--history file]
+	                       start the REPL, or run a program piped to stdin.  with --session, the
+	                       bindings saved in file are loaded at the start and saved back at the end.
+	                       lines typed in are kept in the --history file, ~/.monkey_history by default.
+	                       output is in color unless the NO_COLOR environment variable is set
+	monkey run [--profile out] [--cover out] [file]
+	                       run a program from file, or stdin when file is omitted or "-".  with
+	                       --profile, a profile of where the program spent its time is written to out,
+	                       in the folded stack format when out ends in .folded and for pprof otherwise.
+	                       with --cover, a report of the statements and branches that ran is written to
+	                       out, as HTML when out ends in .html, lcov for .lcov or .info, and text otherwise
+	monkey fmt [-w] [-d] [files...]
+	                       format programs in canonical style
+	monkey ast [--json] [file]
+	                       print the parsed program, or its full AST as JSON
+	monkey lsp             start a language server that talks to an editor over stdin and stdout
+	monkey debug file      run a program in the debugger, stopped before its first statement.
+	                       type help at the (debug) prompt for the commands
+	monkey dap             start a debug adapter that talks to an editor over stdin and stdout
+	monkey test [-v] [--junit out] [--cover out] [paths...]
+	                       run the test_ functions of the *_test.monkey files in paths, the current
+	                       directory by default, each in an environment of its own.  tests check their
+	                       results with assert(cond[, message]), assert_eq(actual, expected[, message])
+	                       and assert_error(fn[, message]).  -v reports the tests that pass too,
+	                       --junit writes a JUnit XML report to out, and --cover a coverage report as
+	                       with run
+`
+
 func main() {
+	args := os.Args[1:]
+
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "run":
+			os.Exit(runCommand(args[1:]))
+		case "fmt":
+			os.Exit(fmtCommand(args[1:]))
+		case "ast":
+			os.Exit(astCommand(args[1:]))
+		case "lsp":
+			os.Exit(lspCommand(args[1:]))
+		case "debug":
+			os.Exit(debugCommand(args[1:]))
+		case "dap":
+			os.Exit(dapCommand(args[1:]))
+		case "test":
+			os.Exit(testCommand(args[1:]))
+		default:
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+	}
+
+	flags := flag.NewFlagSet("monkey", flag.ContinueOnError)
+	session := flags.String("session", "", "load the REPL bindings from `file` and save them back on exit")
+	history := flags.String("history", defaultHistoryFile(), "keep the lines typed in the REPL in `file`")
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		if flags.NArg() > 0 {
+			flags.Usage()
+		}
+		os.Exit(2)
+	}
+
+	// input is being piped in, run it as a program instead of starting an interactive session
+	if !isTerminal(os.Stdin) {
+		os.Exit(execute(os.Stdin, os.Stderr))
+	}
+
 	currentUser, err := user.Current()
 
 	if err != nil {
@@ -16,5 +96,168 @@ func main() {
 
 	fmt.Printf("Hello %s!  This is the Monkey programming language!\n", currentUser.Username)
 	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+	repl.StartSession(os.Stdin, os.Stdout, repl.Options{SessionFile: *session, HistoryFile: *history})
+}
+
+// defaultHistoryFile returns where the REPL history is kept unless --history says otherwise, or "" when there is no home directory to keep it in
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".monkey_history")
+}
+
+// runCommand implements "monkey run [--profile file] [--cover file] [file]" and returns the process exit code
+func runCommand(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	profile := flags.String("profile", "", "write a profile of the program to `file`")
+	cover := flags.String("cover", "", "write a coverage report of the program to `file`")
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+
+	if err := flags.Parse(args); err != nil || flags.NArg() > 1 {
+		if flags.NArg() > 1 {
+			flags.Usage()
+		}
+		return 2
+	}
+
+	path, name := flags.Arg(0), flags.Arg(0)
+	if path == "" || path == "-" {
+		path, name = "", "<standard input>"
+	}
+
+	var in io.Reader = os.Stdin
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer file.Close()
+
+		in = file
+	}
+
+	i := interpreter.New()
+
+	var p *profiler.Profiler
+	if *profile != "" {
+		p = profiler.New(name)
+		i.Hooks = p.Hooks()
+	}
+
+	// coverage is measured against the whole source, so the program is read into memory rather than streamed
+	var c *coverage.Coverage
+	if *cover != "" {
+		source, err := io.ReadAll(in)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		// both follow the evaluation with hooks, the profiler and the coverage each get called in turn
+		c = coverage.New()
+		i.Hooks = evaluator.ChainHooks(i.Hooks, c.Hooks(name, string(source)))
+		in = bytes.NewReader(source)
+	}
+
+	code := executeWith(i, in, os.Stderr)
+
+	if p != nil {
+		p.Stop()
+
+		if err := writeProfile(p, *profile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	if c != nil {
+		if err := writeCoverage(c, *cover); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	return code
+}
+
+// writeProfile writes the profile to path, in the folded stack format when its name ends in .folded and as a pprof profile otherwise
+func writeProfile(p *profiler.Profiler, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(path, ".folded") {
+		err = p.WriteFolded(file)
+	} else {
+		err = p.WritePprof(file)
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// writeCoverage writes the coverage report to path: a page of annotated source when its name ends in .html, an lcov tracefile when it ends in .lcov or .info, and a text report otherwise
+func writeCoverage(c *coverage.Coverage, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	switch filepath.Ext(path) {
+	case ".html":
+		err = c.WriteHTML(file)
+	case ".lcov", ".info":
+		err = c.WriteLcov(file)
+	default:
+		err = c.WriteText(file)
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// execute runs the program read from in.  the lexer streams the input, so it is never read into memory all at once
+// parser errors and runtime errors are written to errOut, and the returned exit code is non-zero when there were any
+func execute(in io.Reader, errOut io.Writer) int {
+	return executeWith(interpreter.New(), in, errOut)
+}
+
+// executeWith runs the program read from in with i, the same as execute
+func executeWith(i *interpreter.Interpreter, in io.Reader, errOut io.Writer) int {
+	i.Stderr = errOut
+
+	_, err := i.RunReader(in)
+
+	var runtimeErr *interpreter.RuntimeError
+	switch {
+	case errors.As(err, &runtimeErr):
+		fmt.Fprintln(errOut, runtimeErr.Object.Inspect())
+		return 1
+	case err != nil:
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+
+	return 0
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a pipe or a regular file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
 }
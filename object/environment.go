@@ -1,9 +1,18 @@
 package object
 
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
 // Environment represents an object environment.  This is where we keep track of all identifiers and their values
+// an environment is safe to use from many goroutines at once.  once it is frozen it can no longer change, and reading from it no longer needs a lock, which makes a frozen environment a cheap base to share between goroutines that each evaluate in an enclosed environment of their own
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	mu     sync.RWMutex
+	store  map[string]Object
+	outer  *Environment
+	frozen atomic.Bool
 }
 
 // NewEnvironment creates an empty environment
@@ -22,7 +31,16 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 
 // Get returns the Object stored at name
 func (e *Environment) Get(name string) (Object, bool) {
-	obj, ok := e.store[name]
+	var obj Object
+	var ok bool
+
+	if e.frozen.Load() {
+		obj, ok = e.store[name]
+	} else {
+		e.mu.RLock()
+		obj, ok = e.store[name]
+		e.mu.RUnlock()
+	}
 
 	if !ok && e.outer != nil {
 		// could not find the object in the current environment, search the outer environments
@@ -32,8 +50,51 @@ func (e *Environment) Get(name string) (Object, bool) {
 	return obj, ok
 }
 
-// Set creates an entry for Object at name
+// Set creates an entry for Object at name.  Setting a name in a frozen environment is a programming error and panics
 func (e *Environment) Set(name string, value Object) Object {
+	if e.frozen.Load() {
+		panic("object: can not set " + name + " in a frozen environment")
+	}
+
+	e.mu.Lock()
 	e.store[name] = value
+	e.mu.Unlock()
+
 	return value
 }
+
+// Freeze makes e and all of its outer environments read-only.  names can still be set in environments enclosed by a frozen environment
+func (e *Environment) Freeze() {
+	for env := e; env != nil; env = env.outer {
+		env.mu.Lock()
+		env.frozen.Store(true)
+		env.mu.Unlock()
+	}
+}
+
+// Frozen reports whether e has been frozen
+func (e *Environment) Frozen() bool {
+	return e.frozen.Load()
+}
+
+// Outer returns the environment that encloses e, or nil
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Names returns the names set in e itself, not in its outer environments, in sorted order
+func (e *Environment) Names() []string {
+	if !e.frozen.Load() {
+		e.mu.RLock()
+		defer e.mu.RUnlock()
+	}
+
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
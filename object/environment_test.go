@@ -0,0 +1,105 @@
+package object
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestEnvironmentFreeze(t *testing.T) {
+	base := NewEnvironment()
+	base.Set("x", &Integer{Value: 1})
+
+	scope := NewEnclosedEnvironment(base)
+	scope.Set("y", &Integer{Value: 2})
+	scope.Freeze()
+
+	if !base.Frozen() || !scope.Frozen() {
+		t.Fatalf("Freeze did not freeze the environment and its outer environment")
+	}
+
+	child := NewEnclosedEnvironment(scope)
+	child.Set("x", &Integer{Value: 3})
+
+	if obj, _ := child.Get("x"); obj.(*Integer).Value != 3 {
+		t.Errorf("child does not shadow x. got=%s", obj.Inspect())
+	}
+
+	if obj, _ := child.Get("y"); obj.(*Integer).Value != 2 {
+		t.Errorf("child does not see y. got=%s", obj.Inspect())
+	}
+
+	if obj, _ := base.Get("x"); obj.(*Integer).Value != 1 {
+		t.Errorf("x changed in the frozen environment. got=%s", obj.Inspect())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Set on a frozen environment did not panic")
+		}
+	}()
+
+	base.Set("z", &Integer{Value: 4})
+}
+
+func TestEnvironmentConcurrentAccess(t *testing.T) {
+	env := NewEnvironment()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+
+		go func(g int) {
+			defer wg.Done()
+
+			for n := 0; n < 100; n++ {
+				name := fmt.Sprintf("%d-%d", g, n)
+				env.Set(name, &Integer{Value: int64(n)})
+
+				if _, ok := env.Get(name); !ok {
+					t.Errorf("%s is not set", name)
+				}
+			}
+		}(g)
+	}
+
+	wg.Wait()
+}
+
+func BenchmarkEnvironmentGet(b *testing.B) {
+	for _, frozen := range []bool{false, true} {
+		b.Run(fmt.Sprintf("frozen=%t", frozen), func(b *testing.B) {
+			base := NewEnvironment()
+			base.Set("x", &Integer{Value: 1})
+
+			if frozen {
+				base.Freeze()
+			}
+
+			b.RunParallel(func(pb *testing.PB) {
+				env := NewEnclosedEnvironment(base)
+
+				for pb.Next() {
+					env.Get("x")
+				}
+			})
+		})
+	}
+}
+
+func TestEnvironmentNames(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("z", &Integer{Value: 1})
+
+	env := NewEnclosedEnvironment(outer)
+	env.Set("b", &Integer{Value: 2})
+	env.Set("a", &Integer{Value: 3})
+
+	if names := env.Names(); len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("wrong names. expected=[a b], got=%v", names)
+	}
+
+	if env.Outer() != outer || outer.Outer() != nil {
+		t.Errorf("wrong outer environments")
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"akdjr/monkey/ast"
 	"bytes"
 	"fmt"
+	"hash/fnv"
+	"sort"
 	"strings"
+	"sync"
 )
 
 // ObjectType represents the type of object
@@ -18,6 +21,14 @@ const (
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
+	QUOTE_OBJ        = "QUOTE"
+	MACRO_OBJ        = "MACRO"
+	STRING_OBJ       = "STRING"
+	ARRAY_OBJ        = "ARRAY"
+	HASH_OBJ         = "HASH"
+	BUILTIN_OBJ      = "BUILTIN"
+	TASK_OBJ         = "TASK"
+	CHANNEL_OBJ      = "CHANNEL"
 )
 
 // Object is an internal representation of a value.  Every value will be wrapped in a struct that fulfills this interface
@@ -91,3 +102,205 @@ func (f *Function) Inspect() string {
 	return out.String()
 }
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+
+// Name returns the name f is bound to in the environment it was defined in, or the outer ones, for tools such as debuggers and profilers to show.  a function that is not bound to a name is described by its parameters, as in fn(x, y)
+func (f *Function) Name() string {
+	for env := f.Env; env != nil; env = env.Outer() {
+		for _, name := range env.Names() {
+			if value, ok := env.Get(name); ok && value == f {
+				return name
+			}
+		}
+	}
+
+	params := make([]string, 0, len(f.Parameters))
+	for _, param := range f.Parameters {
+		params = append(params, param.Value)
+	}
+
+	return "fn(" + strings.Join(params, ", ") + ")"
+}
+
+// Quote represents a piece of unevaluated code, the result of quote(<expression>)
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Inspect() string  { return "QUOTE(" + q.Node.String() + ")" }
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+
+// Macro represents a macro.  Like a function, we keep track of the parameters, the body, and the environment it was defined in
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("macro")
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(m.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+
+// String represents a string value
+type String struct {
+	Value string
+}
+
+func (s *String) Inspect() string  { return s.Value }
+func (s *String) Type() ObjectType { return STRING_OBJ }
+
+// Array represents an ordered list of values
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Inspect() string {
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, e.Inspect())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+
+// HashKey is the key a Hashable value is stored under in a Hash.  Two values that are equal have the same HashKey
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable is implemented by the values that can be used as keys in a Hash
+type Hashable interface {
+	HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+
+	if b.Value {
+		value = 1
+	}
+
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+// HashPair holds a key of a Hash together with its value, so the original key is still available when the hash is inspected or iterated
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// Hash represents a map from Hashable values to values
+type Hash struct {
+	Pairs map[HashKey]HashPair
+}
+
+// Inspect lists the pairs of the hash sorted by their keys, so the same hash always looks the same
+func (h *Hash) Inspect() string {
+	pairs := []string{}
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+
+	sort.Strings(pairs)
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+
+// BuiltinFunction is the Go implementation of a builtin function.  It returns an Error when it can not be called with args
+type BuiltinFunction func(args ...Object) Object
+
+// Builtin represents a function implemented in Go that can be called from monkey code
+type Builtin struct {
+	Fn BuiltinFunction
+}
+
+func (b *Builtin) Inspect() string  { return "builtin function" }
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+
+// Task represents a function running on a goroutine of its own, the result of a spawn expression
+type Task struct {
+	done   chan struct{}
+	result Object
+}
+
+// NewTask creates a task that is still running
+func NewTask() *Task {
+	return &Task{done: make(chan struct{})}
+}
+
+// Finish records the result of the task and wakes up everyone waiting for it.  it must be called exactly once
+func (t *Task) Finish(result Object) {
+	t.result = result
+	close(t.done)
+}
+
+// Done returns a channel that is closed once the task has finished
+func (t *Task) Done() <-chan struct{} { return t.done }
+
+// Result returns the result of a finished task
+func (t *Task) Result() Object {
+	<-t.done
+	return t.result
+}
+
+func (t *Task) Inspect() string  { return "task" }
+func (t *Task) Type() ObjectType { return TASK_OBJ }
+
+// Channel represents a channel that values can be sent over between tasks
+type Channel struct {
+	Ch chan Object
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewChannel creates a channel that buffers up to size values
+func NewChannel(size int) *Channel {
+	return &Channel{Ch: make(chan Object, size)}
+}
+
+// Close closes the channel, it reports false if the channel was already closed
+func (c *Channel) Close() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return false
+	}
+
+	c.closed = true
+	close(c.Ch)
+
+	return true
+}
+
+func (c *Channel) Inspect() string  { return fmt.Sprintf("channel(%d)", cap(c.Ch)) }
+func (c *Channel) Type() ObjectType { return CHANNEL_OBJ }
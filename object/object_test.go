@@ -0,0 +1,42 @@
+package object
+
+import (
+	"akdjr/monkey/ast"
+	"testing"
+)
+
+func TestFunctionName(t *testing.T) {
+	params := []*ast.Identifier{{Value: "x"}, {Value: "y"}}
+
+	global := NewEnvironment()
+	local := NewEnclosedEnvironment(global)
+
+	named := &Function{Parameters: params, Env: global}
+	global.Set("named", named)
+
+	inner := &Function{Parameters: params, Env: local}
+	local.Set("inner", inner)
+
+	// a function bound in an outer environment is found there
+	outer := &Function{Parameters: params, Env: local}
+	global.Set("outer", outer)
+
+	anonymous := &Function{Parameters: params, Env: global}
+
+	tests := []struct {
+		fn       *Function
+		expected string
+	}{
+		{named, "named"},
+		{inner, "inner"},
+		{outer, "outer"},
+		{anonymous, "fn(x, y)"},
+		{&Function{Env: global}, "fn()"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.fn.Name(); got != tt.expected {
+			t.Errorf("wrong name. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+}
@@ -29,6 +29,15 @@ const (
 	CALL            // myFunction(X)
 )
 
+// Precedence returns the binding power of an infix operator token, or LOWEST if the token is not an infix operator
+func Precedence(t token.TokenType) int {
+	if p, ok := precedences[t]; ok {
+		return p
+	}
+
+	return LOWEST
+}
+
 var precedences = map[token.TokenType]int{
 	token.EQ:       EQUALS,
 	token.NOT_EQ:   EQUALS,
@@ -43,8 +52,9 @@ var precedences = map[token.TokenType]int{
 
 // Parser represents an instance of a parser.  It takes a lexer and creates an AST, a tree of statements and expressions that represents the grammar of the language
 type Parser struct {
-	l      *lexer.Lexer
-	errors []string
+	l        *lexer.Lexer
+	errors   []*Error
+	comments []token.Comment // every comment read so far, when the lexer keeps them
 
 	currentToken token.Token
 	peekToken    token.Token
@@ -57,7 +67,7 @@ type Parser struct {
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []*Error{},
 	}
 
 	// Read two tokens to set currentToken and peekToken
@@ -77,6 +87,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
+	p.registerPrefix(token.SPAWN, p.parseSpawnExpression)
 
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
@@ -91,9 +103,46 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
-// Errors returns all parser errors
+// Errors returns all parser errors, preceded by any errors the lexer found in the input
 func (p *Parser) Errors() []string {
-	return p.errors
+	errors := []string{}
+
+	for _, err := range p.l.Errors() {
+		errors = append(errors, err.Error())
+	}
+
+	for _, err := range p.errors {
+		errors = append(errors, err.Message)
+	}
+
+	return errors
+}
+
+// Error is a problem found in the source while parsing it.  Line and Column are the position of the token where it was found
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// ErrorList returns the same errors as Errors, lexer errors first, along with where in the source each of them was found
+func (p *Parser) ErrorList() []*Error {
+	errors := []*Error{}
+
+	for _, err := range p.l.Errors() {
+		errors = append(errors, &Error{Line: err.Line, Column: err.Column, Message: err.Message})
+	}
+
+	return append(errors, p.errors...)
+}
+
+// errorAt registers a parser error found at tok
+func (p *Parser) errorAt(tok token.Token, format string, a ...interface{}) {
+	p.errors = append(p.errors, &Error{Line: tok.Line, Column: tok.Column, Message: fmt.Sprintf(format, a...)})
 }
 
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
@@ -109,6 +158,8 @@ func (p *Parser) nextToken() {
 	// read the next token from the lexer
 	p.currentToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	p.comments = append(p.comments, p.peekToken.Comments...)
 }
 
 // ParseProgram generates and returns an AST of the program
@@ -129,6 +180,8 @@ func (p *Parser) ParseProgram() *ast.Program {
 		p.nextToken()
 	}
 
+	program.Comments = p.comments
+
 	return program
 }
 
@@ -228,12 +281,10 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 
 // register a parser error when something unimplemented is encountered
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for '%s' found", t)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.currentToken, "no prefix parse function for '%s' found", t)
 }
 func (p *Parser) noInfixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no infix parse function for '%s' found", t)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.currentToken, "no infix parse function for '%s' found", t)
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
@@ -283,8 +334,7 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	value, err := strconv.ParseInt(p.currentToken.Literal, 0, 64)
 
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.currentToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.currentToken, "could not parse %q as integer", p.currentToken.Literal)
 		return nil
 	}
 
@@ -418,6 +468,8 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		p.nextToken()
 	}
 
+	block.Rbrace = p.currentToken
+
 	return block
 }
 
@@ -445,6 +497,29 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return function
 }
 
+// parse a macro literal expression of form macro(<identifier params>) { <blockstatement> }
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	macro := &ast.MacroLiteral{
+		Token: p.currentToken,
+	}
+
+	// next token must be a '('
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	macro.Parameters = p.parseFunctionParameters()
+
+	// next token must be the '{' to start the macro body
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	macro.Body = p.parseBlockStatement()
+
+	return macro
+}
+
 func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	// empty set of parameters
 	identifiers := []*ast.Identifier{}
@@ -493,6 +568,7 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 		Function: function,
 	}
 	exp.Arguments = p.parseCallArguments()
+	exp.Rparen = p.currentToken
 
 	return exp
 }
@@ -552,24 +628,26 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 }
 
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.peekToken, "expected next token to be %s, got %s instead", t, p.peekToken.Type)
 }
 
 // get the precedence of the next token
 func (p *Parser) peekPrecedence() int {
-	if p, ok := precedences[p.peekToken.Type]; ok {
-		return p
-	}
-
-	return LOWEST
+	return Precedence(p.peekToken.Type)
 }
 
 // get the precedence of the current token
 func (p *Parser) currentPrecedence() int {
-	if p, ok := precedences[p.currentToken.Type]; ok {
-		return p
-	}
+	return Precedence(p.currentToken.Type)
+}
 
-	return LOWEST
+// parse a spawn expression.  spawn binds as tightly as a prefix operator, so spawn f(x) spawns the call f(x)
+func (p *Parser) parseSpawnExpression() ast.Expression {
+	expression := &ast.SpawnExpression{Token: p.currentToken}
+
+	p.nextToken()
+
+	expression.Function = p.parseExpression(PREFIX)
+
+	return expression
 }
@@ -3,7 +3,9 @@ package parser
 import (
 	"akdjr/monkey/ast"
 	"akdjr/monkey/lexer"
+	"akdjr/monkey/token"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -756,6 +758,10 @@ func TestCallExpressionParsing(t *testing.T) {
 	testLiteralExpession(t, exp.Arguments[0], 1)
 	testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
 	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
+
+	if exp.Rparen.Type != token.RPAREN || exp.Rparen.Line != 1 || exp.Rparen.Column != 20 {
+		t.Errorf("wrong closing paren. got=%+v", exp.Rparen)
+	}
 }
 
 func TestCallExpressionParameterParsing(t *testing.T) {
@@ -811,3 +817,128 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 		}
 	}
 }
+
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("macro literal parameters wrong. expected=%d, got=%d", 2, len(macro.Parameters))
+	}
+
+	testLiteralExpession(t, macro.Parameters[0], "x")
+	testLiteralExpession(t, macro.Parameters[1], "y")
+
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf("macro.Body.Statements does not have %d statements. got=%d", 1, len(macro.Body.Statements))
+	}
+
+	bodyStmt, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("macro body stmt is not ast.ExpressionStatement. got=%T", macro.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestSpawnExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"spawn fn() { x }", "(spawn fn() x)"},
+		{"spawn worker(a, 1)", "(spawn worker(a, 1))"},
+		{"let t = spawn f;", "let t = (spawn f);"},
+		{"spawn f + 1", "((spawn f) + 1)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New("spawn worker(1)")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	spawn, ok := stmt.Expression.(*ast.SpawnExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.SpawnExpression. got=%T", stmt.Expression)
+	}
+
+	if _, ok := spawn.Function.(*ast.CallExpression); !ok {
+		t.Fatalf("spawn.Function is not ast.CallExpression. got=%T", spawn.Function)
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []Error
+	}{
+		{"let x = 5;", []Error{}},
+		{
+			"let = 5;",
+			[]Error{
+				{1, 5, "expected next token to be IDENTIFIER, got ASSIGN instead"},
+				{1, 5, "no prefix parse function for 'ASSIGN' found"},
+			},
+		},
+		{"let x = 5;\nlet y 6;", []Error{{2, 7, "expected next token to be ASSIGN, got INT instead"}}},
+		{"f(1, 99999999999999999999)", []Error{{1, 6, "could not parse \"99999999999999999999\" as integer"}}},
+		{
+			"let x = 1;\n  x \xff",
+			[]Error{
+				{2, 5, `invalid UTF-8 encoding "\xff"`},
+				{2, 5, "no prefix parse function for 'ILLEGAL' found"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.ErrorList()
+		if len(errors) != len(tt.expected) {
+			t.Errorf("wrong number of errors for %q. expected=%d, got=%d (%v)", tt.input, len(tt.expected), len(errors), p.Errors())
+			continue
+		}
+
+		for i, err := range errors {
+			if *err != tt.expected[i] {
+				t.Errorf("wrong error %d for %q. expected=%+v, got=%+v", i, tt.input, tt.expected[i], *err)
+			}
+
+			if err.Message != p.Errors()[i] && !strings.HasSuffix(p.Errors()[i], err.Message) {
+				t.Errorf("ErrorList does not match Errors. %q is not %q", err.Message, p.Errors()[i])
+			}
+		}
+	}
+}
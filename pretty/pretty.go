@@ -0,0 +1,265 @@
+package pretty
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/format"
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/object"
+	"akdjr/monkey/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultWidth is the width objects are wrapped to when the width of the terminal is not known
+const DefaultWidth = 80
+
+// indent is the string used for each level of indentation, the same as in formatted source
+const indent = "    "
+
+// ANSI escape sequences for the colors used
+const (
+	reset   = "\x1b[0m"
+	red     = "\x1b[31m"
+	green   = "\x1b[32m"
+	yellow  = "\x1b[33m"
+	blue    = "\x1b[34m"
+	magenta = "\x1b[35m"
+	cyan    = "\x1b[36m"
+	gray    = "\x1b[90m"
+)
+
+// Printer renders objects and source code for display in a terminal
+// objects are laid out on a single line when they fit in Width, otherwise arrays and hashes are broken up with one element per line, and functions are shown as formatted source
+type Printer struct {
+	// Color turns on colors: each type of object, and each kind of token in source, gets its own
+	Color bool
+
+	// Width is the number of columns objects are wrapped to, DefaultWidth when it is 0
+	Width int
+}
+
+// Object renders obj
+func (p *Printer) Object(obj object.Object) string {
+	return p.object(obj, 0)
+}
+
+// object renders obj at depth levels of indentation.  the first line is not indented, the caller has already written something in front of it
+func (p *Printer) object(obj object.Object, depth int) string {
+	if rv, ok := obj.(*object.ReturnValue); ok {
+		obj = rv.Value
+	}
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		if flat := p.flat(obj); p.fits(flat, depth) {
+			return flat
+		}
+
+		lines := []string{}
+		for _, e := range obj.Elements {
+			lines = append(lines, p.object(e, depth+1))
+		}
+
+		return p.block("[", lines, "]", depth)
+	case *object.Hash:
+		if flat := p.flat(obj); p.fits(flat, depth) {
+			return flat
+		}
+
+		lines := []string{}
+		for _, pair := range sortedPairs(obj) {
+			lines = append(lines, p.object(pair.Key, depth+1)+": "+p.object(pair.Value, depth+1))
+		}
+
+		return p.block("{", lines, "}", depth)
+	case *object.Function, *object.Macro, *object.Quote:
+		return strings.ReplaceAll(p.flat(obj), "\n", "\n"+strings.Repeat(indent, depth))
+	}
+
+	return p.flat(obj)
+}
+
+// block renders lines between open and close, each on a line of its own at one more level of indentation
+func (p *Printer) block(open string, lines []string, close string, depth int) string {
+	if len(lines) == 0 {
+		return open + close
+	}
+
+	inner := "\n" + strings.Repeat(indent, depth+1)
+	return open + inner + strings.Join(lines, ","+inner) + "\n" + strings.Repeat(indent, depth) + close
+}
+
+// fits reports whether s fits on a single line at depth levels of indentation
+func (p *Printer) fits(s string, depth int) bool {
+	width := p.Width
+	if width <= 0 {
+		width = DefaultWidth
+	}
+
+	return !strings.Contains(s, "\n") && len(indent)*depth+visibleWidth(s) <= width
+}
+
+// flat renders obj on a single line, except for functions, macros and quotes, which are rendered as the formatted source they are made of
+func (p *Printer) flat(obj object.Object) string {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return p.paint(yellow, obj.Inspect())
+	case *object.Boolean, *object.Null:
+		return p.paint(cyan, obj.Inspect())
+	case *object.String:
+		return p.paint(green, strconv.Quote(obj.Value))
+	case *object.Error:
+		return p.paint(red, obj.Inspect())
+	case *object.ReturnValue:
+		return p.flat(obj.Value)
+	case *object.Array:
+		elements := []string{}
+		for _, e := range obj.Elements {
+			elements = append(elements, p.flat(e))
+		}
+
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *object.Hash:
+		pairs := []string{}
+		for _, pair := range sortedPairs(obj) {
+			pairs = append(pairs, p.flat(pair.Key)+": "+p.flat(pair.Value))
+		}
+
+		return "{" + strings.Join(pairs, ", ") + "}"
+	case *object.Function:
+		return p.Source(format.Node(&ast.FunctionLiteral{
+			Token:      token.Token{Type: token.FUNCTION, Literal: "fn"},
+			Parameters: obj.Parameters,
+			Body:       obj.Body,
+		}))
+	case *object.Macro:
+		return p.Source(format.Node(&ast.MacroLiteral{
+			Token:      token.Token{Type: token.MACRO, Literal: "macro"},
+			Parameters: obj.Parameters,
+			Body:       obj.Body,
+		}))
+	case *object.Quote:
+		return "quote(" + p.Source(format.Node(obj.Node)) + ")"
+	default:
+		return p.paint(blue, obj.Inspect())
+	}
+}
+
+// sortedPairs returns the pairs of a hash in the order of their keys, the same order Inspect uses
+func sortedPairs(hash *object.Hash) []object.HashPair {
+	pairs := make([]object.HashPair, 0, len(hash.Pairs))
+	for _, pair := range hash.Pairs {
+		pairs = append(pairs, pair)
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.Inspect() < pairs[j].Key.Inspect()
+	})
+
+	return pairs
+}
+
+// Source highlights the tokens in src.  everything in src is kept as it is, colors are only added around it
+func (p *Printer) Source(src string) string {
+	if !p.Color {
+		return src
+	}
+
+	runes := []rune(src)
+
+	// lineStarts are the offsets in runes at which each line starts, to turn the positions of tokens into offsets
+	lineStarts := []int{0}
+	for i, r := range runes {
+		if r == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+
+	var out strings.Builder
+	pos := 0
+
+	// emit writes the source up to start as it is, then the text starting at start in color
+	emit := func(line int, column int, text string, color string) {
+		if line < 1 || line > len(lineStarts) {
+			return
+		}
+
+		start := lineStarts[line-1] + column - 1
+		end := start + utf8.RuneCountInString(text)
+		if start < pos || end > len(runes) {
+			return
+		}
+
+		out.WriteString(string(runes[pos:start]))
+		out.WriteString(p.paint(color, string(runes[start:end])))
+		pos = end
+	}
+
+	l := lexer.New(src)
+	l.KeepComments(true)
+
+	for {
+		tok := l.NextToken()
+
+		for _, c := range tok.Comments {
+			emit(c.Line, c.Column, c.Text, gray)
+		}
+
+		if tok.Type == token.EOF {
+			break
+		}
+
+		if color := tokenColor(tok.Type); color != "" {
+			emit(tok.Line, tok.Column, tok.Literal, color)
+		}
+	}
+
+	out.WriteString(string(runes[pos:]))
+	return out.String()
+}
+
+// tokenColor returns the color of a kind of token, or "" for tokens that are not highlighted
+func tokenColor(t token.TokenType) string {
+	switch t {
+	case token.FUNCTION, token.LET, token.IF, token.ELSE, token.RETURN, token.MACRO, token.SPAWN:
+		return magenta
+	case token.TRUE, token.FALSE:
+		return cyan
+	case token.INT:
+		return yellow
+	case token.ILLEGAL:
+		return red
+	}
+
+	return ""
+}
+
+// paint wraps s in color, when colors are on
+func (p *Printer) paint(color string, s string) string {
+	if !p.Color {
+		return s
+	}
+
+	return color + s + reset
+}
+
+// visibleWidth returns the number of columns s takes up in a terminal, leaving out escape sequences
+func visibleWidth(s string) int {
+	width := 0
+	escape := false
+
+	for _, r := range s {
+		switch {
+		case escape:
+			escape = !(r >= 0x40 && r <= 0x7e && r != '[')
+		case r == '\x1b':
+			escape = true
+		default:
+			width++
+		}
+	}
+
+	return width
+}
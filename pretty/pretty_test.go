@@ -0,0 +1,125 @@
+package pretty
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/evaluator"
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/object"
+	"akdjr/monkey/parser"
+	"testing"
+)
+
+func TestObject(t *testing.T) {
+	tests := []struct {
+		input    string
+		width    int
+		expected string
+	}{
+		{"42", 0, "42"},
+		{"1 > 2", 0, "false"},
+		{"if (false) { 1 }", 0, "null"},
+		{"fn(x, y) { x + y }", 0, "fn(x, y) {\n    x + y;\n}"},
+		{"double", 0, "macro(x) {\n    quote(unquote(x) * 2);\n}"},
+		{"quote(1 + 2)", 0, "quote(1 + 2)"},
+		{"1 + true", 0, "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{"words", 0, `["one", "two", "three"]`},
+		{"words", 20, "[\n    \"one\",\n    \"two\",\n    \"three\"\n]"},
+		{"nested", 0, `[1, ["one", "two", "three"], {"key": [true, false]}]`},
+		{"nested", 30, "[\n    1,\n    [\"one\", \"two\", \"three\"],\n    {\"key\": [true, false]}\n]"},
+		{"nested", 22, "[\n    1,\n    [\n        \"one\",\n        \"two\",\n        \"three\"\n    ],\n    {\n        \"key\": [true, false]\n    }\n]"},
+		{"functions", 0, "[\n    fn(x) {\n        x;\n    },\n    2\n]"},
+		{"empty", 1, "[]"},
+	}
+
+	for _, tt := range tests {
+		p := &Printer{Width: tt.width}
+
+		got := p.Object(testEval(tt.input))
+		if got != tt.expected {
+			t.Errorf("wrong rendering of %q at width %d.\nexpected=%q\ngot=%q", tt.input, tt.width, tt.expected, got)
+		}
+	}
+}
+
+func TestColor(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"42", "\x1b[33m42\x1b[0m"},
+		{"true", "\x1b[36mtrue\x1b[0m"},
+		{"words", "[\x1b[32m\"one\"\x1b[0m, \x1b[32m\"two\"\x1b[0m, \x1b[32m\"three\"\x1b[0m]"},
+		{"-true", "\x1b[31mERROR: unknown operator: -BOOLEAN\x1b[0m"},
+		{"fn() { 1 }", "\x1b[35mfn\x1b[0m() {\n    \x1b[33m1\x1b[0m;\n}"},
+	}
+
+	for _, tt := range tests {
+		p := &Printer{Color: true}
+
+		got := p.Object(testEval(tt.input))
+		if got != tt.expected {
+			t.Errorf("wrong rendering of %q.\nexpected=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	// colors do not count towards the width
+	p := &Printer{Color: true, Width: 23}
+	if got := p.Object(testEval("words")); got != "[\x1b[32m\"one\"\x1b[0m, \x1b[32m\"two\"\x1b[0m, \x1b[32m\"three\"\x1b[0m]" {
+		t.Errorf("colored array does not fit its width. got=%q", got)
+	}
+}
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 5;", "\x1b[35mlet\x1b[0m x = \x1b[33m5\x1b[0m;"},
+		{"if (true) { x } // done", "\x1b[35mif\x1b[0m (\x1b[36mtrue\x1b[0m) { x } \x1b[90m// done\x1b[0m"},
+		{"let s = spawn f(1) @", "\x1b[35mlet\x1b[0m s = \x1b[35mspawn\x1b[0m f(\x1b[33m1\x1b[0m) \x1b[31m@\x1b[0m"},
+		{"fn(x) {\n\treturn x == 10\n}", "\x1b[35mfn\x1b[0m(x) {\n\t\x1b[35mreturn\x1b[0m x == \x1b[33m10\x1b[0m\n}"},
+		{"/* unterminated", "\x1b[90m/* unterminated\x1b[0m"},
+	}
+
+	for _, tt := range tests {
+		p := &Printer{Color: true}
+
+		if got := p.Source(tt.input); got != tt.expected {
+			t.Errorf("wrong highlighting of %q.\nexpected=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	if got := (&Printer{}).Source("let x = 5;"); got != "let x = 5;" {
+		t.Errorf("source is highlighted without colors. got=%q", got)
+	}
+}
+
+// testEval evaluates input in an environment that has a few values that can not be written in monkey itself
+func testEval(input string) object.Object {
+	env := object.NewEnvironment()
+
+	words := &object.Array{Elements: []object.Object{
+		&object.String{Value: "one"},
+		&object.String{Value: "two"},
+		&object.String{Value: "three"},
+	}}
+	env.Set("words", words)
+
+	key := &object.String{Value: "key"}
+	hash := &object.Hash{Pairs: map[object.HashKey]object.HashPair{
+		key.HashKey(): {Key: key, Value: &object.Array{Elements: []object.Object{evaluator.TRUE, evaluator.FALSE}}},
+	}}
+	env.Set("nested", &object.Array{Elements: []object.Object{&object.Integer{Value: 1}, words, hash}})
+	env.Set("empty", &object.Array{})
+
+	macro := parser.New(lexer.New("macro(x) { quote(unquote(x) * 2) }")).ParseProgram().Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MacroLiteral)
+	env.Set("double", &object.Macro{Parameters: macro.Parameters, Body: macro.Body, Env: env})
+
+	program := parser.New(lexer.New(input)).ParseProgram()
+	env.Set("functions", &object.Array{Elements: []object.Object{
+		evaluator.Eval(parser.New(lexer.New("fn(x) { x }")).ParseProgram(), env),
+		&object.Integer{Value: 2},
+	}})
+
+	return evaluator.Eval(program, env)
+}
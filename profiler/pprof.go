@@ -0,0 +1,190 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// the field numbers of the messages of profile.proto, the format of pprof, see https://github.com/google/pprof/blob/main/proto/profile.proto
+const (
+	profileSampleType    = 1
+	profileSample        = 2
+	profileLocation      = 4
+	profileFunction      = 5
+	profileStringTable   = 6
+	profileTimeNanos     = 9
+	profileDurationNanos = 10
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID        = 1
+	functionName      = 2
+	functionFilename  = 4
+	functionStartLine = 5
+)
+
+// WritePprof writes the profile in the gzipped protocol buffer format that pprof reads.  every sample has two values: the calls made to the innermost function of its stack, and the time spent in it in nanoseconds
+func (p *Profiler) WritePprof(w io.Writer) error {
+	table := newStringTable()
+	profile := &protobuf{}
+
+	for _, valueType := range [][2]string{{"calls", "count"}, {"time", "nanoseconds"}} {
+		profile.message(profileSampleType, func(m *protobuf) {
+			m.int(valueTypeType, table.index(valueType[0]))
+			m.int(valueTypeUnit, table.index(valueType[1]))
+		})
+	}
+
+	functions := map[location]uint64{}
+	locations := map[frame]uint64{}
+
+	// functions and locations are numbered from 1 in the order they are first seen
+	for _, s := range p.order {
+		ids := make([]uint64, 0, len(s.stack))
+
+		for _, f := range s.stack {
+			if _, ok := functions[f.function]; !ok {
+				id := uint64(len(functions) + 1)
+				functions[f.function] = id
+
+				profile.message(profileFunction, func(m *protobuf) {
+					m.uint(functionID, id)
+					m.int(functionName, table.index(f.function.name))
+					m.int(functionFilename, table.index(p.Filename))
+					m.int(functionStartLine, int64(f.function.line))
+				})
+			}
+
+			id, ok := locations[f]
+			if !ok {
+				id = uint64(len(locations) + 1)
+				locations[f] = id
+
+				function := functions[f.function]
+				profile.message(profileLocation, func(m *protobuf) {
+					m.uint(locationID, id)
+					m.message(locationLine, func(line *protobuf) {
+						line.uint(lineFunctionID, function)
+						line.int(lineLine, int64(f.line))
+					})
+				})
+			}
+
+			ids = append(ids, id)
+		}
+
+		profile.message(profileSample, func(m *protobuf) {
+			m.packed(sampleLocationID, ids)
+			m.packed(sampleValue, []uint64{uint64(s.calls), uint64(s.time.Nanoseconds())})
+		})
+	}
+
+	for _, s := range table.strings {
+		profile.bytes(profileStringTable, []byte(s))
+	}
+
+	if !p.start.IsZero() {
+		profile.int(profileTimeNanos, p.start.UnixNano())
+		profile.int(profileDurationNanos, p.end.Sub(p.start).Nanoseconds())
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(profile.data); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// stringTable numbers the strings of a profile.  the first string of every profile must be the empty one
+type stringTable struct {
+	strings []string
+	indexes map[string]int64
+}
+
+func newStringTable() *stringTable {
+	return &stringTable{strings: []string{""}, indexes: map[string]int64{"": 0}}
+}
+
+func (t *stringTable) index(s string) int64 {
+	if i, ok := t.indexes[s]; ok {
+		return i
+	}
+
+	i := int64(len(t.strings))
+	t.strings = append(t.strings, s)
+	t.indexes[s] = i
+
+	return i
+}
+
+// protobuf encodes a protocol buffer message, the few wire types the profile format needs of it
+type protobuf struct {
+	data []byte
+}
+
+const (
+	wireVarint          = 0
+	wireLengthDelimited = 2
+)
+
+func (b *protobuf) varint(v uint64) {
+	for v >= 0x80 {
+		b.data = append(b.data, byte(v)|0x80)
+		v >>= 7
+	}
+	b.data = append(b.data, byte(v))
+}
+
+func (b *protobuf) key(field int, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+// uint encodes a uint64 field.  zero is the default value, which is left out
+func (b *protobuf) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+
+	b.key(field, wireVarint)
+	b.varint(v)
+}
+
+// int encodes an int64 field.  zero is the default value, which is left out
+func (b *protobuf) int(field int, v int64) {
+	b.uint(field, uint64(v))
+}
+
+func (b *protobuf) bytes(field int, v []byte) {
+	b.key(field, wireLengthDelimited)
+	b.varint(uint64(len(v)))
+	b.data = append(b.data, v...)
+}
+
+// packed encodes a repeated integer field in the packed form
+func (b *protobuf) packed(field int, values []uint64) {
+	packed := &protobuf{}
+	for _, v := range values {
+		packed.varint(v)
+	}
+
+	b.bytes(field, packed.data)
+}
+
+// message encodes a field holding the message that encode builds
+func (b *protobuf) message(field int, encode func(m *protobuf)) {
+	m := &protobuf{}
+	encode(m)
+
+	b.bytes(field, m.data)
+}
@@ -0,0 +1,218 @@
+package profiler
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/evaluator"
+	"akdjr/monkey/object"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Profiler measures where a monkey program spends its time.  it follows the evaluation with evaluator hooks, so every statement and function call is counted, none are sampled
+// the time between two statements is attributed to the call stack of the first one: the function and line it is in, and the lines of the calls that led there
+type Profiler struct {
+	// Filename is the name of the program being profiled, as the profiles show it
+	Filename string
+
+	// stack holds the sample of every call that has not returned yet, the program itself first.  the samples form a tree, so moving to another line or into another call finds the sample of the new stack without building it
+	stack []*sample
+	root  *sample
+	order []*sample
+
+	// locations caches the locations of the functions called, finding the name of a function searches the environment it was defined in
+	locations map[*object.Function]location
+
+	start time.Time
+	last  time.Time
+	end   time.Time
+
+	// now returns the current time, it is replaced by tests
+	now func() time.Time
+}
+
+// frame is a function call that has not returned yet, or the program itself
+type frame struct {
+	function location
+	line     int
+}
+
+// location is a function and the line it starts on.  the program itself is the function main, starting on line 1
+type location struct {
+	name string
+	line int
+}
+
+// sample is the time spent in a call stack, and the number of calls that made it.  stack is innermost first, and children are the samples of the stacks one frame deeper
+type sample struct {
+	stack    []frame
+	calls    int64
+	time     time.Duration
+	children map[frame]*sample
+}
+
+// New creates a Profiler that has not started yet
+func New(filename string) *Profiler {
+	return &Profiler{
+		Filename:  filename,
+		root:      &sample{},
+		locations: map[*object.Function]location{},
+		now:       time.Now,
+	}
+}
+
+// Hooks returns the hooks that feed the profiler.  the profile starts with the first of them to be called, and ends with Stop
+func (p *Profiler) Hooks() *evaluator.Hooks {
+	return &evaluator.Hooks{
+		BeforeNode:    p.beforeNode,
+		EnterFunction: p.enterFunction,
+		ExitFunction:  p.exitFunction,
+	}
+}
+
+// Stop ends the profile.  the time since the last statement is attributed to it
+func (p *Profiler) Stop() {
+	p.tick()
+	p.end = p.last
+}
+
+// tick attributes the time since the last event to the current call stack
+func (p *Profiler) tick() {
+	now := p.now()
+
+	if p.start.IsZero() {
+		p.start, p.last = now, now
+		return
+	}
+
+	if len(p.stack) > 0 {
+		p.stack[len(p.stack)-1].time += now.Sub(p.last)
+	}
+
+	p.last = now
+}
+
+// child returns the sample for the stack of parent with f called on top of it
+func (p *Profiler) child(parent *sample, f frame) *sample {
+	if s, ok := parent.children[f]; ok {
+		return s
+	}
+
+	s := &sample{stack: append([]frame{f}, parent.stack...)}
+
+	if parent.children == nil {
+		parent.children = map[frame]*sample{}
+	}
+	parent.children[f] = s
+	p.order = append(p.order, s)
+
+	return s
+}
+
+// parent returns the sample of the stack the innermost call was made from, the root when it is the program itself
+func (p *Profiler) parent() *sample {
+	if len(p.stack) < 2 {
+		return p.root
+	}
+
+	return p.stack[len(p.stack)-2]
+}
+
+// location returns the location of fn
+func (p *Profiler) location(fn *object.Function) location {
+	if l, ok := p.locations[fn]; ok {
+		return l
+	}
+
+	l := location{name: fn.Name(), line: fn.Body.Token.Line}
+	p.locations[fn] = l
+
+	return l
+}
+
+func (p *Profiler) beforeNode(node ast.Node, env *object.Environment) {
+	line := statementLine(node)
+	if line == 0 {
+		return
+	}
+
+	p.tick()
+
+	if len(p.stack) == 0 {
+		p.stack = append(p.stack, p.child(p.root, frame{function: location{name: "main", line: 1}, line: line}))
+		return
+	}
+
+	top := p.stack[len(p.stack)-1].stack[0]
+	if top.line != line {
+		p.stack[len(p.stack)-1] = p.child(p.parent(), frame{function: top.function, line: line})
+	}
+}
+
+func (p *Profiler) enterFunction(fn *object.Function, args []object.Object, env *object.Environment) {
+	p.tick()
+
+	parent := p.root
+	if len(p.stack) > 0 {
+		parent = p.stack[len(p.stack)-1]
+	}
+
+	l := p.location(fn)
+	s := p.child(parent, frame{function: l, line: l.line})
+	s.calls++
+
+	p.stack = append(p.stack, s)
+}
+
+func (p *Profiler) exitFunction(fn *object.Function, result object.Object) {
+	p.tick()
+
+	if len(p.stack) > 0 {
+		p.stack = p.stack[:len(p.stack)-1]
+	}
+}
+
+// statementLine returns the line of node if it is a statement, other than a block, or 0
+func statementLine(node ast.Node) int {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		return node.Token.Line
+	case *ast.ReturnStatement:
+		return node.Token.Line
+	case *ast.ExpressionStatement:
+		return node.Token.Line
+	}
+
+	return 0
+}
+
+// WriteFolded writes the profile in the folded stack format that flamegraph tools read: a line for every call stack, with the names of its functions from the outermost in, separated by semicolons, followed by the time spent in it in nanoseconds
+func (p *Profiler) WriteFolded(w io.Writer) error {
+	totals := map[string]time.Duration{}
+
+	for _, s := range p.order {
+		names := make([]string, 0, len(s.stack))
+		for i := len(s.stack) - 1; i >= 0; i-- {
+			names = append(names, s.stack[i].function.name)
+		}
+
+		totals[strings.Join(names, ";")] += s.time
+	}
+
+	stacks := make([]string, 0, len(totals))
+	for stack := range totals {
+		stacks = append(stacks, stack)
+	}
+
+	sort.Strings(stacks)
+
+	for _, stack := range stacks {
+		if _, err := fmt.Fprintf(w, "%s %d\n", stack, totals[stack].Nanoseconds()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
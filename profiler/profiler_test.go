@@ -0,0 +1,278 @@
+package profiler
+
+import (
+	"akdjr/monkey/interpreter"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+const program = `let f = fn(x) {
+  x + 1
+};
+f(1);
+f(2);`
+
+// profile profiles program with a clock that moves on by a millisecond every time it is read
+func profile(t *testing.T, program string) *Profiler {
+	p := New("program.monkey")
+
+	clock := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.now = func() time.Time {
+		clock = clock.Add(time.Millisecond)
+		return clock
+	}
+
+	i := interpreter.New()
+	i.Hooks = p.Hooks()
+
+	if _, err := i.Run(program); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	p.Stop()
+
+	return p
+}
+
+func TestFolded(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{program, "main 5000000\nmain;f 4000000\n"},
+		// a tail call takes the place of the function that made it
+		{"let g = fn() { 1 };\nlet f = fn() { g() };\nf();", "main 5000000\nmain;f 2000000\nmain;g 2000000\n"},
+		{"fn(a, b) { a }(1, 2);", "main 2000000\nmain;fn(a, b) 2000000\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := profile(t, tt.input).WriteFolded(&out); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if out.String() != tt.expected {
+			t.Errorf("wrong folded stacks for %q.\nexpected=%q\ngot=%q", tt.input, tt.expected, out.String())
+		}
+	}
+}
+
+func TestPprof(t *testing.T) {
+	var out bytes.Buffer
+	if err := profile(t, program).WritePprof(&out); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	r, err := gzip.NewReader(&out)
+	if err != nil {
+		t.Fatalf("the profile is not gzipped: %s", err)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := decode(t, data)
+
+	table := []string{}
+	for _, s := range fields[profileStringTable] {
+		table = append(table, string(s.bytes))
+	}
+
+	if len(table) == 0 || table[0] != "" {
+		t.Fatalf("the string table does not start with the empty string. got=%q", table)
+	}
+
+	sampleTypes := []string{}
+	for _, sampleType := range fields[profileSampleType] {
+		m := decode(t, sampleType.bytes)
+		sampleTypes = append(sampleTypes, table[m[valueTypeType][0].varint]+"/"+table[m[valueTypeUnit][0].varint])
+	}
+
+	if len(sampleTypes) != 2 || sampleTypes[0] != "calls/count" || sampleTypes[1] != "time/nanoseconds" {
+		t.Errorf("wrong sample types. got=%q", sampleTypes)
+	}
+
+	functions := map[uint64]string{}
+	for _, function := range fields[profileFunction] {
+		m := decode(t, function.bytes)
+		functions[m[functionID][0].varint] = table[m[functionName][0].varint]
+
+		if filename := table[m[functionFilename][0].varint]; filename != "program.monkey" {
+			t.Errorf("wrong filename. got=%q", filename)
+		}
+	}
+
+	if len(functions) != 2 || functions[1] != "main" || functions[2] != "f" {
+		t.Errorf("wrong functions. got=%v", functions)
+	}
+
+	// every location is a line of a function
+	locations := map[uint64]string{}
+	for _, location := range fields[profileLocation] {
+		m := decode(t, location.bytes)
+		line := decode(t, m[locationLine][0].bytes)
+
+		number := uint64(0)
+		if values := line[lineLine]; len(values) > 0 {
+			number = values[0].varint
+		}
+
+		locations[m[locationID][0].varint] = fmt.Sprintf("%s:%d", functions[line[lineFunctionID][0].varint], number)
+	}
+
+	// the samples are the same as the folded stacks, by line
+	samples := map[string][2]uint64{}
+	for _, sample := range fields[profileSample] {
+		m := decode(t, sample.bytes)
+
+		stack := ""
+		for _, id := range packed(t, m[sampleLocationID][0].bytes) {
+			stack += locations[id] + " "
+		}
+
+		values := packed(t, m[sampleValue][0].bytes)
+		samples[stack] = [2]uint64{values[0], values[1]}
+	}
+
+	expected := map[string][2]uint64{
+		"main:1 ":     {0, 1000000},
+		"main:4 ":     {0, 2000000},
+		"f:1 main:4 ": {1, 1000000},
+		"f:2 main:4 ": {0, 1000000},
+		"main:5 ":     {0, 2000000},
+		"f:1 main:5 ": {1, 1000000},
+		"f:2 main:5 ": {0, 1000000},
+	}
+
+	if len(samples) != len(expected) {
+		t.Errorf("wrong number of samples. expected=%d, got=%d (%v)", len(expected), len(samples), samples)
+	}
+
+	for stack, values := range expected {
+		if samples[stack] != values {
+			t.Errorf("wrong values for %q. expected=%v, got=%v", stack, values, samples[stack])
+		}
+	}
+
+	if duration := fields[profileDurationNanos][0].varint; duration != uint64(9*time.Millisecond) {
+		t.Errorf("wrong duration. got=%d", duration)
+	}
+}
+
+// field is the value of a protocol buffer field, either a varint or length delimited bytes
+type field struct {
+	varint uint64
+	bytes  []byte
+}
+
+// decode decodes a protocol buffer message into its fields, by number
+func decode(t *testing.T, data []byte) map[int][]field {
+	fields := map[int][]field{}
+
+	for len(data) > 0 {
+		key, n := uvarint(t, data)
+		data = data[n:]
+
+		number := int(key >> 3)
+		switch key & 7 {
+		case wireVarint:
+			v, n := uvarint(t, data)
+			data = data[n:]
+			fields[number] = append(fields[number], field{varint: v})
+		case wireLengthDelimited:
+			length, n := uvarint(t, data)
+			data = data[n:]
+			fields[number] = append(fields[number], field{bytes: data[:length]})
+			data = data[length:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+
+	return fields
+}
+
+func packed(t *testing.T, data []byte) []uint64 {
+	values := []uint64{}
+	for len(data) > 0 {
+		v, n := uvarint(t, data)
+		data = data[n:]
+		values = append(values, v)
+	}
+
+	return values
+}
+
+func uvarint(t *testing.T, data []byte) (uint64, int) {
+	var v uint64
+	for i, b := range data {
+		v |= uint64(b&0x7f) << (7 * i)
+		if b < 0x80 {
+			return v, i + 1
+		}
+	}
+
+	t.Fatalf("truncated varint")
+	return 0, 0
+}
+
+// describe returns the call stacks of the samples of p, in the order they were first seen, outermost first with the line every frame is at, and their number of calls
+func describe(p *Profiler) []string {
+	stacks := []string{}
+	for _, s := range p.order {
+		frames := []string{}
+		for i := len(s.stack) - 1; i >= 0; i-- {
+			frames = append(frames, fmt.Sprintf("%s:%d", s.stack[i].function.name, s.stack[i].line))
+		}
+
+		stacks = append(stacks, fmt.Sprintf("%s calls=%d", strings.Join(frames, ";"), s.calls))
+	}
+
+	return stacks
+}
+
+func TestSamples(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  []string
+		functions int
+	}{
+		{
+			// calls made from the same stack share a sample, calls from another line do not
+			"let f = fn(x) {\n  x + 1\n};\nf(1) + f(2);\nf(3);",
+			[]string{"main:1 calls=0", "main:4 calls=0", "main:4;f:1 calls=2", "main:4;f:2 calls=0", "main:5 calls=0", "main:5;f:1 calls=1", "main:5;f:2 calls=0"},
+			1,
+		},
+		{
+			// every level of a recursion is a stack of its own
+			"let f = fn(n) {\n  if (n > 0) {\n    1 + f(n - 1)\n  } else {\n    0\n  }\n};\nf(2);",
+			[]string{
+				"main:1 calls=0", "main:8 calls=0",
+				"main:8;f:1 calls=1", "main:8;f:2 calls=0", "main:8;f:3 calls=0",
+				"main:8;f:3;f:1 calls=1", "main:8;f:3;f:2 calls=0", "main:8;f:3;f:3 calls=0",
+				"main:8;f:3;f:3;f:1 calls=1", "main:8;f:3;f:3;f:2 calls=0", "main:8;f:3;f:3;f:5 calls=0",
+			},
+			1,
+		},
+	}
+
+	for _, tt := range tests {
+		p := profile(t, tt.input)
+
+		if stacks := describe(p); strings.Join(stacks, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("wrong samples for %q.\nexpected=%q\ngot=%q", tt.input, tt.expected, stacks)
+		}
+
+		// the location of every function called is looked up once, however many times it is called
+		if len(p.locations) != tt.functions {
+			t.Errorf("wrong number of functions for %q. expected=%d, got=%d", tt.input, tt.functions, len(p.locations))
+		}
+	}
+}
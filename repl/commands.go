@@ -0,0 +1,252 @@
+package repl
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/object"
+	"akdjr/monkey/snapshot"
+	"akdjr/monkey/token"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// command is a REPL command, run by typing a colon followed by its name
+type command struct {
+	usage string
+	help  string
+	run   func(s *session, arg string)
+}
+
+// commands is filled in by init, as :help refers back to it
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"help":   {":help", "list the REPL commands", (*session).help},
+		"save":   {":save <file>", "save all bindings to file", (*session).saveCommand},
+		"load":   {":load <file>", "load the bindings saved in file", (*session).loadCommand},
+		"tokens": {":tokens <code>", "list the tokens of code", (*session).tokens},
+		"ast":    {":ast <code>", "print the syntax tree of code", (*session).tree},
+		"env":    {":env", "list all bindings", (*session).bindings},
+		"type":   {":type <expr>", "evaluate expr and print the type of its value", (*session).typeOf},
+		"time":   {":time <expr>", "evaluate expr and report how long it took and how much it allocated", (*session).time},
+		"reset":  {":reset", "forget all bindings", (*session).reset},
+		"color":  {":color [on|off]", "turn colors on or off, or show whether they are on", (*session).color},
+	}
+}
+
+// command runs the REPL command on line
+func (s *session) command(line string) {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), ":"), " ")
+
+	cmd, ok := commands[name]
+	if !ok {
+		s.printf("unknown command :%s, try :help", name)
+		return
+	}
+
+	cmd.run(s, strings.TrimSpace(arg))
+}
+
+func (s *session) help(string) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		s.printf("%-16s %s", commands[name].usage, commands[name].help)
+	}
+}
+
+func (s *session) saveCommand(path string) {
+	if path == "" {
+		s.printf("usage: %s", commands["save"].usage)
+		return
+	}
+
+	s.save(path)
+}
+
+func (s *session) loadCommand(path string) {
+	if path == "" {
+		s.printf("usage: %s", commands["load"].usage)
+		return
+	}
+
+	s.load(path)
+}
+
+// save saves the bindings of the session to path.  the snapshot is written next to path first, so a failed save never leaves a broken file behind
+func (s *session) save(path string) {
+	tmp := path + ".tmp"
+
+	file, err := os.Create(tmp)
+	if err != nil {
+		s.printf("%s", err)
+		return
+	}
+
+	skipped, err := snapshot.Save(file, s.env, s.macroEnv)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+		s.printf("%s", err)
+		return
+	}
+
+	if len(skipped) > 0 {
+		s.printf("not saved, these values only exist while the session runs: %s", strings.Join(skipped, ", "))
+	}
+}
+
+// load loads the bindings saved in path into the session
+func (s *session) load(path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		s.printf("%s", err)
+		return
+	}
+	defer file.Close()
+
+	if err := snapshot.Load(file, s.env, s.macroEnv); err != nil {
+		s.printf("%s: %s", path, err)
+	}
+}
+
+// printf prints a message from the REPL itself, indented like parser errors
+func (s *session) printf(format string, a ...interface{}) {
+	io.WriteString(s.out, "\t"+fmt.Sprintf(format, a...)+"\n")
+}
+
+func (s *session) tokens(code string) {
+	l := lexer.New(code)
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		s.printf("%d:%-4d %-12s %s", tok.Line, tok.Column, tok.Type, tok.Literal)
+	}
+
+	for _, err := range l.Errors() {
+		s.printf("%s", err)
+	}
+}
+
+// tree prints every node of the syntax tree of code on a line of its own, indented by its depth
+func (s *session) tree(code string) {
+	program, ok := s.parse(code)
+	if !ok {
+		return
+	}
+
+	depth := 0
+	ast.Inspect(program, func(node ast.Node) bool {
+		if node == nil {
+			depth--
+			return false
+		}
+
+		s.printf("%s%s", strings.Repeat("  ", depth), describe(node))
+		depth++
+
+		return true
+	})
+}
+
+// describe names a node for :ast, together with the operator or value it holds
+func describe(node ast.Node) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+
+	switch node := node.(type) {
+	case *ast.Identifier:
+		return name + " " + node.Value
+	case *ast.IntegerLiteral, *ast.Boolean:
+		return name + " " + node.TokenLiteral()
+	case *ast.PrefixExpression:
+		return name + " " + node.Operator
+	case *ast.InfixExpression:
+		return name + " " + node.Operator
+	default:
+		return name
+	}
+}
+
+// bindings lists the bindings of the session, macros included, one per line
+func (s *session) bindings(string) {
+	for _, env := range []*object.Environment{s.macroEnv, s.env} {
+		for _, name := range env.Names() {
+			value, _ := env.Get(name)
+			s.printf("%s = %s", name, strings.Join(strings.Fields(value.Inspect()), " "))
+		}
+	}
+}
+
+func (s *session) typeOf(expr string) {
+	result, value, ok := s.evaluate(expr)
+	if !ok {
+		return
+	}
+
+	if !value {
+		s.printf("no value")
+		return
+	}
+
+	s.printf("%s", result.Type())
+}
+
+func (s *session) time(expr string) {
+	var before, after runtime.MemStats
+
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	result, value, ok := s.evaluate(expr)
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	if !ok {
+		return
+	}
+
+	if value {
+		s.print(result)
+	}
+	s.printf("time: %s, allocations: %d (%d bytes)", elapsed, after.Mallocs-before.Mallocs, after.TotalAlloc-before.TotalAlloc)
+}
+
+func (s *session) reset(string) {
+	s.env = s.globals()
+	s.macroEnv = object.NewEnvironment()
+}
+
+func (s *session) color(arg string) {
+	switch arg {
+	case "on":
+		s.printer.Color = true
+	case "off":
+		s.printer.Color = false
+	case "":
+		if s.printer.Color {
+			s.printf("colors are on")
+		} else {
+			s.printf("colors are off")
+		}
+	default:
+		s.printf("usage: %s", commands["color"].usage)
+	}
+}
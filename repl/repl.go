@@ -1,50 +1,252 @@
 package repl
 
 import (
+	"akdjr/monkey/ast"
 	"akdjr/monkey/evaluator"
+	"akdjr/monkey/interpreter"
 	"akdjr/monkey/lexer"
+	"akdjr/monkey/lineedit"
 	"akdjr/monkey/object"
 	"akdjr/monkey/parser"
+	"akdjr/monkey/pretty"
+	"akdjr/monkey/token"
 	"bufio"
+	"context"
 	"io"
+	"os"
+	"os/signal"
+	"strings"
 )
 
 // PROMPT is the repl line prompt
 const PROMPT = ">>"
 
+// session is the state of one REPL session
+type session struct {
+	env      *object.Environment
+	macroEnv *object.Environment
+	out      io.Writer
+	printer  *pretty.Printer
+
+	// tasks is the context the tasks spawned in the session run in.  they outlive the line that spawned them, until the session ends or Ctrl-C is pressed at the prompt
+	tasks     context.Context
+	stopTasks context.CancelFunc
+}
+
+// Options configures a session started with StartSession
+type Options struct {
+	// SessionFile is where the bindings are loaded from before the first prompt, if it exists, and saved back to when the session ends.  bindings are not kept when it is empty
+	SessionFile string
+
+	// HistoryFile is where the lines typed in are kept, so they can be recalled in later sessions.  history is not kept when it is empty
+	HistoryFile string
+}
+
 // Start reads tokens until it hits EOF
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
+	StartSession(in, out, Options{})
+}
+
+// StartSession starts the REPL the same as Start, with the bindings and history kept as options says
+// when in is a terminal, lines are read with a line editor, with history and tab completion of keywords, builtins and bindings
+func StartSession(in io.Reader, out io.Writer, options Options) {
+	s := &session{
+		out:     out,
+		printer: &pretty.Printer{Color: colorEnabled(out)},
+	}
+	s.reset("")
+
+	s.tasks, s.stopTasks = context.WithCancel(context.Background())
+	defer func() { s.stopTasks() }()
+
+	if options.SessionFile != "" {
+		if _, err := os.Stat(options.SessionFile); err == nil {
+			s.load(options.SessionFile)
+		}
+	}
+
+	readLine := s.lineReader(in, options.HistoryFile)
 
 	for {
-		io.WriteString(out, PROMPT)
-		scanned := scanner.Scan()
+		line, err := readLine(PROMPT)
+		if err == lineedit.ErrInterrupted {
+			s.stopTasks()
+			s.tasks, s.stopTasks = context.WithCancel(context.Background())
+			continue
+		}
 
-		if !scanned {
+		if err != nil {
+			if options.SessionFile != "" {
+				s.save(options.SessionFile)
+			}
 			return
 		}
 
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
-		program := p.ParseProgram()
-		errors := p.Errors()
+		// lines starting with a colon are commands for the REPL itself, not monkey code
+		if strings.HasPrefix(strings.TrimSpace(line), ":") {
+			s.command(line)
+			continue
+		}
+
+		s.eval(line)
+	}
+}
 
-		if len(errors) > 0 {
-			for _, msg := range errors {
-				io.WriteString(out, "\t"+msg+"\n")
+// lineReader returns a function that shows a prompt and reads a line from in.  terminals get a line editor, everything else is scanned a line at a time
+func (s *session) lineReader(in io.Reader, historyFile string) func(prompt string) (string, error) {
+	if f, ok := in.(*os.File); ok && lineedit.IsTerminal(int(f.Fd())) {
+		editor := lineedit.New(in, s.out)
+		editor.Complete = s.complete
+		editor.Highlight = s.printer.Source
+
+		if historyFile != "" {
+			if err := editor.History.Load(historyFile); err != nil {
+				s.printf("%s", err)
 			}
-			continue
-		} else {
-			// io.WriteString(out, program.String())
-			result := evaluator.Eval(program, env)
-			if result != nil {
-				io.WriteString(out, result.Inspect())
-			} else {
-				io.WriteString(out, "nil")
+		}
+
+		return func(prompt string) (string, error) {
+			line, err := editor.ReadLine(prompt)
+			if err == nil {
+				editor.History.Add(line)
+			}
+			return line, err
+		}
+	}
+
+	scanner := bufio.NewScanner(in)
+	return func(prompt string) (string, error) {
+		io.WriteString(s.out, prompt)
+
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return "", err
 			}
-			io.WriteString(out, "\n")
+			return "", io.EOF
 		}
+
+		return scanner.Text(), nil
 	}
 }
+
+// colorEnabled reports whether output to out should be in color: only terminals get colors, and not when the NO_COLOR environment variable is set
+func colorEnabled(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	return ok && lineedit.IsTerminal(int(f.Fd())) && os.Getenv("NO_COLOR") == ""
+}
+
+// globals creates the environment for the bindings of a session.  it is enclosed in an environment of its own with the builtins of the interpreter, so they are there to call and complete, but are neither listed nor saved with the bindings
+func (s *session) globals() *object.Environment {
+	builtins := object.NewEnvironment()
+	interpreter.DefineBuiltins(builtins, s.out, os.Stderr)
+
+	return object.NewEnclosedEnvironment(builtins)
+}
+
+// complete returns what word could be completed to: REPL commands for a word starting with a colon, otherwise keywords, builtins and bindings
+func (s *session) complete(word string) []string {
+	if strings.HasPrefix(word, ":") {
+		candidates := []string{}
+		for name := range commands {
+			candidates = append(candidates, ":"+name)
+		}
+		return candidates
+	}
+
+	candidates := append(token.Keywords(), evaluator.Builtins()...)
+	for _, env := range []*object.Environment{s.env, s.macroEnv} {
+		for ; env != nil; env = env.Outer() {
+			candidates = append(candidates, env.Names()...)
+		}
+	}
+
+	return candidates
+}
+
+// eval evaluates a line of monkey code and prints the result.  declarations have no result, nothing is printed for them unless they fail
+func (s *session) eval(line string) {
+	result, value, ok := s.evaluate(line)
+	if !ok || !value {
+		return
+	}
+
+	s.print(result)
+}
+
+// evaluate evaluates a line of monkey code in the session.  it reports false if the line could not be evaluated at all, after printing why
+// value reports whether the line has a value to show: a line that ends in an expression does, a line that ends in a declaration only does when it fails.  the value of an expression is bound to _, so the next line can use it
+func (s *session) evaluate(line string) (result object.Object, value bool, ok bool) {
+	program, ok := s.parse(line)
+	if !ok {
+		return nil, false, false
+	}
+
+	// Ctrl-C interrupts the expansion and evaluation of this line instead of the whole session.  the tasks the line spawns keep running after it
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	e := evaluator.New()
+	e.TaskContext = s.tasks
+
+	evaluator.DefineMacros(program, s.macroEnv)
+	expanded, err := e.ExpandMacrosContext(ctx, program, s.macroEnv)
+	if err != nil {
+		io.WriteString(s.out, "\t"+err.Error()+"\n")
+		return nil, false, false
+	}
+
+	result = e.EvalContext(ctx, expanded, s.env)
+	stop()
+
+	if _, isError := result.(*object.Error); isError {
+		return result, true, true
+	}
+
+	if !endsInExpression(expanded) {
+		return result, false, true
+	}
+
+	s.env.Set("_", result)
+	return result, true, true
+}
+
+// endsInExpression reports whether the value of program is the value of an expression, rather than a program that only declares things
+func endsInExpression(program ast.Node) bool {
+	p, ok := program.(*ast.Program)
+	if !ok || len(p.Statements) == 0 {
+		return false
+	}
+
+	switch p.Statements[len(p.Statements)-1].(type) {
+	case *ast.LetStatement:
+		return false
+	default:
+		return true
+	}
+}
+
+// parse parses a line of monkey code, printing the parser errors if there are any
+func (s *session) parse(line string) (*ast.Program, bool) {
+	l := lexer.New(line)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	errors := p.Errors()
+
+	if len(errors) > 0 {
+		for _, msg := range errors {
+			io.WriteString(s.out, "\t"+msg+"\n")
+		}
+		return nil, false
+	}
+
+	return program, true
+}
+
+// print prints the result of evaluating a line, pretty printed to fit the terminal
+func (s *session) print(result object.Object) {
+	if f, ok := s.out.(*os.File); ok {
+		s.printer.Width = lineedit.Width(int(f.Fd()))
+	}
+
+	io.WriteString(s.out, s.printer.Object(result)+"\n")
+}
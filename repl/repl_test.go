@@ -0,0 +1,176 @@
+package repl
+
+import (
+	"akdjr/monkey/object"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	var out bytes.Buffer
+	Start(strings.NewReader("let double = fn(x) { x * 2 };\nlet c = channel();\n:save "+path+"\n"), &out)
+
+	if !strings.Contains(out.String(), "\tnot saved, these values only exist while the session runs: c\n") {
+		t.Errorf("skipped values are not reported. got=%q", out.String())
+	}
+
+	out.Reset()
+	Start(strings.NewReader(":load "+path+"\ndouble(21)\n"), &out)
+
+	if out.String() != ">>>>42\n>>" {
+		t.Errorf("wrong output after :load. got=%q", out.String())
+	}
+}
+
+func TestSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	var out bytes.Buffer
+	StartSession(strings.NewReader("let answer = 42;\n"), &out, Options{SessionFile: path})
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("the session was not saved: %s", err)
+	}
+
+	out.Reset()
+	StartSession(strings.NewReader("answer\n"), &out, Options{SessionFile: path})
+
+	if out.String() != ">>42\n>>" {
+		t.Errorf("the session was not loaded. got=%q", out.String())
+	}
+}
+
+func TestResults(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 5;", ""},
+		{"let x = 5; x", "5\n"},
+		{"let x = 1 + true;", "ERROR: type mismatch: INTEGER + BOOLEAN\n"},
+		{"let m = macro(x) { x };", ""},
+		{"if (false) { 1 }", "null\n"},
+		{"return 7; 8", "7\n"},
+		{"6 * 7\n_ + 1\n_ * 2", "42\n" + PROMPT + "43\n" + PROMPT + "86\n"},
+		{"6 * 7\nlet y = 1;\n_", "42\n" + PROMPT + PROMPT + "42\n"},
+		{"6 * 7\n-true\n_", "42\n" + PROMPT + "ERROR: unknown operator: -BOOLEAN\n" + PROMPT + "42\n"},
+		{"_", "ERROR: identifier not found: _\n"},
+		{":type let x = 1;\n_", "\tno value\n" + PROMPT + "ERROR: identifier not found: _\n"},
+		{"puts(6 * 7)", "42\nnull\n"},
+		// a task outlives the line that spawned it
+		{"let c = channel(1); let t = spawn fn() { recv(c) * 2 };\nsend(c, 21); wait(t)", PROMPT + "42\n"},
+		{":reset\nputs(1)", PROMPT + "1\nnull\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(tt.input+"\n"), &out)
+
+		got := strings.TrimSuffix(strings.TrimPrefix(out.String(), PROMPT), PROMPT)
+		if got != tt.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{":nonsense", "\tunknown command :nonsense, try :help\n"},
+		{":save", "\tusage: :save <file>\n"},
+		{":load", "\tusage: :load <file>\n"},
+		{":help", "\t:ast <code>      print the syntax tree of code\n\t:color [on|off]  turn colors on or off, or show whether they are on\n\t:env             list all bindings\n\t:help            list the REPL commands\n" +
+			"\t:load <file>     load the bindings saved in file\n\t:reset           forget all bindings\n\t:save <file>     save all bindings to file\n" +
+			"\t:time <expr>     evaluate expr and report how long it took and how much it allocated\n\t:tokens <code>   list the tokens of code\n" +
+			"\t:type <expr>     evaluate expr and print the type of its value\n"},
+		{":tokens let x = 5;", "\t1:1    LET          let\n\t1:5    IDENTIFIER   x\n\t1:7    ASSIGN       =\n\t1:9    INT          5\n\t1:10   SEMICOLON    ;\n"},
+		{":tokens 1 @", "\t1:1    INT          1\n\t1:3    ILLEGAL      @\n"},
+		{":ast -a + 2 * b", "\tProgram\n\t  ExpressionStatement\n\t    InfixExpression +\n\t      PrefixExpression -\n\t        Identifier a\n" +
+			"\t      InfixExpression *\n\t        IntegerLiteral 2\n\t        Identifier b\n"},
+		{":ast let = 1", "\texpected next token to be IDENTIFIER, got ASSIGN instead\n\tno prefix parse function for 'ASSIGN' found\n"},
+		{":type 1 < 2", "\tBOOLEAN\n"},
+		{":type fn(x) { x }", "\tFUNCTION\n"},
+		{":type let x = 1;", "\tno value\n"},
+		{":color", "\tcolors are off\n"},
+		{":color on\n:color", PROMPT + "\tcolors are on\n"},
+		{":color on\n1 == 1", PROMPT + "\x1b[36mtrue\x1b[0m\n"},
+		{":color blue", "\tusage: :color [on|off]\n"},
+		{"fn(x) { x * 2 }", "fn(x) {\n    x * 2;\n}\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(tt.input+"\n"), &out)
+
+		got := strings.TrimSuffix(strings.TrimPrefix(out.String(), PROMPT), PROMPT)
+		if got != tt.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	var out bytes.Buffer
+	s := &session{out: &out}
+	s.reset("")
+	s.env.Set("answer", &object.Integer{Value: 42})
+
+	candidates := s.complete("")
+	for _, name := range []string{"let", "channel", "puts", "eputs", "answer"} {
+		found := false
+		for _, candidate := range candidates {
+			found = found || candidate == name
+		}
+
+		if !found {
+			t.Errorf("%s is not completed. got=%q", name, candidates)
+		}
+	}
+}
+
+func TestSessionCommands(t *testing.T) {
+	input := `let add = fn(a, b) { a + b };
+let unless = macro(c, body) { quote(if (!(unquote(c))) { unquote(body) }) };
+let x = 5;
+:env
+:time add(x, 1)
+:reset
+:env
+x
+`
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := []string{
+		">>>>>>>>\tunless = macro(c, body) { quote(if (!unquote(c)) unquote(body)) }",
+		"\tadd = fn(a, b) { (a + b) }",
+		"\tx = 5",
+		">>6",
+		"\ttime: ",
+		">>>>>>ERROR: identifier not found: x",
+		">>",
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("wrong number of lines. expected=%d, got=%d (%q)", len(expected), len(lines), out.String())
+	}
+
+	for i, want := range expected {
+		if !strings.HasPrefix(lines[i], want) {
+			t.Errorf("wrong line %d. expected=%q, got=%q", i, want, lines[i])
+		}
+	}
+
+	if !strings.Contains(lines[4], ", allocations: ") {
+		t.Errorf(":time does not report allocations. got=%q", lines[4])
+	}
+}
@@ -0,0 +1,394 @@
+package snapshot
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/evaluator"
+	"akdjr/monkey/object"
+	"akdjr/monkey/token"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Version is the version of the snapshot format written by Save.  Load refuses snapshots of any other version
+const Version = 1
+
+// the environments of a snapshot are numbered, the globals and the macros always come first.  every other environment is one that a closure was created in
+const (
+	globalsID = iota
+	macrosID
+)
+
+type snapshot struct {
+	Version      int           `json:"version"`
+	Environments []environment `json:"environments"`
+}
+
+type environment struct {
+	Outer    *int      `json:"outer,omitempty"`
+	Bindings []binding `json:"bindings"`
+}
+
+type binding struct {
+	Name  string `json:"name"`
+	Value *value `json:"value"`
+}
+
+// value is a saved object.  functions and macros refer to the environment they were created in by its number, and keep their parameters and body as the JSON encoding of their AST
+type value struct {
+	Type     object.ObjectType `json:"type"`
+	Integer  int64             `json:"integer,omitempty"`
+	Boolean  bool              `json:"boolean,omitempty"`
+	String   string            `json:"string,omitempty"`
+	Elements []*value          `json:"elements,omitempty"`
+	Pairs    []pair            `json:"pairs,omitempty"`
+	Node     json.RawMessage   `json:"node,omitempty"`
+	Env      int               `json:"env,omitempty"`
+}
+
+type pair struct {
+	Key   *value `json:"key"`
+	Value *value `json:"value"`
+}
+
+var errUnsupported = errors.New("value can not be saved")
+
+// Save writes the bindings of the globals and macros environments to w, together with every environment the functions in them were created in.  macros may be nil
+// values that only exist while the program runs, such as builtins, tasks and channels, can not be saved.  bindings of such values are left out, and their names are returned
+func Save(w io.Writer, globals, macros *object.Environment) ([]string, error) {
+	if macros == nil {
+		macros = object.NewEnvironment()
+	}
+
+	enc := &encoder{ids: map[*object.Environment]int{}}
+	enc.id(globals)
+	enc.id(macros)
+
+	// encoding a function can find environments that have not been seen yet, so keep going until every one is done
+	for i := 0; i < len(enc.queue); i++ {
+		enc.environments = append(enc.environments, enc.environment(enc.queue[i]))
+	}
+
+	encoded, err := json.MarshalIndent(snapshot{Version: Version, Environments: enc.environments}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(append(encoded, '\n')); err != nil {
+		return nil, err
+	}
+
+	return enc.skipped, nil
+}
+
+type encoder struct {
+	ids          map[*object.Environment]int
+	queue        []*object.Environment
+	environments []environment
+	skipped      []string
+}
+
+// id returns the number of env, numbering it if it has not been seen yet
+func (enc *encoder) id(env *object.Environment) int {
+	if id, ok := enc.ids[env]; ok {
+		return id
+	}
+
+	id := len(enc.queue)
+	enc.ids[env] = id
+	enc.queue = append(enc.queue, env)
+
+	return id
+}
+
+func (enc *encoder) environment(env *object.Environment) environment {
+	saved := environment{Bindings: []binding{}}
+
+	// the outer environments of the globals and the macros, such as the one the REPL defines its builtins in, belong to whoever loads the snapshot
+	if outer := env.Outer(); outer != nil && enc.ids[env] > macrosID {
+		id := enc.id(outer)
+		saved.Outer = &id
+	}
+
+	for _, name := range env.Names() {
+		obj, _ := env.Get(name)
+
+		v, err := enc.value(obj)
+		if err != nil {
+			enc.skipped = append(enc.skipped, name)
+			continue
+		}
+
+		saved.Bindings = append(saved.Bindings, binding{Name: name, Value: v})
+	}
+
+	return saved
+}
+
+func (enc *encoder) value(obj object.Object) (*value, error) {
+	v := &value{Type: obj.Type()}
+
+	switch obj := obj.(type) {
+	case *object.Null:
+	case *object.Integer:
+		v.Integer = obj.Value
+	case *object.Boolean:
+		v.Boolean = obj.Value
+	case *object.String:
+		v.String = obj.Value
+	case *object.Array:
+		for _, e := range obj.Elements {
+			element, err := enc.value(e)
+			if err != nil {
+				return nil, err
+			}
+
+			v.Elements = append(v.Elements, element)
+		}
+	case *object.Hash:
+		for _, p := range obj.Pairs {
+			key, err := enc.value(p.Key)
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := enc.value(p.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			v.Pairs = append(v.Pairs, pair{Key: key, Value: value})
+		}
+
+		// maps have no order, sort the pairs so the same hash is always saved the same
+		sort.Slice(v.Pairs, func(i, j int) bool {
+			return v.Pairs[i].Key.inspect() < v.Pairs[j].Key.inspect()
+		})
+	case *object.Quote:
+		node, err := ast.EncodeJSON(obj.Node)
+		if err != nil {
+			return nil, err
+		}
+
+		v.Node = node
+	case *object.Function:
+		literal := &ast.FunctionLiteral{
+			Token:      token.Token{Type: token.FUNCTION, Literal: "fn"},
+			Parameters: obj.Parameters,
+			Body:       obj.Body,
+		}
+
+		node, err := ast.EncodeJSON(literal)
+		if err != nil {
+			return nil, err
+		}
+
+		v.Node = node
+		v.Env = enc.id(obj.Env)
+	case *object.Macro:
+		literal := &ast.MacroLiteral{
+			Token:      token.Token{Type: token.MACRO, Literal: "macro"},
+			Parameters: obj.Parameters,
+			Body:       obj.Body,
+		}
+
+		node, err := ast.EncodeJSON(literal)
+		if err != nil {
+			return nil, err
+		}
+
+		v.Node = node
+		v.Env = enc.id(obj.Env)
+	default:
+		return nil, errUnsupported
+	}
+
+	return v, nil
+}
+
+// inspect returns a key that orders saved hash keys, integers before booleans before strings
+func (v *value) inspect() string {
+	return fmt.Sprintf("%s %d %t %s", v.Type, v.Integer, v.Boolean, v.String)
+}
+
+// Load restores a snapshot written by Save.  The saved globals and macros are set in globals and macros, replacing bindings with the same names, and the environments that saved functions were created in are recreated around them.  macros may be nil, in which case saved macros are not restored
+func Load(r io.Reader, globals, macros *object.Environment) error {
+	var saved snapshot
+	if err := json.NewDecoder(r).Decode(&saved); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+
+	if saved.Version != Version {
+		return fmt.Errorf("snapshot: unsupported version %d, want %d", saved.Version, Version)
+	}
+
+	if len(saved.Environments) < 2 {
+		return fmt.Errorf("snapshot: missing the globals and macros environments")
+	}
+
+	if macros == nil {
+		macros = object.NewEnvironment()
+	}
+
+	dec := &decoder{saved: saved.Environments, environments: make([]*object.Environment, len(saved.Environments))}
+	dec.environments[globalsID] = globals
+	dec.environments[macrosID] = macros
+
+	for id := range saved.Environments {
+		if _, err := dec.environment(id, 0); err != nil {
+			return err
+		}
+	}
+
+	// all environments exist now, so functions can refer to any of them
+	for id, env := range saved.Environments {
+		for _, b := range env.Bindings {
+			obj, err := dec.value(b.Value)
+			if err != nil {
+				return fmt.Errorf("snapshot: %s: %w", b.Name, err)
+			}
+
+			dec.environments[id].Set(b.Name, obj)
+		}
+	}
+
+	return nil
+}
+
+type decoder struct {
+	saved        []environment
+	environments []*object.Environment
+}
+
+// environment returns the environment numbered id, creating it and the environments around it when needed.  depth guards against environments that enclose themselves
+func (dec *decoder) environment(id int, depth int) (*object.Environment, error) {
+	if id < 0 || id >= len(dec.environments) {
+		return nil, fmt.Errorf("snapshot: unknown environment %d", id)
+	}
+
+	if env := dec.environments[id]; env != nil {
+		return env, nil
+	}
+
+	if depth > len(dec.environments) {
+		return nil, fmt.Errorf("snapshot: environment %d encloses itself", id)
+	}
+
+	var env *object.Environment
+	if outer := dec.saved[id].Outer; outer != nil {
+		enclosing, err := dec.environment(*outer, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		env = object.NewEnclosedEnvironment(enclosing)
+	} else {
+		env = object.NewEnvironment()
+	}
+
+	dec.environments[id] = env
+
+	return env, nil
+}
+
+func (dec *decoder) value(v *value) (object.Object, error) {
+	if v == nil {
+		return nil, fmt.Errorf("missing value")
+	}
+
+	switch v.Type {
+	case object.NULL_OBJ:
+		return evaluator.NULL, nil
+	case object.INTEGER_OBJ:
+		return &object.Integer{Value: v.Integer}, nil
+	case object.BOOLEAN_OBJ:
+		if v.Boolean {
+			return evaluator.TRUE, nil
+		}
+
+		return evaluator.FALSE, nil
+	case object.STRING_OBJ:
+		return &object.String{Value: v.String}, nil
+	case object.ARRAY_OBJ:
+		elements := make([]object.Object, 0, len(v.Elements))
+
+		for _, e := range v.Elements {
+			element, err := dec.value(e)
+			if err != nil {
+				return nil, err
+			}
+
+			elements = append(elements, element)
+		}
+
+		return &object.Array{Elements: elements}, nil
+	case object.HASH_OBJ:
+		pairs := make(map[object.HashKey]object.HashPair, len(v.Pairs))
+
+		for _, p := range v.Pairs {
+			key, err := dec.value(p.Key)
+			if err != nil {
+				return nil, err
+			}
+
+			hashable, ok := key.(object.Hashable)
+			if !ok {
+				return nil, fmt.Errorf("%s can not be used as a hash key", key.Type())
+			}
+
+			value, err := dec.value(p.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			pairs[hashable.HashKey()] = object.HashPair{Key: key, Value: value}
+		}
+
+		return &object.Hash{Pairs: pairs}, nil
+	case object.QUOTE_OBJ:
+		node, err := ast.DecodeJSON(v.Node)
+		if err != nil {
+			return nil, err
+		}
+
+		return &object.Quote{Node: node}, nil
+	case object.FUNCTION_OBJ:
+		node, err := ast.DecodeJSON(v.Node)
+		if err != nil {
+			return nil, err
+		}
+
+		literal, ok := node.(*ast.FunctionLiteral)
+		if !ok {
+			return nil, fmt.Errorf("function is a %T, not a function literal", node)
+		}
+
+		env, err := dec.environment(v.Env, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		return &object.Function{Parameters: literal.Parameters, Body: literal.Body, Env: env}, nil
+	case object.MACRO_OBJ:
+		node, err := ast.DecodeJSON(v.Node)
+		if err != nil {
+			return nil, err
+		}
+
+		literal, ok := node.(*ast.MacroLiteral)
+		if !ok {
+			return nil, fmt.Errorf("macro is a %T, not a macro literal", node)
+		}
+
+		env, err := dec.environment(v.Env, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		return &object.Macro{Parameters: literal.Parameters, Body: literal.Body, Env: env}, nil
+	}
+
+	return nil, fmt.Errorf("unknown type %s", v.Type)
+}
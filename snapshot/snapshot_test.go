@@ -0,0 +1,138 @@
+package snapshot
+
+import (
+	"akdjr/monkey/evaluator"
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/object"
+	"akdjr/monkey/parser"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// run evaluates input in env, defining and expanding macros the same as the REPL
+func run(t *testing.T, input string, env, macros *object.Environment) object.Object {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser has errors: %v", p.Errors())
+	}
+
+	evaluator.DefineMacros(program, macros)
+	expanded, err := evaluator.ExpandMacros(program, macros)
+	if err != nil {
+		t.Fatalf("ExpandMacros returned an error: %s", err)
+	}
+
+	return evaluator.Eval(expanded, env)
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	env := object.NewEnvironment()
+	macros := object.NewEnvironment()
+
+	run(t, `
+let answer = 42;
+let yes = !false;
+let nothing = if (false) { 1 };
+let newAdder = fn(x) { fn(y) { x + y } };
+let addTwo = newAdder(2);
+let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+let code = quote(1 + unquote(answer));
+let unless = macro(c, body) { quote(if (!(unquote(c))) { unquote(body) }) };
+let ch = channel();
+`, env, macros)
+
+	env.Set("name", &object.String{Value: "monkey"})
+	env.Set("list", &object.Array{Elements: []object.Object{&object.Integer{Value: 1}, env.Set("inner", &object.String{Value: "two"})}})
+
+	key := &object.String{Value: "key"}
+	env.Set("table", &object.Hash{Pairs: map[object.HashKey]object.HashPair{
+		key.HashKey():                         {Key: key, Value: &object.Integer{Value: 1}},
+		(&object.Integer{Value: 2}).HashKey(): {Key: &object.Integer{Value: 2}, Value: evaluator.TRUE},
+	}})
+
+	var buf bytes.Buffer
+	skipped, err := Save(&buf, env, macros)
+	if err != nil {
+		t.Fatalf("Save returned an error: %s", err)
+	}
+
+	if len(skipped) != 1 || skipped[0] != "ch" {
+		t.Errorf("wrong names skipped. expected=[ch], got=%v", skipped)
+	}
+
+	restored := object.NewEnvironment()
+	restoredMacros := object.NewEnvironment()
+	if err := Load(strings.NewReader(buf.String()), restored, restoredMacros); err != nil {
+		t.Fatalf("Load returned an error: %s", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"answer", "42"},
+		{"yes", "true"},
+		{"nothing", "null"},
+		{"addTwo(3)", "5"},
+		{"newAdder(10)(5)", "15"},
+		{"fib(15)", "610"},
+		{"code", "QUOTE((1 + 42))"},
+		{"unless(false, answer + 1)", "43"},
+		{"name", "monkey"},
+		{"list", "[1, two]"},
+		{"table", "{2: true, key: 1}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := run(t, tt.input, restored, restoredMacros)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+
+	// the restored closures refer to the environment they were loaded into
+	run(t, "let fib = fn(n) { 0 };", restored, restoredMacros)
+	if evaluated := run(t, "let f = fn(n) { fib(n) }; f(10)", restored, restoredMacros); evaluated.Inspect() != "0" {
+		t.Errorf("restored functions do not see the environment they were loaded into. got=%s", evaluated.Inspect())
+	}
+
+	// saving the same environment twice gives the same snapshot
+	var again bytes.Buffer
+	if _, err := Save(&again, env, macros); err != nil {
+		t.Fatalf("Save returned an error: %s", err)
+	}
+
+	if again.String() != buf.String() {
+		t.Errorf("saving twice gave different snapshots")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`not json`, "snapshot: invalid character 'o' in literal null (expecting 'u')"},
+		{`{"version": 2, "environments": []}`, "snapshot: unsupported version 2, want 1"},
+		{`{"version": 1, "environments": []}`, "snapshot: missing the globals and macros environments"},
+		{`{"version": 1, "environments": [{"bindings": []}, {"bindings": []}, {"outer": 2, "bindings": []}]}`, "snapshot: environment 2 encloses itself"},
+		{`{"version": 1, "environments": [{"bindings": [{"name": "x", "value": {"type": "TASK"}}]}, {"bindings": []}]}`, "snapshot: x: unknown type TASK"},
+		{`{"version": 1, "environments": [{"bindings": [{"name": "f", "value": {"type": "FUNCTION", "node": {"kind": "Identifier", "value": "f"}}}]}, {"bindings": []}]}`, "snapshot: f: function is a *ast.Identifier, not a function literal"},
+	}
+
+	for _, tt := range tests {
+		err := Load(strings.NewReader(tt.input), object.NewEnvironment(), object.NewEnvironment())
+		if err == nil {
+			t.Errorf("expected an error loading %s", tt.input)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error loading %s. expected=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"akdjr/monkey/coverage"
+	"akdjr/monkey/tester"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// testCommand implements "monkey test [-v] [--junit file] [--cover file] [paths...]" and returns the process exit code
+// the exit code is 0 when every test passed, 1 when any failed or a test file could not run, and 2 when the arguments are wrong
+func testCommand(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	verbose := flags.Bool("v", false, "report the tests that pass too")
+	junit := flags.String("junit", "", "write a JUnit XML report of the results to `file`")
+	cover := flags.String("cover", "", "write a coverage report of the test files to `file`")
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := tester.Find(paths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stdout, "no test files")
+		return 0
+	}
+
+	r := tester.New(os.Stdout)
+	r.Verbose = *verbose
+
+	if *cover != "" {
+		r.Coverage = coverage.New()
+	}
+
+	for _, file := range files {
+		r.RunFile(file)
+	}
+
+	code := 0
+	if !r.Summary() {
+		code = 1
+	}
+
+	if *junit != "" {
+		if err := writeJUnit(r, *junit); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	if r.Coverage != nil {
+		if err := writeCoverage(r.Coverage, *cover); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	return code
+}
+
+// writeJUnit writes the JUnit XML report of the tests r ran to path
+func writeJUnit(r *tester.Runner, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = r.WriteJUnit(file)
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,117 @@
+package tester
+
+import (
+	"akdjr/monkey/diff"
+	"akdjr/monkey/evaluator"
+	"akdjr/monkey/interpreter"
+	"akdjr/monkey/object"
+	"akdjr/monkey/pretty"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Builtins returns the names of the assertion builtins every test file has defined
+func Builtins() []string {
+	return []string{"assert", "assert_eq", "assert_error"}
+}
+
+// defineBuiltins defines the assertion builtins in i
+func (r *Runner) defineBuiltins(i *interpreter.Interpreter) {
+	i.Set("assert", &object.Builtin{Fn: r.assert})
+	i.Set("assert_eq", &object.Builtin{Fn: r.assertEq})
+	i.Set("assert_error", &object.Builtin{Fn: r.assertError})
+}
+
+// fail returns the error of a failed assertion.  the message the test gave the assertion, if any, follows the first line of message
+func (r *Runner) fail(message string, args []object.Object, messageAt int) object.Object {
+	if len(args) > messageAt {
+		first, rest, _ := strings.Cut(message, "\n")
+		message = first + ": " + text(args[messageAt])
+		if rest != "" {
+			message += "\n" + rest
+		}
+	}
+
+	r.failure = &object.Error{Message: message}
+	return r.failure
+}
+
+// assert(condition) and assert(condition, message) fail unless condition is truthy
+func (r *Runner) assert(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return &object.Error{Message: fmt.Sprintf("wrong number of arguments to `assert`. got=%d, want=1 or 2", len(args))}
+	}
+
+	if !truthy(args[0]) {
+		return r.fail("assertion failed", args, 1)
+	}
+
+	return evaluator.NULL
+}
+
+// assert_eq(actual, expected) and assert_eq(actual, expected, message) fail unless actual and expected are of the same type and look the same, with a diff of the two
+func (r *Runner) assertEq(args ...object.Object) object.Object {
+	if len(args) < 2 || len(args) > 3 {
+		return &object.Error{Message: fmt.Sprintf("wrong number of arguments to `assert_eq`. got=%d, want=2 or 3", len(args))}
+	}
+
+	actual, expected := args[0], args[1]
+
+	// objects are compared by what they look like, as the language has no equality for arrays, hashes and functions.  strings are quoted, so "1" is not 1
+	printer := &pretty.Printer{}
+	a, e := printer.Object(actual), printer.Object(expected)
+
+	if actual.Type() == expected.Type() && a == e {
+		return evaluator.NULL
+	}
+
+	message := "values are not equal\n" + diff.Unified("expected", "actual", e+"\n", a+"\n")
+	if a == e {
+		message = fmt.Sprintf("values are of different types: expected %s, got %s", expected.Type(), actual.Type())
+	}
+
+	return r.fail(strings.TrimSuffix(message, "\n"), args, 2)
+}
+
+// assert_error(fn) and assert_error(fn, message) call fn with no arguments in the interpreter of the test, and fail unless it results in an error.  an assertion that fails within fn fails the test, it is not the error expected.  the message of the error is returned
+func (r *Runner) assertError(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return &object.Error{Message: fmt.Sprintf("wrong number of arguments to `assert_error`. got=%d, want=1 or 2", len(args))}
+	}
+
+	result, err := r.test.CallFunction(args[0])
+
+	var runtimeErr *interpreter.RuntimeError
+	switch {
+	case errors.As(err, &runtimeErr) && runtimeErr.Object == r.failure:
+		return r.failure
+	case errors.As(err, &runtimeErr):
+		return &object.String{Value: runtimeErr.Object.Message}
+	case err != nil:
+		return &object.Error{Message: err.Error()}
+	}
+
+	return r.fail(fmt.Sprintf("expected an error, got %s", result.Inspect()), args, 1)
+}
+
+// truthy reports whether obj counts as true in a condition, the same as in an if expression
+func truthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Null:
+		return false
+	case *object.Boolean:
+		return obj.Value
+	default:
+		return true
+	}
+}
+
+// text returns the value of a string, or what any other object looks like
+func text(obj object.Object) string {
+	if str, ok := obj.(*object.String); ok {
+		return str.Value
+	}
+
+	return obj.Inspect()
+}
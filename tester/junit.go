@@ -0,0 +1,112 @@
+package tester
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// the elements of a JUnit XML report, the format most continuous integration services read test results in
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Output    string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results of the files run so far as a JUnit XML report: a test suite for every file, with a test case for every test.  a file that could not run is a suite with a single test case, named after the file, that is an error
+func (r *Runner) WriteJUnit(w io.Writer) error {
+	report := junitSuites{}
+	var total float64
+
+	for _, f := range r.Files {
+		suite := junitSuite{Name: f.Name, Time: junitTime(f.Time.Seconds())}
+		total += f.Time.Seconds()
+
+		if f.Err != nil {
+			suite.Cases = append(suite.Cases, junitCase{
+				Name:      f.Name,
+				Classname: f.Name,
+				Time:      junitTime(0),
+				Error:     &junitProblem{Message: firstLine(f.Err.Error()), Text: f.Err.Error()},
+			})
+			suite.Errors++
+		}
+
+		for _, t := range f.Tests {
+			c := junitCase{Name: t.Name, Classname: f.Name, Time: junitTime(t.Time.Seconds()), Output: t.Output}
+
+			switch {
+			case t.Failure != "":
+				c.Failure = &junitProblem{Message: firstLine(t.Failure), Text: t.Failure}
+				suite.Failures++
+			case t.Error != "":
+				c.Error = &junitProblem{Message: firstLine(t.Error), Text: t.Error}
+				suite.Errors++
+			}
+
+			suite.Cases = append(suite.Cases, c)
+		}
+
+		suite.Tests = len(suite.Cases)
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Suites = append(report.Suites, suite)
+	}
+
+	report.Time = junitTime(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitTime(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
+
+func firstLine(text string) string {
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		return text[:i]
+	}
+
+	return text
+}
@@ -0,0 +1,352 @@
+package tester
+
+import (
+	"akdjr/monkey/ast"
+	"akdjr/monkey/coverage"
+	"akdjr/monkey/evaluator"
+	"akdjr/monkey/interpreter"
+	"akdjr/monkey/lexer"
+	"akdjr/monkey/object"
+	"akdjr/monkey/parser"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Suffix is the end of the name of every file that holds tests
+const Suffix = "_test.monkey"
+
+// Prefix is the start of the name of every function that is a test
+const Prefix = "test_"
+
+// Runner runs the tests of monkey programs.  every test file is run once to define its functions, after which each of its tests is called in a fork of the interpreter, so no test sees the globals another one defines
+// tests fail through the assertion builtins, assert, assert_eq and assert_error, which every test file has defined on top of the usual builtins.  any other error fails the test as well
+type Runner struct {
+	// Out is where the results are reported as the tests run
+	Out io.Writer
+
+	// Verbose reports the tests that pass too, not only the ones that fail
+	Verbose bool
+
+	// Coverage, when set, records the coverage of the test files as they run
+	Coverage *coverage.Coverage
+
+	// Files are the results of the files run so far, in order
+	Files []*File
+
+	// hooks are the hooks of the file being run
+	hooks *evaluator.Hooks
+
+	// test is the fork of the interpreter the current test runs in, assert_error calls functions in it
+	test *interpreter.Interpreter
+
+	// failure is the error of the last assertion that failed, to tell failed assertions apart from other errors
+	failure *object.Error
+
+	// now returns the current time, it is replaced by tests
+	now func() time.Time
+}
+
+// File is the result of running a test file.  Err is set when the file itself could not be read or run, in which case none of its tests ran
+type File struct {
+	Name  string
+	Err   error
+	Tests []*Test
+	Time  time.Duration
+}
+
+// Test is the result of a test.  Failure is the message of the assertion that failed, Error is the message of any other error that stopped it, and Output is what it printed
+type Test struct {
+	Name    string
+	Failure string
+	Error   string
+	Output  string
+	Time    time.Duration
+}
+
+// Passed reports whether t passed
+func (t *Test) Passed() bool {
+	return t.Failure == "" && t.Error == ""
+}
+
+// Passed reports whether f ran and all of its tests passed
+func (f *File) Passed() bool {
+	if f.Err != nil {
+		return false
+	}
+
+	for _, t := range f.Tests {
+		if !t.Passed() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// New creates a Runner that reports to out
+func New(out io.Writer) *Runner {
+	return &Runner{Out: out, now: time.Now}
+}
+
+// Find returns the test files in paths, in order.  directories are searched recursively for files named with Suffix, other paths are taken to be test files whatever their name
+func Find(paths []string) ([]string, error) {
+	files := []string{}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		found := []string{}
+		err = filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !d.IsDir() && strings.HasSuffix(d.Name(), Suffix) {
+				found = append(found, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+
+	return files, nil
+}
+
+// RunFile runs the tests in the file at path
+func (r *Runner) RunFile(path string) *File {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		f := &File{Name: path, Err: err}
+		r.Files = append(r.Files, f)
+		r.report(f)
+
+		return f
+	}
+
+	return r.Run(path, string(source))
+}
+
+// Run runs the tests in source, the test file named name.  the tests are the top level let statements that bind a function to a name starting with Prefix, and they run in the order they are defined
+func (r *Runner) Run(name string, source string) *File {
+	f := &File{Name: name}
+	r.Files = append(r.Files, f)
+
+	start := r.now()
+	defer func() {
+		f.Time = r.now().Sub(start)
+		r.report(f)
+	}()
+
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+
+	if errors := p.Errors(); len(errors) > 0 {
+		f.Err = &interpreter.ParseError{Errors: errors}
+		return f
+	}
+
+	r.hooks = nil
+	if r.Coverage != nil {
+		r.hooks = r.Coverage.Hooks(name, source)
+	}
+
+	i := interpreter.New()
+	i.Hooks = r.hooks
+	r.defineBuiltins(i)
+
+	// the output of the file itself goes with the first test, or with the error it failed with
+	var output bytes.Buffer
+	i.Stdout = &output
+	i.Stderr = &output
+
+	if _, err := i.Run(source); err != nil {
+		var runtimeErr *interpreter.RuntimeError
+		if errors.As(err, &runtimeErr) {
+			err = errors.New(runtimeErr.Object.Inspect())
+		}
+
+		f.Err = err
+		return f
+	}
+
+	for _, test := range testNames(program) {
+		if fn, ok := i.Get(test); ok && fn.Type() == object.FUNCTION_OBJ {
+			f.Tests = append(f.Tests, r.runTest(i, test, &output))
+		}
+	}
+
+	return f
+}
+
+// testNames returns the names of the tests defined in program, in order
+func testNames(program *ast.Program) []string {
+	names := []string{}
+	seen := map[string]bool{}
+
+	for _, stmt := range program.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || !strings.HasPrefix(let.Name.Value, Prefix) || seen[let.Name.Value] {
+			continue
+		}
+
+		if _, ok := let.Value.(*ast.FunctionLiteral); ok {
+			names = append(names, let.Name.Value)
+			seen[let.Name.Value] = true
+		}
+	}
+
+	return names
+}
+
+// runTest calls the test name in a fork of i.  output holds what was printed before the test started, it is emptied once the test has it
+func (r *Runner) runTest(i *interpreter.Interpreter, name string, output *bytes.Buffer) *Test {
+	t := &Test{Name: name}
+
+	r.test = i.Fork()
+	r.test.Hooks = r.hooks
+	r.test.Stdout = output
+	r.test.Stderr = output
+
+	r.failure = nil
+	start := r.now()
+
+	err := r.call(name)
+
+	t.Time = r.now().Sub(start)
+	t.Output = output.String()
+	output.Reset()
+
+	var runtimeErr *interpreter.RuntimeError
+	switch {
+	case errors.As(err, &runtimeErr) && runtimeErr.Object == r.failure:
+		t.Failure = runtimeErr.Object.Message
+	case errors.As(err, &runtimeErr):
+		t.Error = runtimeErr.Object.Inspect()
+	case err != nil:
+		t.Error = err.Error()
+	}
+
+	return t
+}
+
+// call calls the test name in r.test.  a panic, which the interpreter turns into an error as well, is caught here as a last resort, so it fails the test rather than ending the whole run
+func (r *Runner) call(name string) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("internal error: %v", p)
+		}
+	}()
+
+	_, err = r.test.Call(name)
+	return err
+}
+
+// report writes the result of f to r.Out: the tests that failed with why they did and what they printed, every test when r.Verbose is set, and a line for the file
+func (r *Runner) report(f *File) {
+	if f.Err != nil {
+		fmt.Fprintf(r.Out, "FAIL\t%s\n%s\n", f.Name, indent(f.Err.Error()))
+		return
+	}
+
+	passed := 0
+	for _, t := range f.Tests {
+		if t.Passed() {
+			passed++
+		}
+
+		if t.Passed() && !r.Verbose {
+			continue
+		}
+
+		status := "PASS"
+		if !t.Passed() {
+			status = "FAIL"
+		}
+
+		fmt.Fprintf(r.Out, "--- %s: %s (%s)\n", status, t.Name, seconds(t.Time))
+
+		for _, text := range []string{t.Output, t.Failure, t.Error} {
+			if text != "" {
+				fmt.Fprintln(r.Out, indent(text))
+			}
+		}
+	}
+
+	status := "ok"
+	if passed < len(f.Tests) {
+		status = "FAIL"
+	}
+
+	fmt.Fprintf(r.Out, "%s\t%s\t%s\t%d passed, %d failed\n", status, f.Name, seconds(f.Time), passed, len(f.Tests)-passed)
+}
+
+// Summary writes a line with the number of tests that passed and failed in all files, and reports whether they all passed
+func (r *Runner) Summary() bool {
+	passed, failed, broken := 0, 0, 0
+
+	for _, f := range r.Files {
+		if f.Err != nil {
+			broken++
+		}
+
+		for _, t := range f.Tests {
+			if t.Passed() {
+				passed++
+			} else {
+				failed++
+			}
+		}
+	}
+
+	status := "PASS"
+	if failed > 0 || broken > 0 {
+		status = "FAIL"
+	}
+
+	fmt.Fprintf(r.Out, "%s: %d passed, %d failed", status, passed, failed)
+	if broken > 0 {
+		fmt.Fprintf(r.Out, ", %d %s could not run", broken, plural(broken, "file", "files"))
+	}
+	fmt.Fprintln(r.Out)
+
+	return status == "PASS"
+}
+
+func plural(n int, one string, many string) string {
+	if n == 1 {
+		return one
+	}
+
+	return many
+}
+
+// indent indents every line of text by four spaces
+func indent(text string) string {
+	return "    " + strings.ReplaceAll(strings.TrimSuffix(text, "\n"), "\n", "\n    ")
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3fs", d.Seconds())
+}
@@ -0,0 +1,261 @@
+package tester
+
+import (
+	"akdjr/monkey/coverage"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newRunner creates a Runner with a clock that never moves, so the times reported are always 0
+func newRunner(out *bytes.Buffer) *Runner {
+	r := New(out)
+	r.now = func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	return r
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		input   string
+		failure string
+		err     string
+		output  string
+	}{
+		{"let test_pass = fn() { assert(true); assert_eq(1 + 1, 2) };", "", "", ""},
+		{"let test_assert = fn() { assert(false) };", "assertion failed", "", ""},
+		{"let test_assert = fn() { assert(1 > 2, 42) };", "assertion failed: 42", "", ""},
+		{"let test_eq = fn() { assert_eq(1, 2) };", "values are not equal\n--- expected\n+++ actual\n@@ -1 +1 @@\n-2\n+1", "", ""},
+		{"let test_eq = fn() { assert_eq(1, true, 7) };", "values are not equal: 7\n--- expected\n+++ actual\n@@ -1 +1 @@\n-true\n+1", "", ""},
+		{"let test_eq = fn() { assert_eq(fn(x) { x }, fn(x) { x }) };", "", "", ""},
+		{"let test_error = fn() { puts(assert_error(fn() { -true })) };", "", "", "unknown operator: -BOOLEAN\n"},
+		{"let test_error = fn() { assert_error(fn() { 1 }) };", "expected an error, got 1", "", ""},
+		{"let test_error = fn() { assert_error(fn() { 1 }, 42) };", "expected an error, got 1: 42", "", ""},
+		// an assertion that fails inside assert_error fails the test, it is not the error assert_error expects
+		{"let test_error = fn() { assert_error(fn() { assert(false) }); assert(true) };", "assertion failed", "", ""},
+		{"let test_error = fn() { assert_error(fn() { 1 }, 2, 3) };", "", "ERROR: wrong number of arguments to `assert_error`. got=3, want=1 or 2", ""},
+		{"let test_error = fn() { 1 + true };", "", "ERROR: type mismatch: INTEGER + BOOLEAN", ""},
+		{"let test_error = fn() { assert(1, 2, 3) };", "", "ERROR: wrong number of arguments to `assert`. got=3, want=1 or 2", ""},
+		{"puts(1);\nlet test_output = fn() { puts(2) };", "", "", "1\n2\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		f := newRunner(&out).Run("example_test.monkey", tt.input)
+
+		if f.Err != nil {
+			t.Fatalf("unexpected error for %q: %s", tt.input, f.Err)
+		}
+
+		if len(f.Tests) != 1 {
+			t.Fatalf("wrong number of tests for %q. got=%d", tt.input, len(f.Tests))
+		}
+
+		test := f.Tests[0]
+		if test.Failure != tt.failure {
+			t.Errorf("wrong failure for %q.\nexpected=%q\ngot=%q", tt.input, tt.failure, test.Failure)
+		}
+
+		if test.Error != tt.err {
+			t.Errorf("wrong error for %q.\nexpected=%q\ngot=%q", tt.input, tt.err, test.Error)
+		}
+
+		if test.Output != tt.output {
+			t.Errorf("wrong output for %q.\nexpected=%q\ngot=%q", tt.input, tt.output, test.Output)
+		}
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	input := `let helper = fn() { 1 };
+let test_b = fn() { assert(true) };
+let test_a = fn() { assert(true) };
+let test_not_a_function = 1;
+let testing = fn() { assert(false) };`
+
+	var out bytes.Buffer
+	f := newRunner(&out).Run("example_test.monkey", input)
+
+	names := []string{}
+	for _, test := range f.Tests {
+		names = append(names, test.Name)
+	}
+
+	if expected := []string{"test_b", "test_a"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("wrong tests. expected=%q, got=%q", expected, names)
+	}
+}
+
+func TestIsolation(t *testing.T) {
+	input := `let test_a = fn() {
+  let x = 1;
+  assert_eq(x, 1)
+};
+let test_b = fn() {
+  assert_error(fn() { x })
+};`
+
+	var out bytes.Buffer
+	f := newRunner(&out).Run("example_test.monkey", input)
+
+	if !f.Passed() {
+		t.Errorf("a test saw the bindings of another.\n%s", out.String())
+	}
+}
+
+func TestFileErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let test_a = fn() { assert(true) };\nlet x = ;", "no prefix parse function for 'SEMICOLON' found"},
+		{"let test_a = fn() { assert(true) };\n1 + true;", "ERROR: type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		f := newRunner(&out).Run("example_test.monkey", tt.input)
+
+		if f.Err == nil || !strings.Contains(f.Err.Error(), tt.expected) {
+			t.Errorf("wrong error for %q. expected=%q, got=%v", tt.input, tt.expected, f.Err)
+		}
+
+		if len(f.Tests) != 0 || f.Passed() {
+			t.Errorf("the tests of a file that could not run ran for %q", tt.input)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	var out bytes.Buffer
+	r := newRunner(&out)
+
+	r.Run("a_test.monkey", "let test_pass = fn() { assert(true) };\nlet test_fail = fn() { puts(1); assert(false) };")
+	r.Run("b_test.monkey", "let test_pass = fn() { assert(true) };")
+	r.Run("c_test.monkey", "let = ;")
+
+	if r.Summary() {
+		t.Errorf("the tests passed")
+	}
+
+	expected := `--- FAIL: test_fail (0.000s)
+    1
+    assertion failed
+FAIL	a_test.monkey	0.000s	1 passed, 1 failed
+ok	b_test.monkey	0.000s	1 passed, 0 failed
+FAIL	c_test.monkey
+    expected next token to be IDENTIFIER, got ASSIGN instead
+    no prefix parse function for 'ASSIGN' found
+FAIL: 2 passed, 1 failed, 1 file could not run
+`
+
+	if out.String() != expected {
+		t.Errorf("wrong report.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+
+	out.Reset()
+	r = newRunner(&out)
+	r.Verbose = true
+
+	r.Run("b_test.monkey", "let test_pass = fn() { assert(true) };")
+
+	if !r.Summary() {
+		t.Errorf("the tests failed")
+	}
+
+	expected = "--- PASS: test_pass (0.000s)\nok\tb_test.monkey\t0.000s\t1 passed, 0 failed\nPASS: 1 passed, 0 failed\n"
+	if out.String() != expected {
+		t.Errorf("wrong verbose report.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestJUnit(t *testing.T) {
+	var out bytes.Buffer
+	r := newRunner(&out)
+
+	r.Run("a_test.monkey", "let test_pass = fn() { assert(true) };\nlet test_fail = fn() { puts(1); assert(false) };\nlet test_error = fn() { -true };")
+	r.Run("b_test.monkey", "let = ;")
+
+	var report bytes.Buffer
+	if err := r.WriteJUnit(&report); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="4" failures="1" errors="2" time="0.000">
+  <testsuite name="a_test.monkey" tests="3" failures="1" errors="1" time="0.000">
+    <testcase name="test_pass" classname="a_test.monkey" time="0.000"></testcase>
+    <testcase name="test_fail" classname="a_test.monkey" time="0.000">
+      <failure message="assertion failed">assertion failed</failure>
+      <system-out>1&#xA;</system-out>
+    </testcase>
+    <testcase name="test_error" classname="a_test.monkey" time="0.000">
+      <error message="ERROR: unknown operator: -BOOLEAN">ERROR: unknown operator: -BOOLEAN</error>
+    </testcase>
+  </testsuite>
+  <testsuite name="b_test.monkey" tests="1" failures="0" errors="1" time="0.000">
+    <testcase name="b_test.monkey" classname="b_test.monkey" time="0.000">
+      <error message="expected next token to be IDENTIFIER, got ASSIGN instead">expected next token to be IDENTIFIER, got ASSIGN instead&#xA;no prefix parse function for &#39;ASSIGN&#39; found</error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+
+	if report.String() != expected {
+		t.Errorf("wrong report.\nexpected=%s\ngot=%s", expected, report.String())
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	var out bytes.Buffer
+	r := newRunner(&out)
+	r.Coverage = coverage.New()
+
+	r.Run("a_test.monkey", `let max = fn(a, b) { if (a > b) { a } else { b } };
+let test_max = fn() { assert_eq(max(2, 1), 2) };
+let test_error = fn() { assert_error(fn() { max(1, 2) + true }) };`)
+
+	// the branch taken inside assert_error counts as well
+	if s := r.Coverage.Summary(); s.Statements != 9 || s.StatementsRun != 9 || s.BranchesTaken != 2 {
+		t.Errorf("wrong coverage. got=%+v", s)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"b_test.monkey", "a_test.monkey", "sub/c_test.monkey", "main.monkey", "test.monkey"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := Find([]string{dir, filepath.Join(dir, "main.monkey")})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "a_test.monkey"),
+		filepath.Join(dir, "b_test.monkey"),
+		filepath.Join(dir, "sub/c_test.monkey"),
+		filepath.Join(dir, "main.monkey"),
+	}
+
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("wrong files.\nexpected=%q\ngot=%q", expected, files)
+	}
+
+	if _, err := Find([]string{filepath.Join(dir, "missing")}); err == nil {
+		t.Errorf("expected an error for a missing path")
+	}
+}
@@ -1,5 +1,7 @@
 package token
 
+import "sort"
+
 // TokenType holdes the type of the token.  String for now
 // TODO: could change to int
 type TokenType string
@@ -7,10 +9,21 @@ type TokenType string
 // Token represents a single token in the source
 // Type is the type of token
 // Literal is the literal value of the token (such as the name of the identifier or the value of a literal).  It is the sequence of characters directly taken from the input
-// TODO: Add some more information, such as line number, column number for enhanced error reporting
+// Line and Column are the 1-based position of the first character of the token.  Columns are counted in runes, not bytes
+// Comments holds the comments found between the previous token and this one.  It is only filled in when the lexer is asked to keep comments
 type Token struct {
-	Type    TokenType
-	Literal string
+	Type     TokenType
+	Literal  string
+	Line     int
+	Column   int
+	Comments []Comment
+}
+
+// Comment represents a // line comment or a /* */ block comment.  Text is the comment exactly as it appears in the source, including the comment markers
+type Comment struct {
+	Text   string
+	Line   int
+	Column int
 }
 
 var keywords = map[string]TokenType{
@@ -21,6 +34,8 @@ var keywords = map[string]TokenType{
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
+	"macro":  MACRO,
+	"spawn":  SPAWN,
 }
 
 // TODO: could change to int
@@ -64,17 +79,29 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	MACRO    = "MACRO"
+	SPAWN    = "SPAWN"
 )
 
-// New creates a new Token from a byte
-// TODO: Full unicode support instead of simple ASCII
-func New(tokenType TokenType, ch byte) Token {
+// New creates a new Token from a single rune
+func New(tokenType TokenType, ch rune) Token {
 	return Token{
 		Type:    tokenType,
 		Literal: string(ch),
 	}
 }
 
+// Keywords returns the reserved keywords, sorted
+func Keywords() []string {
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
 // LookupIdentifier checks to see if the input identifier is a reserved keyword
 func LookupIdentifier(ident string) TokenType {
 	if tokenType, ok := keywords[ident]; ok {